@@ -0,0 +1,179 @@
+package accounting
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gobillingengine/engine"
+	"gobillingengine/engine/enginetest"
+	"gobillingengine/model"
+)
+
+func TestReplay(t *testing.T) {
+	billing := enginetest.NewBilling(t, enginetest.NewLoan("100"), 110, 0, 110)
+	require.NoError(t, billing.ChargePenalty(2)) // dated Jan 22
+
+	journal := NewJournal(DefaultChart)
+	require.NoError(t, journal.Replay(billing))
+
+	require.Len(t, journal.Entries, 4)
+	assert.Equal(t, EventDisbursement, journal.Entries[0].Event)
+	assert.Equal(t, []Line{
+		{Account: "1100", Debit: 110},
+		{Account: "1200", Credit: 100},
+		{Account: "4100", Credit: 10},
+	}, journal.Entries[1].Lines)
+	assert.Equal(t, time.Date(2024, 1, 22, 0, 0, 0, 0, time.UTC), journal.Entries[2].Date)
+	assert.Equal(t, EventPayment, journal.Entries[2].Event)
+	assert.Equal(t, EventPenalty, journal.Entries[3].Event)
+
+	assert.Equal(t, []Balance{
+		{Account: "1100", Name: "Cash clearing", Credit: 4780},
+		{Account: "1200", Name: "Loan receivable", Debit: 4802},
+		{Account: "4100", Name: "Interest income", Credit: 20},
+		{Account: "4200", Name: "Penalty income", Credit: 2},
+	}, journal.TrialBalance())
+}
+
+func TestReversalAndWriteOff(t *testing.T) {
+	billing := enginetest.NewBilling(t, enginetest.NewLoan("100"), 110)
+	journal := NewJournal(DefaultChart)
+	require.NoError(t, journal.Replay(billing))
+
+	payment, err := billing.ReversePayment()
+	require.NoError(t, err)
+	require.NoError(t, journal.Reversal(billing, payment, time.Date(2024, 1, 9, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, []Line{
+		{Account: "1100", Credit: 110},
+		{Account: "1200", Debit: 100},
+		{Account: "4100", Debit: 10},
+	}, journal.Entries[2].Lines)
+
+	require.NoError(t, journal.WriteOff("100", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, []Balance{
+		{Account: "1100", Name: "Cash clearing", Credit: 5000},
+		{Account: "1200", Name: "Loan receivable"},
+		{Account: "4100", Name: "Interest income"},
+		{Account: "5100", Name: "Loan write-off expense", Debit: 5000},
+	}, journal.TrialBalance())
+
+	assert.Error(t, journal.WriteOff("100", time.Date(2024, 6, 2, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestReplay_WrittenOff(t *testing.T) {
+	billing := enginetest.NewBilling(t, enginetest.NewLoan("100"), 110, 0, 0)
+	require.NoError(t, billing.WriteOff(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)))
+
+	journal := NewJournal(DefaultChart)
+	require.NoError(t, journal.Replay(billing))
+	writeOff := journal.Entries[len(journal.Entries)-1]
+	assert.Equal(t, EventWriteOff, writeOff.Event)
+	assert.Equal(t, []Line{
+		{Account: "5100", Debit: 4900},
+		{Account: "1200", Credit: 4900},
+	}, writeOff.Lines)
+}
+
+func TestReplay_Recovered(t *testing.T) {
+	billing := enginetest.NewBilling(t, enginetest.NewLoan("100"), 110, 0, 0)
+	require.NoError(t, billing.WriteOff(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)))
+	_, err := billing.Settle(3000, 2, time.Date(2024, 6, 3, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.NoError(t, billing.PaySettlement(1500, time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC)))
+
+	journal := NewJournal(DefaultChart)
+	require.NoError(t, journal.Replay(billing))
+	recovery := journal.Entries[len(journal.Entries)-1]
+	assert.Equal(t, EventRecovery, recovery.Event)
+	assert.Equal(t, time.Date(2024, 6, 10, 0, 0, 0, 0, time.UTC), recovery.Date)
+	assert.Equal(t, []Line{
+		{Account: "1100", Debit: 1500},
+		{Account: "5100", Credit: 1500},
+	}, recovery.Lines)
+	assert.Contains(t, journal.TrialBalance(), Balance{Account: "5100", Name: "Loan write-off expense", Debit: 3400})
+}
+
+func TestReplay_Fees(t *testing.T) {
+	loan := model.NewLoan("100", 50, 5000, 0.1)
+	loan.Fees = []model.Fee{
+		{Code: "admin", Kind: model.FeeDeducted, Amount: 50},
+		{Code: "origination", Kind: model.FeeFinanced, Amount: 100},
+		{Code: "insurance", Kind: model.FeeInstallment, Amount: 2},
+	}
+	billing := engine.NewBilling(loan)
+	require.NoError(t, billing.MakePayment(billing.PayableAmount))
+
+	journal := NewJournal(DefaultChart)
+	require.NoError(t, journal.Replay(billing))
+
+	require.Len(t, journal.Entries, 2)
+	assert.Equal(t, []Line{
+		{Account: "1200", Debit: 5100},
+		{Account: "1100", Credit: 4950},
+		{Account: "4300", Credit: 150},
+	}, journal.Entries[0].Lines)
+	assert.Equal(t, []Line{
+		{Account: "1100", Debit: 114},
+		{Account: "1200", Credit: 102},
+		{Account: "4100", Credit: 10},
+		{Account: "4300", Credit: 2},
+	}, journal.Entries[1].Lines)
+}
+
+func TestReplay_Payoff(t *testing.T) {
+	billing := enginetest.NewBilling(t, enginetest.NewLoan("100"), 110, 110, 0)
+	require.NoError(t, billing.ChargePenalty(5))
+	date := time.Date(2024, 1, 30, 0, 0, 0, 0, time.UTC)
+	refinanced, err := engine.Refinance(billing, model.NewLoan("101", 50, 10000, 0.1), date)
+	require.NoError(t, err)
+
+	journal := NewJournal(DefaultChart)
+	require.NoError(t, journal.Replay(billing))
+	require.NoError(t, journal.Disburse(refinanced.Loan))
+
+	payoff := journal.Entries[len(journal.Entries)-2]
+	assert.Equal(t, EventPayoff, payoff.Event)
+	assert.Equal(t, date, payoff.Date)
+	assert.Equal(t, []Line{
+		{Account: "1100", Debit: 4825},
+		{Account: "1200", Credit: 4805},
+		{Account: "4100", Credit: 20},
+	}, payoff.Lines)
+	// the payoff settles the receivable of the old loan
+	assert.Zero(t, journal.balance("100", "1200"))
+}
+
+func TestPost_Unbalanced(t *testing.T) {
+	journal := NewJournal(DefaultChart)
+	err := journal.post(Entry{Description: "broken", Lines: []Line{{Account: "1100", Debit: 1}}})
+	assert.EqualError(t, err, `entry "broken" is unbalanced: debit 1.00, credit 0.00`)
+	assert.Empty(t, journal.Entries)
+}
+
+func TestLoadChart(t *testing.T) {
+	chart, err := LoadChart(strings.NewReader("cash:\n  code: \"1010\"\n  name: Bank VA clearing\n"))
+	require.NoError(t, err)
+	assert.Equal(t, Account{Code: "1010", Name: "Bank VA clearing"}, chart.Cash)
+	assert.Equal(t, DefaultChart.LoanReceivable, chart.LoanReceivable)
+
+	_, err = LoadChart(strings.NewReader("cash:\n  code: \"1200\"\n"))
+	assert.EqualError(t, err, "account code 1200 is used twice")
+}
+
+func TestWriteTrialBalanceCSV(t *testing.T) {
+	journal := NewJournal(DefaultChart)
+	require.NoError(t, journal.Replay(enginetest.NewBilling(t, enginetest.NewLoan("100"), 110)))
+
+	var buf bytes.Buffer
+	require.NoError(t, journal.WriteTrialBalanceCSV(&buf))
+	assert.Equal(t, "account,account_name,debit,credit\n"+
+		"1100,Cash clearing,0.00,4890.00\n"+
+		"1200,Loan receivable,4900.00,0.00\n"+
+		"4100,Interest income,0.00,10.00\n"+
+		",Total,4900.00,4900.00\n", buf.String())
+}
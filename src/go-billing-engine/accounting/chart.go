@@ -0,0 +1,73 @@
+// Package accounting books the billing events as double-entry journal entries.
+package accounting
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Account struct {
+	Code string `yaml:"code"`
+	Name string `yaml:"name"`
+}
+
+// Chart is the chart of accounts the journal books to.
+type Chart struct {
+	LoanReceivable  Account `yaml:"loan_receivable"`
+	InterestIncome  Account `yaml:"interest_income"`
+	PenaltyIncome   Account `yaml:"penalty_income"`
+	FeeIncome       Account `yaml:"fee_income"`
+	Cash            Account `yaml:"cash"` // Cash is the bank or clearing account money is disbursed from and repaid to
+	WriteOffExpense Account `yaml:"write_off_expense"`
+}
+
+var DefaultChart = Chart{
+	LoanReceivable:  Account{Code: "1200", Name: "Loan receivable"},
+	InterestIncome:  Account{Code: "4100", Name: "Interest income"},
+	PenaltyIncome:   Account{Code: "4200", Name: "Penalty income"},
+	FeeIncome:       Account{Code: "4300", Name: "Fee income"},
+	Cash:            Account{Code: "1100", Name: "Cash clearing"},
+	WriteOffExpense: Account{Code: "5100", Name: "Loan write-off expense"},
+}
+
+// LoadChart reads a YAML chart of accounts, accounts left out keep their DefaultChart value.
+func LoadChart(r io.Reader) (Chart, error) {
+	chart := DefaultChart
+	if err := yaml.NewDecoder(r).Decode(&chart); err != nil && !errors.Is(err, io.EOF) {
+		return Chart{}, err
+	}
+	if err := chart.validate(); err != nil {
+		return Chart{}, err
+	}
+	return chart, nil
+}
+
+func (c Chart) accounts() []Account {
+	return []Account{c.LoanReceivable, c.InterestIncome, c.PenaltyIncome, c.FeeIncome, c.Cash, c.WriteOffExpense}
+}
+
+func (c Chart) validate() error {
+	seen := make(map[string]bool)
+	for _, account := range c.accounts() {
+		if account.Code == "" {
+			return fmt.Errorf("account %q has no code", account.Name)
+		}
+		if seen[account.Code] {
+			return fmt.Errorf("account code %s is used twice", account.Code)
+		}
+		seen[account.Code] = true
+	}
+	return nil
+}
+
+func (c Chart) name(code string) string {
+	for _, account := range c.accounts() {
+		if account.Code == code {
+			return account.Name
+		}
+	}
+	return ""
+}
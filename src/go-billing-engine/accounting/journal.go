@@ -0,0 +1,358 @@
+package accounting
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"gobillingengine/engine"
+	"gobillingengine/model"
+)
+
+const (
+	EventDisbursement = "disbursement"
+	EventPayment      = "payment"
+	EventReversal     = "reversal"
+	EventPenalty      = "penalty"
+	EventWriteOff     = "write_off"
+	EventPayoff       = "payoff"
+	EventRecovery     = "recovery"
+)
+
+// Line debits or credits an account.
+type Line struct {
+	Account string
+	Debit   float64
+	Credit  float64
+}
+
+// Entry is a balanced journal entry.
+type Entry struct {
+	Date        time.Time
+	LoanID      string
+	Event       string
+	Description string
+	Lines       []Line
+}
+
+// Journal records the entries of the billings in booking order.
+type Journal struct {
+	Chart   Chart
+	Entries []Entry
+}
+
+func NewJournal(chart Chart) *Journal {
+	return &Journal{Chart: chart}
+}
+
+// Disburse books the principal handed to the borrower. Deducted and financed fees are income at disbursement,
+// the financed ones are receivable from the borrower with the principal.
+func (j *Journal) Disburse(loan *model.Loan) error {
+	financed := round(loan.FeeTotal(model.FeeFinanced))
+	deducted := round(loan.FeeTotal(model.FeeDeducted))
+	lines := []Line{
+		{Account: j.Chart.LoanReceivable.Code, Debit: round(loan.Amount + financed)},
+		{Account: j.Chart.Cash.Code, Credit: round(loan.Amount - deducted)},
+	}
+	if fees := round(financed + deducted); fees > 0 {
+		lines = append(lines, Line{Account: j.Chart.FeeIncome.Code, Credit: fees})
+	}
+	return j.post(Entry{
+		Date:        loan.DisbursementDate,
+		LoanID:      loan.LoanID,
+		Event:       EventDisbursement,
+		Description: fmt.Sprintf("Disbursement of loan %s", loan.LoanID),
+		Lines:       lines,
+	})
+}
+
+// Payment books a repayment, split between principal and interest in proportion to the flat rate of its period.
+// A missed payment books nothing.
+func (j *Journal) Payment(b *engine.Billing, payment *engine.Payment) error {
+	if payment.Amount == 0 {
+		return nil
+	}
+	return j.post(Entry{
+		Date:        payment.Date,
+		LoanID:      b.Loan.LoanID,
+		Event:       EventPayment,
+		Description: fmt.Sprintf("Week %d installment of loan %s", payment.Week, b.Loan.LoanID),
+		Lines:       j.paymentLines(b, payment, false),
+	})
+}
+
+// Reversal books the opposite of a reversed payment.
+func (j *Journal) Reversal(b *engine.Billing, payment *engine.Payment, date time.Time) error {
+	if payment.Amount == 0 {
+		return nil
+	}
+	return j.post(Entry{
+		Date:        date,
+		LoanID:      b.Loan.LoanID,
+		Event:       EventReversal,
+		Description: fmt.Sprintf("Reversal of week %d installment of loan %s", payment.Week, b.Loan.LoanID),
+		Lines:       j.paymentLines(b, payment, true),
+	})
+}
+
+// paymentLines splits an installment between the loan receivable (principal and financed fee), the interest
+// at the rate of its period and the per-installment fee.
+func (j *Journal) paymentLines(b *engine.Billing, payment *engine.Payment, reverse bool) []Line {
+	amount := payment.Amount
+	financed := round(b.Loan.FeeTotal(model.FeeFinanced) / float64(b.Loan.Weeks))
+	fee := round(b.Loan.FeeTotal(model.FeeInstallment))
+	principal := round((amount - financed - fee) / (1 + b.RateAt(payment.Date)))
+	interest := round(round(amount) - principal - financed - fee)
+	lines := []Line{
+		{Account: j.Chart.Cash.Code, Debit: round(amount)},
+		{Account: j.Chart.LoanReceivable.Code, Credit: round(principal + financed)},
+		{Account: j.Chart.InterestIncome.Code, Credit: interest},
+	}
+	if fee > 0 {
+		lines = append(lines, Line{Account: j.Chart.FeeIncome.Code, Credit: fee})
+	}
+	if reverse {
+		for i := range lines {
+			lines[i].Debit, lines[i].Credit = lines[i].Credit, lines[i].Debit
+		}
+	}
+	return lines
+}
+
+// Penalty books a penalty charged on the loan as income receivable from the borrower.
+func (j *Journal) Penalty(b *engine.Billing, penalty *engine.Penalty) error {
+	return j.post(Entry{
+		Date:        penalty.Date,
+		LoanID:      b.Loan.LoanID,
+		Event:       EventPenalty,
+		Description: fmt.Sprintf("Week %d penalty of loan %s", penalty.Week, b.Loan.LoanID),
+		Lines: []Line{
+			{Account: j.Chart.LoanReceivable.Code, Debit: round(penalty.Amount)},
+			{Account: j.Chart.PenaltyIncome.Code, Credit: round(penalty.Amount)},
+		},
+	})
+}
+
+// WriteOff books the loan receivable left on the loan as an expense.
+func (j *Journal) WriteOff(loanID string, date time.Time) error {
+	receivable := j.balance(loanID, j.Chart.LoanReceivable.Code)
+	if receivable <= 0 {
+		return fmt.Errorf("loan %s has no receivable to write off", loanID)
+	}
+	return j.post(Entry{
+		Date:        date,
+		LoanID:      loanID,
+		Event:       EventWriteOff,
+		Description: fmt.Sprintf("Write-off of loan %s", loanID),
+		Lines: []Line{
+			{Account: j.Chart.WriteOffExpense.Code, Debit: receivable},
+			{Account: j.Chart.LoanReceivable.Code, Credit: receivable},
+		},
+	})
+}
+
+// Recovery books a payment under a settlement of a written off loan, the cash received reduces the loss.
+func (j *Journal) Recovery(b *engine.Billing, payment *engine.SettlementPayment) error {
+	return j.post(Entry{
+		Date:        payment.Date,
+		LoanID:      b.Loan.LoanID,
+		Event:       EventRecovery,
+		Description: fmt.Sprintf("Settlement recovery of loan %s", b.Loan.LoanID),
+		Lines: []Line{
+			{Account: j.Chart.Cash.Code, Debit: round(payment.Amount)},
+			{Account: j.Chart.WriteOffExpense.Code, Credit: round(payment.Amount)},
+		},
+	})
+}
+
+// Replay books the disbursement, payments, penalties and payoff or write-off of a billing in date order, then the
+// recoveries of a written off one.
+func (j *Journal) Replay(b *engine.Billing) error {
+	if err := j.Disburse(b.Loan); err != nil {
+		return err
+	}
+	penalties := b.Penalties
+	for _, payment := range b.Payments() {
+		for len(penalties) > 0 && penalties[0].Date.Before(payment.Date) {
+			if err := j.Penalty(b, penalties[0]); err != nil {
+				return err
+			}
+			penalties = penalties[1:]
+		}
+		if err := j.Payment(b, payment); err != nil {
+			return err
+		}
+	}
+	for _, penalty := range penalties {
+		if err := j.Penalty(b, penalty); err != nil {
+			return err
+		}
+	}
+	if !b.IsWrittenOff() {
+		return j.Payoff(b)
+	}
+	if err := j.WriteOff(b.Loan.LoanID, b.Closure.Date); err != nil {
+		return err
+	}
+	for _, s := range b.Settlements {
+		for _, payment := range s.Payments {
+			if err := j.Recovery(b, payment); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Payoff books the payoff of a billing closed before the end of its schedule, e.g. by a refinancing loan: the cash
+// received settles the receivable left, and the interest and fees of the overdue installments are income.
+func (j *Journal) Payoff(b *engine.Billing) error {
+	if b.Closure == nil || b.IsWrittenOff() {
+		return nil
+	}
+	payoff := b.Closure.Payoff
+	receivable, interest, fees := round(payoff.Principal+payoff.Penalty), round(payoff.Interest), round(payoff.Fees)
+	lines := []Line{
+		{Account: j.Chart.Cash.Code, Debit: round(receivable + interest + fees)},
+		{Account: j.Chart.LoanReceivable.Code, Credit: receivable},
+	}
+	if interest > 0 {
+		lines = append(lines, Line{Account: j.Chart.InterestIncome.Code, Credit: interest})
+	}
+	if fees > 0 {
+		lines = append(lines, Line{Account: j.Chart.FeeIncome.Code, Credit: fees})
+	}
+	return j.post(Entry{
+		Date:        b.Closure.Date,
+		LoanID:      b.Loan.LoanID,
+		Event:       EventPayoff,
+		Description: fmt.Sprintf("Payoff of loan %s, %s into loan %s", b.Loan.LoanID, b.Closure.Reason, b.Closure.LinkedLoanID),
+		Lines:       lines,
+	})
+}
+
+func (j *Journal) post(entry Entry) error {
+	var debit, credit float64
+	for _, line := range entry.Lines {
+		debit += line.Debit
+		credit += line.Credit
+	}
+	if round(debit) != round(credit) {
+		return fmt.Errorf("entry %q is unbalanced: debit %.2f, credit %.2f", entry.Description, debit, credit)
+	}
+	j.Entries = append(j.Entries, entry)
+	return nil
+}
+
+// balance returns the debit balance of an account for a loan.
+func (j *Journal) balance(loanID, account string) float64 {
+	var balance float64
+	for _, entry := range j.Entries {
+		if entry.LoanID != loanID {
+			continue
+		}
+		for _, line := range entry.Lines {
+			if line.Account == account {
+				balance += line.Debit - line.Credit
+			}
+		}
+	}
+	return round(balance)
+}
+
+// Balance is the total of an account in the trial balance.
+type Balance struct {
+	Account string
+	Name    string
+	Debit   float64
+	Credit  float64
+}
+
+// TrialBalance returns the balance of every account, by account code, on its debit or credit side.
+func (j *Journal) TrialBalance() []Balance {
+	totals := make(map[string]*Balance)
+	for _, entry := range j.Entries {
+		for _, line := range entry.Lines {
+			total, ok := totals[line.Account]
+			if !ok {
+				total = &Balance{Account: line.Account, Name: j.Chart.name(line.Account)}
+				totals[line.Account] = total
+			}
+			total.Debit = round(total.Debit + line.Debit)
+			total.Credit = round(total.Credit + line.Credit)
+		}
+	}
+	balances := make([]Balance, 0, len(totals))
+	for _, total := range totals {
+		net := round(total.Debit - total.Credit)
+		total.Debit, total.Credit = 0, 0
+		if net > 0 {
+			total.Debit = net
+		} else {
+			total.Credit = -net
+		}
+		balances = append(balances, *total)
+	}
+	sort.Slice(balances, func(i, k int) bool { return balances[i].Account < balances[k].Account })
+	return balances
+}
+
+// WriteCSV writes one row per journal line.
+func (j *Journal) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"date", "loan_id", "event", "description", "account", "account_name", "debit", "credit"}); err != nil {
+		return err
+	}
+	for _, entry := range j.Entries {
+		for _, line := range entry.Lines {
+			err := cw.Write([]string{
+				entry.Date.Format("2006-01-02"),
+				entry.LoanID,
+				entry.Event,
+				entry.Description,
+				line.Account,
+				j.Chart.name(line.Account),
+				formatAmount(line.Debit),
+				formatAmount(line.Credit),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteTrialBalanceCSV writes the trial balance followed by a total row.
+func (j *Journal) WriteTrialBalanceCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"account", "account_name", "debit", "credit"}); err != nil {
+		return err
+	}
+	var debit, credit float64
+	for _, balance := range j.TrialBalance() {
+		debit += balance.Debit
+		credit += balance.Credit
+		if err := cw.Write([]string{balance.Account, balance.Name, formatAmount(balance.Debit), formatAmount(balance.Credit)}); err != nil {
+			return err
+		}
+	}
+	if err := cw.Write([]string{"", "Total", formatAmount(debit), formatAmount(credit)}); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
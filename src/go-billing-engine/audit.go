@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"gobillingengine/audit"
+	"gobillingengine/portfolio"
+)
+
+func newAuditCmd() *cobra.Command {
+	auditCmd := &cobra.Command{
+		Use:   "audit",
+		Short: "Verify the billing invariants of a persisted portfolio",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, _ := cmd.Flags().GetString("portfolio")
+			loanID, _ := cmd.Flags().GetString("loan")
+			asJSON, _ := cmd.Flags().GetBool("json")
+
+			store, err := portfolio.OpenFileStore(path)
+			if err != nil {
+				return err
+			}
+
+			var report *audit.Report
+			if loanID != "" {
+				b, err := store.Get(loanID)
+				if err != nil {
+					return fmt.Errorf("loanID:%s - %v", loanID, err)
+				}
+				report = &audit.Report{Checked: 1, Discrepancies: append([]audit.Discrepancy{}, audit.Check(b)...)}
+			} else if report, err = audit.CheckPortfolio(store); err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if asJSON {
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				if err := enc.Encode(report); err != nil {
+					return err
+				}
+			} else {
+				for _, d := range report.Discrepancies {
+					fmt.Fprintf(out, "loan %s: %s is %v, suggested %v\n", d.LoanID, d.Field, d.Recorded, d.Suggested)
+				}
+				fmt.Fprintf(out, "%d billing(s) checked, %d discrepancy(ies) found\n", report.Checked, len(report.Discrepancies))
+			}
+
+			if len(report.Discrepancies) > 0 {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("audit found %d discrepancy(ies)", len(report.Discrepancies))
+			}
+			return nil
+		},
+	}
+	auditCmd.Flags().String("portfolio", "portfolio.json", "The persisted portfolio file")
+	auditCmd.Flags().String("loan", "", "Only audit the billing of this loan ID")
+	auditCmd.Flags().Bool("json", false, "Print the report as JSON")
+	return auditCmd
+}
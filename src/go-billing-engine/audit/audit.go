@@ -0,0 +1,92 @@
+// Package audit verifies that the derived fields of billings agree with their payment history.
+package audit
+
+import (
+	"math"
+
+	"gobillingengine/engine"
+	"gobillingengine/portfolio"
+)
+
+const (
+	FieldOutstanding    = "outstanding"
+	FieldRemainingWeeks = "remaining_weeks"
+	FieldMissedPayment  = "missed_payment"
+)
+
+// tolerance absorbs float rounding of the money fields.
+const tolerance = 0.005
+
+// Discrepancy is a field of a billing which disagrees with its history.
+type Discrepancy struct {
+	LoanID    string  `json:"loan_id"`
+	Field     string  `json:"field"`
+	Recorded  float64 `json:"recorded"`
+	Suggested float64 `json:"suggested"` // Suggested is the value recomputed from the history
+}
+
+// Report is the outcome of auditing a set of billings.
+type Report struct {
+	Checked       int           `json:"checked"`
+	Discrepancies []Discrepancy `json:"discrepancies"`
+}
+
+// Check verifies the invariants of one billing:
+//   - Outstanding is the scheduled total, repricings included, minus the paid amounts
+//   - RemainingWeeks is the number of weeks minus the paid installments
+//   - MissedPayment is the number of trailing misses in the history
+//
+// A billing closed before the end of its schedule has nothing outstanding, remaining or missed.
+func Check(b *engine.Billing) []Discrepancy {
+	var paid float64
+	var paidWeeks, trailingMisses int
+	for _, p := range b.Payments() {
+		if p.Amount == 0 {
+			trailingMisses++
+			continue
+		}
+		paid += p.Amount
+		paidWeeks++
+		trailingMisses = 0
+	}
+
+	var discrepancies []Discrepancy
+	add := func(field string, recorded, suggested float64) {
+		discrepancies = append(discrepancies, Discrepancy{
+			LoanID:    b.Loan.LoanID,
+			Field:     field,
+			Recorded:  recorded,
+			Suggested: suggested,
+		})
+	}
+
+	outstanding := b.ScheduledTotal() - paid
+	if b.IsClosed() {
+		outstanding, paidWeeks, trailingMisses = 0, b.Loan.Weeks, 0
+	}
+	if math.Abs(b.Outstanding-outstanding) > tolerance {
+		add(FieldOutstanding, b.Outstanding, outstanding)
+	}
+	if remaining := b.Loan.Weeks - paidWeeks; b.RemainingWeeks != remaining {
+		add(FieldRemainingWeeks, float64(b.RemainingWeeks), float64(remaining))
+	}
+	if b.MissedPayment != trailingMisses {
+		add(FieldMissedPayment, float64(b.MissedPayment), float64(trailingMisses))
+	}
+	return discrepancies
+}
+
+// CheckPortfolio audits every billing of the store.
+func CheckPortfolio(store portfolio.Store) (*Report, error) {
+	billings, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{Discrepancies: []Discrepancy{}}
+	for _, b := range billings {
+		report.Checked++
+		report.Discrepancies = append(report.Discrepancies, Check(b)...)
+	}
+	return report, nil
+}
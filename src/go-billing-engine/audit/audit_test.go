@@ -0,0 +1,60 @@
+package audit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gobillingengine/engine"
+	"gobillingengine/engine/enginetest"
+	"gobillingengine/model"
+	"gobillingengine/portfolio"
+)
+
+func TestCheck(t *testing.T) {
+	billing := enginetest.NewBilling(t, enginetest.NewLoan("1001"), 110, 0, 110, 0, 0)
+	assert.Empty(t, Check(billing))
+
+	billing.Outstanding = 5500
+	billing.RemainingWeeks = 49
+	billing.MissedPayment = 0
+	assert.Equal(t, []Discrepancy{
+		{LoanID: "1001", Field: FieldOutstanding, Recorded: 5500, Suggested: 5280},
+		{LoanID: "1001", Field: FieldRemainingWeeks, Recorded: 49, Suggested: 48},
+		{LoanID: "1001", Field: FieldMissedPayment, Recorded: 0, Suggested: 2},
+	}, Check(billing))
+}
+
+func TestCheck_Repriced(t *testing.T) {
+	loan := model.NewLoan("1001", 50, 5000, 0.1)
+	loan.VariableRate = true
+	billing := engine.NewBilling(loan)
+	require.NoError(t, billing.MakePayment(110))
+	require.NoError(t, billing.ChangeRate(0.2, billing.DueDate(2)))
+	require.NoError(t, billing.MakePayment(120))
+	assert.Empty(t, Check(billing))
+}
+
+func TestCheck_Closed(t *testing.T) {
+	billing := enginetest.NewBilling(t, enginetest.NewLoan("1001"), 110, 0)
+	_, err := engine.Refinance(billing, model.NewLoan("1002", 50, 10000, 0.1), billing.DueDate(2))
+	require.NoError(t, err)
+	assert.Empty(t, Check(billing))
+}
+
+func TestCheckPortfolio(t *testing.T) {
+	store := portfolio.NewMemoryStore()
+	require.NoError(t, store.Create(enginetest.NewBilling(t, enginetest.NewLoan("1001"), 110, 110)))
+
+	broken := enginetest.NewBilling(t, enginetest.NewLoan("1002"), 0, 0)
+	broken.MissedPayment = 1
+	require.NoError(t, store.Create(broken))
+
+	report, err := CheckPortfolio(store)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Checked)
+	assert.Equal(t, []Discrepancy{
+		{LoanID: "1002", Field: FieldMissedPayment, Recorded: 1, Suggested: 2},
+	}, report.Discrepancies)
+}
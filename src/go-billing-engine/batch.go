@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"gobillingengine/batch"
+	"gobillingengine/portfolio"
+	"gobillingengine/product"
+)
+
+func newBatchCmd() *cobra.Command {
+	batchCmd := &cobra.Command{
+		Use:   "batch",
+		Short: "Run the end-of-week processing of the portfolio: mark missed installments, charge late fees, update statuses",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, _ := cmd.Flags().GetString("portfolio")
+			catalogPath, _ := cmd.Flags().GetString("catalog")
+			checkpointPath, _ := cmd.Flags().GetString("checkpoint")
+			lateFee, _ := cmd.Flags().GetFloat64("late-fee")
+			workers, _ := cmd.Flags().GetInt("workers")
+			every, _ := cmd.Flags().GetInt("checkpoint-every")
+
+			cutoff, err := getDate(cmd, "cutoff")
+			if err != nil {
+				return err
+			}
+			if checkpointPath == "" {
+				checkpointPath = path + ".checkpoint"
+			}
+
+			store, err := portfolio.OpenFileStore(path)
+			if err != nil {
+				return err
+			}
+			subscribed, closeWebhook := withWebhook(cmd, store)
+			defer closeWebhook()
+			processor := &batch.Processor{
+				Store:           subscribed,
+				LateFee:         lateFee,
+				Workers:         workers,
+				CheckpointEvery: every,
+				CheckpointPath:  checkpointPath,
+			}
+			if catalogPath != "" {
+				if processor.Catalog, err = product.Load(catalogPath); err != nil {
+					return err
+				}
+			}
+
+			report, err := processor.Run(cmd.Context(), cutoff)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.ErrOrStderr(), report.Summary())
+			for _, failure := range report.Failures {
+				fmt.Fprintf(cmd.ErrOrStderr(), "loanID:%s - %s\n", failure.LoanID, failure.Reason)
+			}
+
+			out, closeOut, err := getOutput(cmd)
+			if err != nil {
+				return err
+			}
+			err = report.WriteJSON(out)
+			if errClose := closeOut(); err == nil {
+				err = errClose
+			}
+			return err
+		},
+	}
+	batchCmd.Flags().String("portfolio", "portfolio.json", "The persisted portfolio file")
+	batchCmd.Flags().String("cutoff", "", "The cutoff date (YYYY-MM-DD), installments due before it are missed if unpaid, today by default")
+	batchCmd.Flags().String("catalog", "", "The YAML product catalog setting the late fee of each product")
+	batchCmd.Flags().Float64("late-fee", 0, "The late fee of each missed installment of the loans without product")
+	batchCmd.Flags().Int("workers", 0, "The number of loans processed concurrently, the number of CPUs by default")
+	batchCmd.Flags().String("checkpoint", "", "The checkpoint file to resume an interrupted run from, <portfolio>.checkpoint by default")
+	batchCmd.Flags().Int("checkpoint-every", batch.DefaultCheckpointEvery, "The number of loans saved and checkpointed at once")
+	batchCmd.Flags().StringP("output", "o", "", "Write the JSON report to this file instead of stdout")
+	addWebhookFlags(batchCmd)
+	return batchCmd
+}
@@ -0,0 +1,346 @@
+// Package batch runs the end-of-week processing of a whole billing portfolio at a cutoff date.
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"gobillingengine/clock"
+	"gobillingengine/engine"
+	"gobillingengine/portfolio"
+	"gobillingengine/product"
+)
+
+const (
+	StatusCurrent     = "current"     // StatusCurrent the borrower is not delinquent
+	StatusDelinquent  = "delinquent"  // StatusDelinquent the borrower missed enough continuous payments to be delinquent
+	StatusClosed      = "closed"      // StatusClosed the loan is fully paid
+	StatusUnscheduled = "unscheduled" // StatusUnscheduled the loan has no disbursement date, so nothing can be due
+)
+
+// DefaultCheckpointEvery is the number of loans saved and checkpointed at once.
+const DefaultCheckpointEvery = 10000
+
+// Failure is a loan the batch could not process entirely. What was done before the error, e.g. the periods already
+// marked missed, is saved with the other loans.
+type Failure struct {
+	LoanID string `json:"loan_id"`
+	Reason string `json:"reason"`
+}
+
+// Report is the outcome of a batch run at a cutoff, counting the loans processed before a restart too.
+type Report struct {
+	Cutoff      time.Time     `json:"cutoff"`
+	Loans       int           `json:"loans"`
+	Resumed     int           `json:"resumed"` // Resumed loans already processed by an interrupted run
+	Missed      int           `json:"missed"`  // Missed installments marked by the batch
+	Penalties   float64       `json:"penalties"`
+	Current     int           `json:"current"`
+	Delinquent  int           `json:"delinquent"`
+	Closed      int           `json:"closed"`
+	Unscheduled int           `json:"unscheduled"`
+	Broken      int           `json:"broken_settlements"` // Broken settlements left unpaid past their deadline
+	Failures    []Failure     `json:"failures,omitempty"`
+	Elapsed     time.Duration `json:"elapsed"`
+}
+
+// Throughput returns the loans processed per second by this run, the resumed ones left out.
+func (r *Report) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Loans-r.Resumed) / r.Elapsed.Seconds()
+}
+
+// Summary is a one line overview of the report.
+func (r *Report) Summary() string {
+	return fmt.Sprintf("%d loan(s) at %s: %d missed installment(s), %.2f penalties, %d current, %d delinquent, %d closed, %d unscheduled, %d broken settlement(s), %d failed in %s (%.0f loans/s)",
+		r.Loans, r.Cutoff.Format("2006-01-02"), r.Missed, r.Penalties, r.Current, r.Delinquent, r.Closed, r.Unscheduled,
+		r.Broken, len(r.Failures), r.Elapsed.Round(time.Millisecond), r.Throughput())
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *Report) WriteJSON(out io.Writer) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func (r *Report) add(res result) {
+	r.Loans++
+	r.Missed += res.missed
+	r.Penalties += res.penalties
+	if res.broken {
+		r.Broken++
+	}
+	switch res.status {
+	case StatusCurrent:
+		r.Current++
+	case StatusDelinquent:
+		r.Delinquent++
+	case StatusClosed:
+		r.Closed++
+	case StatusUnscheduled:
+		r.Unscheduled++
+	}
+	if res.err != nil {
+		r.Failures = append(r.Failures, Failure{LoanID: res.loanID, Reason: res.err.Error()})
+	}
+}
+
+// Checkpoint records how far a run at a cutoff got: every loan up to LastLoanID, in loan ID order, is processed and saved.
+type Checkpoint struct {
+	Cutoff     time.Time `json:"cutoff"`
+	LastLoanID string    `json:"last_loan_id"`
+	Report     Report    `json:"report"`
+}
+
+// saver is implemented by the stores able to save several billings at once, e.g. with a single file rewrite.
+type saver interface {
+	SaveAll(billings []*engine.Billing) error
+}
+
+// Processor marks the installments due before the cutoff and left unpaid as missed, charges the late fee of the loan
+// product for each of them, breaks the settlements left unpaid past their deadline and reports the status of every
+// loan of the portfolio.
+type Processor struct {
+	Store           portfolio.Store
+	Catalog         *product.Catalog // Catalog of the loan products, their delinquency policy sets the late fee
+	LateFee         float64          // LateFee of the loans without product, none if zero
+	Workers         int              // Workers processing loans concurrently, the number of CPUs if zero
+	CheckpointEvery int              // CheckpointEvery loans the billings are saved and the checkpoint written, DefaultCheckpointEvery if zero
+	CheckpointPath  string           // CheckpointPath of the checkpoint file, the run cannot resume if empty
+	Clock           clock.Clock      // Clock sets the cutoff of a run without one, the system clock if nil
+}
+
+type result struct {
+	loanID    string
+	missed    int
+	penalties float64
+	broken    bool
+	status    string
+	err       error
+}
+
+// Run processes the portfolio at cutoff, today's business date if zero, resuming from the checkpoint of an interrupted run at the same cutoff.
+// The checkpoint is removed once the whole portfolio is processed. Processing a loan twice is harmless: only the
+// periods without a payment record are marked missed.
+func (p *Processor) Run(ctx context.Context, cutoff time.Time) (*Report, error) {
+	started := time.Now()
+	if cutoff.IsZero() {
+		cutoff = clock.Today(p.Clock)
+	}
+
+	checkpoint, err := p.loadCheckpoint(cutoff)
+	if err != nil {
+		return nil, err
+	}
+	report := checkpoint.Report
+	report.Cutoff = cutoff
+	report.Resumed = report.Loans
+
+	billings, err := p.Store.List()
+	if err != nil {
+		return nil, err
+	}
+	if checkpoint.LastLoanID != "" {
+		skip := 0
+		for skip < len(billings) && billings[skip].Loan.LoanID <= checkpoint.LastLoanID {
+			skip++
+		}
+		billings = billings[skip:]
+	}
+
+	workers := p.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	every := p.CheckpointEvery
+	if every <= 0 {
+		every = DefaultCheckpointEvery
+	}
+
+	results := make([]result, every)
+	jobs := make(chan int)
+	var window []*engine.Billing
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		go func() {
+			for idx := range jobs {
+				results[idx] = p.process(window[idx], cutoff)
+				wg.Done()
+			}
+		}()
+	}
+	defer close(jobs)
+
+	for start := 0; start < len(billings); start += every {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		end := start + every
+		if end > len(billings) {
+			end = len(billings)
+		}
+		window = billings[start:end]
+
+		wg.Add(len(window))
+		for idx := range window {
+			jobs <- idx
+		}
+		wg.Wait()
+
+		changed := make([]*engine.Billing, 0, len(window))
+		for idx, b := range window {
+			report.add(results[idx])
+			if results[idx].missed > 0 || results[idx].broken {
+				changed = append(changed, b)
+			}
+		}
+		if err := p.save(changed); err != nil {
+			return nil, err
+		}
+
+		checkpoint.Cutoff = cutoff
+		checkpoint.LastLoanID = window[len(window)-1].Loan.LoanID
+		checkpoint.Report = report
+		if err := p.writeCheckpoint(checkpoint); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.CheckpointPath != "" {
+		if err := os.Remove(p.CheckpointPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	report.Elapsed = time.Since(started)
+	return &report, nil
+}
+
+// process marks the periods of a billing due before cutoff and without a payment record as missed, and breaks its
+// settlement if still open after the deadline.
+func (p *Processor) process(b *engine.Billing, cutoff time.Time) result {
+	res := result{loanID: b.Loan.LoanID}
+	if b.Loan.DisbursementDate.IsZero() {
+		res.status = StatusUnscheduled
+		return res
+	}
+	if s := b.OpenSettlement(); s != nil && s.IsOverdue(cutoff) {
+		if err := b.BreakSettlement(cutoff); err != nil {
+			res.err = fmt.Errorf("cannot break the settlement with err: %v", err)
+		}
+		res.broken = res.err == nil
+	}
+
+	lateFee, err := p.lateFee(b)
+	if err != nil {
+		res.err = fmt.Errorf("cannot find the late fee with err: %v", err)
+	}
+	for err == nil && b.Outstanding > 0 && b.NextDueDate().Before(cutoff) {
+		if err = b.MakePayment(0); err != nil {
+			res.err = fmt.Errorf("cannot mark a missed payment with err: %v", err)
+			break
+		}
+		res.missed++
+		if lateFee > 0 {
+			if err = b.ChargePenalty(lateFee); err != nil {
+				res.err = fmt.Errorf("cannot charge the late fee with err: %v", err)
+				break
+			}
+			res.penalties += lateFee
+		}
+	}
+
+	switch {
+	case b.Outstanding <= 0:
+		res.status = StatusClosed
+	case b.IsDelinquent():
+		res.status = StatusDelinquent
+	default:
+		res.status = StatusCurrent
+	}
+	return res
+}
+
+func (p *Processor) lateFee(b *engine.Billing) (float64, error) {
+	if b.Loan.ProductCode == "" || p.Catalog == nil {
+		return p.LateFee, nil
+	}
+	prod, err := p.Catalog.Get(b.Loan.ProductCode)
+	if err != nil {
+		return 0, err
+	}
+	return prod.Delinquency.LateFee, nil
+}
+
+func (p *Processor) save(billings []*engine.Billing) error {
+	if len(billings) == 0 {
+		return nil
+	}
+	if s, ok := p.Store.(saver); ok {
+		return s.SaveAll(billings)
+	}
+	for _, b := range billings {
+		if err := p.Store.Save(b); err != nil {
+			return fmt.Errorf("cannot save loanID:%s with err: %v", b.Loan.LoanID, err)
+		}
+	}
+	return nil
+}
+
+// loadCheckpoint reads the checkpoint of an interrupted run, an absent file is a fresh start.
+func (p *Processor) loadCheckpoint(cutoff time.Time) (*Checkpoint, error) {
+	checkpoint := &Checkpoint{Cutoff: cutoff}
+	if p.CheckpointPath == "" {
+		return checkpoint, nil
+	}
+	data, err := os.ReadFile(p.CheckpointPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return checkpoint, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, checkpoint); err != nil {
+		return nil, fmt.Errorf("cannot read checkpoint with err: %v", err)
+	}
+	if !checkpoint.Cutoff.Equal(cutoff) {
+		return nil, fmt.Errorf("checkpoint is of cutoff %s, not %s: remove %s to start over",
+			checkpoint.Cutoff.Format("2006-01-02"), cutoff.Format("2006-01-02"), p.CheckpointPath)
+	}
+	return checkpoint, nil
+}
+
+// writeCheckpoint writes to a temporary file first so a crash never leaves a truncated checkpoint behind.
+func (p *Processor) writeCheckpoint(checkpoint *Checkpoint) error {
+	if p.CheckpointPath == "" {
+		return nil
+	}
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(p.CheckpointPath), filepath.Base(p.CheckpointPath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), p.CheckpointPath)
+}
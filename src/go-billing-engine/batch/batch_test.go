@@ -0,0 +1,209 @@
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gobillingengine/clock"
+	"gobillingengine/engine"
+	"gobillingengine/engine/enginetest"
+	"gobillingengine/portfolio"
+	"gobillingengine/product"
+)
+
+func newBilling(t testing.TB, loanID, productCode string, payments ...float64) *engine.Billing {
+	loan := enginetest.NewLoan(loanID)
+	loan.ProductCode = productCode
+	return enginetest.NewBilling(t, loan, payments...)
+}
+
+func newCatalog(t *testing.T) *product.Catalog {
+	catalog, err := product.LoadCatalog(strings.NewReader(`
+products:
+  - {code: W50, currency: IDR, min_amount: 1000, max_amount: 10000, tenors: [50], annual_rate: 0.1, delinquency: {late_fee: 10}}
+`))
+	require.NoError(t, err)
+	return catalog
+}
+
+func TestProcessor_Run(t *testing.T) {
+	store := portfolio.NewMemoryStore()
+	closed := newBilling(t, "1003", "")
+	closed.Outstanding = 0
+	unscheduled := newBilling(t, "1004", "")
+	unscheduled.Loan.DisbursementDate = time.Time{}
+	for _, b := range []*engine.Billing{
+		newBilling(t, "1001", "", 1, 1), // paid Jan 8 and Jan 15
+		newBilling(t, "1002", "W50"),    // nothing paid
+		closed,
+		unscheduled,
+		newBilling(t, "1005", "M12"),
+	} {
+		require.NoError(t, store.Create(b))
+	}
+
+	checkpointPath := filepath.Join(t.TempDir(), "batch.checkpoint")
+	processor := &Processor{
+		Store:           store,
+		Catalog:         newCatalog(t),
+		LateFee:         5,
+		Workers:         2,
+		CheckpointEvery: 2,
+		CheckpointPath:  checkpointPath,
+	}
+	cutoff := time.Date(2024, 1, 23, 0, 0, 0, 0, time.UTC)
+	report, err := processor.Run(context.Background(), cutoff)
+	require.NoError(t, err)
+
+	assert.Equal(t, 5, report.Loans)
+	assert.Equal(t, 4, report.Missed) // Jan 22 of 1001, Jan 8, 15 and 22 of 1002
+	assert.Equal(t, 35.0, report.Penalties)
+	assert.Equal(t, 2, report.Current) // 1001, and 1005 left as it was
+	assert.Equal(t, 1, report.Delinquent)
+	assert.Equal(t, 1, report.Closed)
+	assert.Equal(t, 1, report.Unscheduled)
+	require.Len(t, report.Failures, 1)
+	assert.Equal(t, "1005", report.Failures[0].LoanID)
+	assert.Greater(t, report.Throughput(), 0.0)
+	assert.NoFileExists(t, checkpointPath)
+
+	b, err := store.Get("1002")
+	require.NoError(t, err)
+	assert.Equal(t, 3, b.MissedPayment)
+	assert.True(t, b.IsDelinquent())
+	assert.Equal(t, 30.0, b.GetPenalty())
+	assert.Equal(t, time.Date(2024, 1, 22, 0, 0, 0, 0, time.UTC), b.Penalties[2].Date)
+
+	b, err = store.Get("1001")
+	require.NoError(t, err)
+	assert.Equal(t, 1, b.MissedPayment)
+	assert.Equal(t, 5.0, b.GetPenalty())
+
+	// a second run at the same cutoff finds nothing left to mark
+	report, err = processor.Run(context.Background(), cutoff)
+	require.NoError(t, err)
+	assert.Zero(t, report.Missed)
+	assert.Zero(t, report.Penalties)
+}
+
+func TestProcessor_Run_Resume(t *testing.T) {
+	store := portfolio.NewMemoryStore()
+	for _, id := range []string{"1001", "1002", "1003"} {
+		require.NoError(t, store.Create(newBilling(t, id, "")))
+	}
+
+	cutoff := time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC)
+	checkpointPath := filepath.Join(t.TempDir(), "batch.checkpoint")
+	data, err := json.Marshal(Checkpoint{Cutoff: cutoff, LastLoanID: "1002", Report: Report{Loans: 2, Missed: 4, Current: 2}})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(checkpointPath, data, 0o644))
+
+	processor := &Processor{Store: store, CheckpointPath: checkpointPath}
+	report, err := processor.Run(context.Background(), cutoff)
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Loans)
+	assert.Equal(t, 2, report.Resumed)
+	assert.Equal(t, 6, report.Missed)
+	assert.Equal(t, 2, report.Current)
+	assert.Equal(t, 1, report.Delinquent)
+
+	// the loans of the checkpoint are not processed again
+	b, err := store.Get("1002")
+	require.NoError(t, err)
+	assert.Zero(t, b.PaymentRecord.Size())
+	b, err = store.Get("1003")
+	require.NoError(t, err)
+	assert.Equal(t, 2, b.PaymentRecord.Size())
+
+	// a checkpoint of another cutoff is not resumed
+	require.NoError(t, os.WriteFile(checkpointPath, data, 0o644))
+	_, err = processor.Run(context.Background(), cutoff.AddDate(0, 0, 7))
+	assert.ErrorContains(t, err, "checkpoint is of cutoff 2024-01-16")
+}
+
+func TestProcessor_Run_Settlements(t *testing.T) {
+	store := portfolio.NewMemoryStore()
+	for _, id := range []string{"1001", "1002"} {
+		b := newBilling(t, id, "")
+		require.NoError(t, b.WriteOff(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)))
+		_, err := b.Settle(2000, 2, time.Date(2024, 2, 5, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+		require.NoError(t, store.Create(b))
+	}
+	b, err := store.Get("1002")
+	require.NoError(t, err)
+	require.NoError(t, b.PaySettlement(2000, time.Date(2024, 2, 12, 0, 0, 0, 0, time.UTC)))
+	require.NoError(t, store.Save(b))
+
+	// the deadline of 1001 passed unpaid, 1002 was honoured
+	cutoff := time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	report, err := (&Processor{Store: store}).Run(context.Background(), cutoff)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Broken)
+	assert.Equal(t, 2, report.Closed)
+	assert.Contains(t, report.Summary(), "1 broken settlement(s)")
+
+	b, err = store.Get("1001")
+	require.NoError(t, err)
+	assert.Equal(t, engine.SettlementBroken, b.Settlements[0].Status)
+	assert.Equal(t, cutoff, b.Settlements[0].ClosedDate)
+	b, err = store.Get("1002")
+	require.NoError(t, err)
+	assert.Equal(t, engine.SettlementHonoured, b.Settlements[0].Status)
+}
+
+func TestProcessor_Run_Cancelled(t *testing.T) {
+	store := portfolio.NewMemoryStore()
+	require.NoError(t, store.Create(newBilling(t, "1001", "")))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := (&Processor{Store: store}).Run(ctx, time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC))
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func BenchmarkProcessor_Run(b *testing.B) {
+	const loans = 1000000
+	cutoff := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC) // 4 periods due
+	checkpointPath := filepath.Join(b.TempDir(), "batch.checkpoint")
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		store := portfolio.NewMemoryStore()
+		for n := 0; n < loans; n++ {
+			payments := []float64{1, 1}
+			if n%10 == 0 {
+				payments = nil
+			}
+			require.NoError(b, store.Create(newBilling(b, fmt.Sprintf("%07d", n), "", payments...)))
+		}
+		processor := &Processor{Store: store, LateFee: 10, CheckpointPath: checkpointPath}
+		b.StartTimer()
+
+		report, err := processor.Run(context.Background(), cutoff)
+		require.NoError(b, err)
+		require.Equal(b, loans, report.Loans)
+		b.ReportMetric(report.Throughput(), "loans/s")
+	}
+}
+
+func TestProcessor_Run_Today(t *testing.T) {
+	store := portfolio.NewMemoryStore()
+	require.NoError(t, store.Create(newBilling(t, "1001", "")))
+
+	// without a cutoff the run is at today's business date of the clock
+	p := &Processor{Store: store, Clock: clock.Fixed(time.Date(2024, 1, 16, 8, 0, 0, 0, time.UTC))}
+	report, err := p.Run(context.Background(), time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC), report.Cutoff)
+	assert.Equal(t, 2, report.Missed) // Jan 8 and Jan 15
+}
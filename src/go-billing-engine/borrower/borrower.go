@@ -0,0 +1,175 @@
+// Package borrower aggregates the loans of each borrower of a portfolio.
+package borrower
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"gobillingengine/engine"
+	"gobillingengine/metrics"
+)
+
+var ErrNotFound = errors.New("borrower not found")
+
+// BucketWrittenOff is the bucket of a loan written off by the date of the aggregate.
+const BucketWrittenOff = engine.ClosedWrittenOff
+
+// Policy decides whether a borrower is eligible for a new loan.
+type Policy struct {
+	MaxActiveLoans int     // MaxActiveLoans a borrower may hold before a new loan, unlimited if zero
+	MaxExposure    float64 // MaxExposure the total exposure must stay below, unlimited if zero
+	MaxBucket      string  // MaxBucket is the worst delinquency bucket allowed, metrics.BucketCurrent if empty
+}
+
+// DefaultPolicy lets a borrower with every loan current hold a second loan.
+var DefaultPolicy = Policy{MaxActiveLoans: 2, MaxBucket: metrics.BucketCurrent}
+
+// Loan is a loan of the borrower at the date of the aggregate.
+type Loan struct {
+	LoanID      string  `json:"loan_id"`
+	Amount      float64 `json:"amount"`
+	Outstanding float64 `json:"outstanding"`
+	Bucket      string  `json:"bucket"`
+}
+
+// Borrower is the aggregate of the loans held by a borrower at a date.
+type Borrower struct {
+	BorrowerID  string    `json:"borrower_id"`
+	AsOf        time.Time `json:"as_of"`
+	Loans       []Loan    `json:"loans"`
+	ActiveLoans int       `json:"active_loans"` // ActiveLoans still with an outstanding
+	Exposure    float64   `json:"exposure"`     // Exposure is the principal lent on the active loans
+	Outstanding float64   `json:"outstanding"`  // Outstanding of every loan combined
+	Penalty     float64   `json:"penalty"`
+	WorstBucket string    `json:"worst_bucket"` // WorstBucket of the active loans, metrics.BucketClosed if there is none
+	WrittenOff  int       `json:"written_off"`  // WrittenOff loans by the date of the aggregate
+	// RecoveryBalance still owed on the written off loans, less what their settlements recovered
+	RecoveryBalance float64  `json:"recovery_balance"`
+	Eligible        bool     `json:"eligible"` // Eligible for a new loan under the policy
+	Reasons         []string `json:"reasons,omitempty"`
+}
+
+// New aggregates the billings of a borrower at asOf and checks its eligibility under the policy.
+func New(borrowerID string, billings []*engine.Billing, asOf time.Time, policy Policy) *Borrower {
+	b := &Borrower{
+		BorrowerID:  borrowerID,
+		AsOf:        asOf,
+		Loans:       []Loan{},
+		WorstBucket: metrics.BucketClosed,
+	}
+	for _, billing := range billings {
+		if billing.IsWrittenOff() && !billing.Closure.Date.After(asOf) {
+			b.Loans = append(b.Loans, Loan{
+				LoanID: billing.Loan.LoanID,
+				Amount: billing.Loan.Amount,
+				Bucket: BucketWrittenOff,
+			})
+			b.WrittenOff++
+			b.RecoveryBalance += billing.RecoveryBalance()
+			continue
+		}
+		position := billing.PositionAsOf(asOf)
+		loan := Loan{
+			LoanID:      billing.Loan.LoanID,
+			Amount:      billing.Loan.Amount,
+			Outstanding: position.Outstanding,
+			Bucket:      metrics.Bucket(billing, asOf),
+		}
+		b.Loans = append(b.Loans, loan)
+		b.Outstanding += position.Outstanding
+		b.Penalty += position.Penalty
+		if loan.Bucket == metrics.BucketClosed {
+			continue
+		}
+		b.ActiveLoans++
+		b.Exposure += loan.Amount
+		if b.WorstBucket == metrics.BucketClosed || rank(loan.Bucket) > rank(b.WorstBucket) {
+			b.WorstBucket = loan.Bucket
+		}
+	}
+	sort.Slice(b.Loans, func(i, j int) bool {
+		return b.Loans[i].LoanID < b.Loans[j].LoanID
+	})
+	b.Reasons = policy.check(b)
+	b.Eligible = len(b.Reasons) == 0
+	return b
+}
+
+// rank is the position of a bucket in metrics.Buckets, the higher the worse.
+func rank(bucket string) int {
+	for i, b := range metrics.Buckets {
+		if b == bucket {
+			return i
+		}
+	}
+	return -1
+}
+
+// check returns the reasons the borrower is not eligible for a new loan, none if it is.
+func (p Policy) check(b *Borrower) []string {
+	maxBucket := p.MaxBucket
+	if maxBucket == "" {
+		maxBucket = metrics.BucketCurrent
+	}
+
+	var reasons []string
+	if b.WrittenOff > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d written off loan(s), %.2f still owed", b.WrittenOff, b.RecoveryBalance))
+	}
+	if b.WorstBucket != metrics.BucketClosed && rank(b.WorstBucket) > rank(maxBucket) {
+		reasons = append(reasons, fmt.Sprintf("a loan is %s days past due", b.WorstBucket))
+	}
+	if p.MaxActiveLoans > 0 && b.ActiveLoans >= p.MaxActiveLoans {
+		reasons = append(reasons, fmt.Sprintf("%d active loan(s), at most %d allowed", b.ActiveLoans, p.MaxActiveLoans))
+	}
+	if p.MaxExposure > 0 && b.Exposure >= p.MaxExposure {
+		reasons = append(reasons, fmt.Sprintf("exposure %.2f reaches the limit of %.2f", b.Exposure, p.MaxExposure))
+	}
+	return reasons
+}
+
+// Aggregate groups the billings by borrower and aggregates each of them, ordered by borrower ID.
+// Loans without borrower ID are left out.
+func Aggregate(billings []*engine.Billing, asOf time.Time, policy Policy) []*Borrower {
+	byBorrower := make(map[string][]*engine.Billing)
+	for _, b := range billings {
+		if b.Loan.BorrowerID == "" {
+			continue
+		}
+		byBorrower[b.Loan.BorrowerID] = append(byBorrower[b.Loan.BorrowerID], b)
+	}
+
+	borrowers := make([]*Borrower, 0, len(byBorrower))
+	for borrowerID, loans := range byBorrower {
+		borrowers = append(borrowers, New(borrowerID, loans, asOf, policy))
+	}
+	sort.Slice(borrowers, func(i, j int) bool {
+		return borrowers[i].BorrowerID < borrowers[j].BorrowerID
+	})
+	return borrowers
+}
+
+// Get aggregates the billings of one borrower, ErrNotFound if the borrower holds none.
+func Get(billings []*engine.Billing, borrowerID string, asOf time.Time, policy Policy) (*Borrower, error) {
+	var loans []*engine.Billing
+	for _, b := range billings {
+		if b.Loan.BorrowerID == borrowerID {
+			loans = append(loans, b)
+		}
+	}
+	if len(loans) == 0 {
+		return nil, ErrNotFound
+	}
+	return New(borrowerID, loans, asOf, policy), nil
+}
+
+// WriteJSON writes the borrowers as indented JSON.
+func WriteJSON(out io.Writer, borrowers []*Borrower) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(borrowers)
+}
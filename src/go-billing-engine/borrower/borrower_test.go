@@ -0,0 +1,106 @@
+package borrower
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gobillingengine/engine"
+	"gobillingengine/engine/enginetest"
+	"gobillingengine/metrics"
+)
+
+func newBilling(t *testing.T, loanID, borrowerID string, amount float64, payments ...float64) *engine.Billing {
+	loan := enginetest.NewLoan(loanID)
+	loan.BorrowerID = borrowerID
+	loan.Amount = amount
+	return enginetest.NewBilling(t, loan, payments...)
+}
+
+func TestAggregate(t *testing.T) {
+	closed := newBilling(t, "1003", "b-2", 1000)
+	closed.Outstanding = 0
+	billings := []*engine.Billing{
+		newBilling(t, "1001", "b-1", 5000, 1, 1),
+		newBilling(t, "1002", "b-1", 2000, 0, 0),
+		closed,
+		newBilling(t, "1004", "b-2", 3000, 1, 1),
+		newBilling(t, "1005", "", 3000),
+	}
+	asOf := time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC)
+
+	borrowers := Aggregate(billings, asOf, DefaultPolicy)
+	require.Len(t, borrowers, 2)
+
+	b1 := borrowers[0]
+	assert.Equal(t, "b-1", b1.BorrowerID)
+	assert.Equal(t, 2, b1.ActiveLoans)
+	assert.Equal(t, 7000.0, b1.Exposure)
+	assert.Equal(t, 7480.0, b1.Outstanding) // 5500 - 2 x 110 and 2200
+	assert.Equal(t, metrics.Bucket8To30, b1.WorstBucket)
+	assert.False(t, b1.Eligible)
+	assert.Equal(t, []string{"a loan is 8-30 days past due", "2 active loan(s), at most 2 allowed"}, b1.Reasons)
+
+	b2 := borrowers[1]
+	assert.Equal(t, 1, b2.ActiveLoans)
+	assert.Equal(t, 3000.0, b2.Exposure)
+	assert.Equal(t, metrics.BucketCurrent, b2.WorstBucket)
+	assert.Equal(t, metrics.BucketClosed, b2.Loans[0].Bucket)
+	assert.True(t, b2.Eligible)
+
+	// a lower exposure limit makes the borrower ineligible
+	b2 = New("b-2", billings[2:4], asOf, Policy{MaxExposure: 3000})
+	assert.Equal(t, []string{"exposure 3000.00 reaches the limit of 3000.00"}, b2.Reasons)
+
+	var out bytes.Buffer
+	require.NoError(t, WriteJSON(&out, borrowers))
+	var decoded []Borrower
+	require.NoError(t, json.Unmarshal(out.Bytes(), &decoded))
+	assert.Equal(t, "b-2", decoded[1].BorrowerID)
+}
+
+func TestNew_WrittenOff(t *testing.T) {
+	writtenOff := newBilling(t, "1001", "b-1", 5000, 1, 1)
+	require.NoError(t, writtenOff.WriteOff(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)))
+	_, err := writtenOff.Settle(2000, 2, time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.NoError(t, writtenOff.PaySettlement(1000, time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)))
+
+	// before the write-off the loan is aggregated as active
+	b := New("b-1", []*engine.Billing{writtenOff}, time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), DefaultPolicy)
+	assert.Equal(t, 1, b.ActiveLoans)
+	assert.Zero(t, b.WrittenOff)
+
+	b = New("b-1", []*engine.Billing{writtenOff}, time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC), DefaultPolicy)
+	assert.Equal(t, BucketWrittenOff, b.Loans[0].Bucket)
+	assert.Zero(t, b.ActiveLoans)
+	assert.Equal(t, metrics.BucketClosed, b.WorstBucket)
+	assert.Equal(t, 1, b.WrittenOff)
+	assert.Equal(t, writtenOff.RecoveryBalance(), b.RecoveryBalance)
+	assert.False(t, b.Eligible)
+	assert.Equal(t, []string{fmt.Sprintf("1 written off loan(s), %.2f still owed", b.RecoveryBalance)}, b.Reasons)
+
+	// an honoured settlement clears the balance, not the write-off
+	require.NoError(t, writtenOff.PaySettlement(1000, time.Date(2024, 3, 18, 0, 0, 0, 0, time.UTC)))
+	b = New("b-1", []*engine.Billing{writtenOff}, time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC), DefaultPolicy)
+	assert.Zero(t, b.RecoveryBalance)
+	assert.Equal(t, []string{"1 written off loan(s), 0.00 still owed"}, b.Reasons)
+}
+
+func TestGet(t *testing.T) {
+	billings := []*engine.Billing{newBilling(t, "1001", "b-1", 5000)}
+	asOf := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	b, err := Get(billings, "b-1", asOf, DefaultPolicy)
+	require.NoError(t, err)
+	assert.Equal(t, metrics.BucketCurrent, b.WorstBucket)
+	assert.True(t, b.Eligible)
+
+	_, err = Get(billings, "b-9", asOf, DefaultPolicy)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
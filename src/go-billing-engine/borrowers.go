@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"gobillingengine/borrower"
+	"gobillingengine/portfolio"
+)
+
+func newBorrowersCmd() *cobra.Command {
+	borrowersCmd := &cobra.Command{
+		Use:   "borrowers",
+		Short: "Report the exposure, outstanding, worst delinquency and loan eligibility of each borrower",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, _ := cmd.Flags().GetString("portfolio")
+			borrowerID, _ := cmd.Flags().GetString("borrower")
+			policy := borrower.DefaultPolicy
+			policy.MaxActiveLoans, _ = cmd.Flags().GetInt("max-active-loans")
+			policy.MaxExposure, _ = cmd.Flags().GetFloat64("max-exposure")
+			policy.MaxBucket, _ = cmd.Flags().GetString("max-bucket")
+
+			asOf := today()
+
+			store, err := portfolio.OpenFileStore(path)
+			if err != nil {
+				return err
+			}
+			billings, err := store.List()
+			if err != nil {
+				return err
+			}
+
+			var borrowers []*borrower.Borrower
+			if borrowerID == "" {
+				borrowers = borrower.Aggregate(billings, asOf, policy)
+			} else {
+				b, err := borrower.Get(billings, borrowerID, asOf, policy)
+				if err != nil {
+					return fmt.Errorf("borrowerID:%s - %v", borrowerID, err)
+				}
+				borrowers = append(borrowers, b)
+			}
+
+			out, closeOut, err := getOutput(cmd)
+			if err != nil {
+				return err
+			}
+			err = borrower.WriteJSON(out, borrowers)
+			if errClose := closeOut(); err == nil {
+				err = errClose
+			}
+			return err
+		},
+	}
+	borrowersCmd.Flags().String("portfolio", "portfolio.json", "The persisted portfolio file")
+	borrowersCmd.Flags().String("borrower", "", "Report only this borrower ID")
+	borrowersCmd.Flags().Int("max-active-loans", borrower.DefaultPolicy.MaxActiveLoans, "The active loans a borrower may hold before a new loan, 0 for unlimited")
+	borrowersCmd.Flags().Float64("max-exposure", borrower.DefaultPolicy.MaxExposure, "The exposure limit of a borrower, 0 for unlimited")
+	borrowersCmd.Flags().String("max-bucket", borrower.DefaultPolicy.MaxBucket, "The worst delinquency bucket allowed for a new loan")
+	borrowersCmd.Flags().StringP("output", "o", "", "Write the report to this file instead of stdout")
+	return borrowersCmd
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"gobillingengine/bureau"
+	"gobillingengine/portfolio"
+)
+
+func newBureauCmd() *cobra.Command {
+	bureauCmd := &cobra.Command{
+		Use:   "bureau",
+		Short: "Export the monthly repayment performance of the portfolio for the credit bureau",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, _ := cmd.Flags().GetString("portfolio")
+			layoutPath, _ := cmd.Flags().GetString("layout")
+			value, _ := cmd.Flags().GetString("month")
+
+			month := today().AddDate(0, 0, 1-today().Day()).AddDate(0, -1, 0)
+			if value != "" {
+				var err error
+				if month, err = time.Parse("2006-01", value); err != nil {
+					return fmt.Errorf("invalid --month %q, expected YYYY-MM", value)
+				}
+			}
+
+			layout := bureau.DefaultLayout
+			if layoutPath != "" {
+				var err error
+				if layout, err = bureau.Load(layoutPath); err != nil {
+					return fmt.Errorf("cannot load layout with err: %v", err)
+				}
+			}
+
+			store, err := portfolio.OpenFileStore(path)
+			if err != nil {
+				return err
+			}
+			billings, err := store.List()
+			if err != nil {
+				return err
+			}
+			records := bureau.Generate(billings, month)
+
+			out, closeOut, err := getOutput(cmd)
+			if err != nil {
+				return err
+			}
+			rejections, err := layout.Write(out, records)
+			if errClose := closeOut(); err == nil {
+				err = errClose
+			}
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.ErrOrStderr(), "%d record(s) exported, %d rejected\n", len(records)-len(rejections), len(rejections))
+			for _, rejection := range rejections {
+				fmt.Fprintf(cmd.ErrOrStderr(), "loanID:%s - %s\n", rejection.LoanID, strings.Join(rejection.Reasons, ", "))
+			}
+			return nil
+		},
+	}
+	bureauCmd.Flags().String("portfolio", "portfolio.json", "The persisted portfolio file")
+	bureauCmd.Flags().String("month", "", "The reporting month (YYYY-MM), the previous month by default")
+	bureauCmd.Flags().String("layout", "", "The YAML layout of the file, a CSV of every field by default")
+	bureauCmd.Flags().StringP("output", "o", "", "Write the file to this path instead of stdout")
+	return bureauCmd
+}
@@ -0,0 +1,136 @@
+package bureau
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gobillingengine/engine"
+	"gobillingengine/engine/enginetest"
+)
+
+func newBilling(t *testing.T, loanID, borrowerID string, weeks int, disbursedAt time.Time, paid int) *engine.Billing {
+	loan := enginetest.NewLoan(loanID)
+	loan.BorrowerID = borrowerID
+	loan.Weeks = weeks
+	loan.DisbursementDate = disbursedAt
+	payments := make([]float64, paid)
+	for i := range payments {
+		payments[i] = 1
+	}
+	return enginetest.NewBilling(t, loan, payments...)
+}
+
+func newRecords(t *testing.T) []Record {
+	jan := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	writtenOff := newBilling(t, "1003", "b-3", 50, jan, 0)
+	require.NoError(t, writtenOff.WriteOff(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)))
+
+	billings := []*engine.Billing{
+		newBilling(t, "1001", "b-1", 50, jan, 12), // paid up to Mar 25
+		newBilling(t, "1002", "b-2", 50, jan, 0),
+		writtenOff,
+		newBilling(t, "1004", "b-4", 1, jan, 1), // closed in January
+		newBilling(t, "1005", "b-5", 50, time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), 0), // disbursed in April
+		newBilling(t, "1006", "", 50, jan, 12),
+	}
+	return Generate(billings, time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC))
+}
+
+func TestGenerate(t *testing.T) {
+	records := newRecords(t)
+	require.Len(t, records, 4)
+
+	march := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, Record{
+		Period:           march,
+		LoanID:           "1001",
+		BorrowerID:       "b-1",
+		DisbursementDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		MaturityDate:     time.Date(2024, 12, 16, 0, 0, 0, 0, time.UTC),
+		Amount:           5000,
+		Outstanding:      4180,
+		Collectibility:   1,
+		Status:           StatusActive,
+	}, records[0])
+
+	assert.Equal(t, 83, records[1].DaysPastDue) // Jan 8 unpaid
+	assert.Equal(t, 2, records[1].Collectibility)
+	assert.Equal(t, 1320.0, records[1].Arrears)
+
+	assert.Equal(t, StatusWrittenOff, records[2].Status)
+	assert.Equal(t, 5, records[2].Collectibility)
+	assert.Equal(t, 66, records[2].DaysPastDue)
+	assert.Equal(t, 5500.0, records[2].WriteOffAmount)
+	assert.Zero(t, records[2].Outstanding)
+
+	assert.Equal(t, []int{1, 2, 2, 3, 4, 4, 5}, []int{
+		Collectibility(0), Collectibility(1), Collectibility(90), Collectibility(91),
+		Collectibility(121), Collectibility(180), Collectibility(181),
+	})
+}
+
+func TestGenerate_Settled(t *testing.T) {
+	b := newBilling(t, "1003", "b-3", 50, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 0)
+	require.NoError(t, b.WriteOff(time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)))
+	_, err := b.Settle(2500, 1, time.Date(2024, 4, 29, 0, 0, 0, 0, time.UTC), time.Date(2024, 5, 31, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.NoError(t, b.PaySettlement(2500, time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)))
+
+	// left out while the settlement is paid, reported settled the month it is honoured
+	assert.Empty(t, Generate([]*engine.Billing{b}, time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)))
+	records := Generate([]*engine.Billing{b}, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC))
+	require.Len(t, records, 1)
+	assert.Equal(t, StatusSettled, records[0].Status)
+	assert.Equal(t, 5500.0, records[0].WriteOffAmount)
+	assert.Empty(t, Generate([]*engine.Billing{b}, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestLayout_WriteCSV(t *testing.T) {
+	var out bytes.Buffer
+	rejections, err := DefaultLayout.Write(&out, newRecords(t))
+	require.NoError(t, err)
+	assert.Equal(t, []Rejection{{LoanID: "1006", Reasons: []string{"borrower_id is missing"}}}, rejections)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 4)
+	assert.Equal(t, "period,loan_id,borrower_id,product_code,disbursement_date,maturity_date,amount,outstanding,arrears,days_past_due,collectibility,status,write_off_amount,write_off_date", lines[0])
+	assert.Equal(t, "2024-03,1001,b-1,,2024-01-01,2024-12-16,5000.00,4180.00,0.00,0,1,active,,", lines[1])
+	assert.Equal(t, "2024-03,1003,b-3,,2024-01-01,2024-12-16,5000.00,0.00,1100.00,66,5,written_off,5500.00,2024-03-15", lines[3])
+}
+
+func TestLayout_WriteFixed(t *testing.T) {
+	layout, err := LoadLayout(strings.NewReader(`
+format: fixed
+columns:
+  - {field: loan_id, width: 6, required: true}
+  - {field: outstanding, width: 10, required: true}
+  - {field: collectibility, width: 1, required: true}
+  - {field: write_off_date, width: 8}
+`))
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	records := newRecords(t)
+	records[0].LoanID = "1001-extra"
+	rejections, err := layout.Write(&out, records)
+	require.NoError(t, err)
+	assert.Equal(t, []Rejection{{LoanID: "1001-extra", Reasons: []string{`loan_id "1001-extra" is longer than 6`}}}, rejections)
+	assert.Equal(t, ""+
+		"1002  00005500002        \n"+
+		"1003  0000000000520240315\n"+
+		"1006  00004180001        \n", out.String())
+}
+
+func TestLoadLayout_Invalid(t *testing.T) {
+	_, err := LoadLayout(strings.NewReader(`format: xml`))
+	assert.EqualError(t, err, `unknown format "xml", expected csv or fixed`)
+	_, err = LoadLayout(strings.NewReader(`columns: [{field: balance}]`))
+	assert.EqualError(t, err, `unknown field "balance" of column idx: 0`)
+	_, err = LoadLayout(strings.NewReader(`{format: fixed, columns: [{field: loan_id}]}`))
+	assert.EqualError(t, err, "width of column loan_id must be positive")
+}
@@ -0,0 +1,278 @@
+package bureau
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatCSV   = "csv"   // FormatCSV delimited columns, amounts with 2 decimals and dates as YYYY-MM-DD
+	FormatFixed = "fixed" // FormatFixed fixed-width columns, amounts in cents and dates as YYYYMMDD
+)
+
+const (
+	FieldPeriod           = "period"
+	FieldLoanID           = "loan_id"
+	FieldBorrowerID       = "borrower_id"
+	FieldProductCode      = "product_code"
+	FieldDisbursementDate = "disbursement_date"
+	FieldMaturityDate     = "maturity_date"
+	FieldAmount           = "amount"
+	FieldOutstanding      = "outstanding"
+	FieldArrears          = "arrears"
+	FieldDaysPastDue      = "days_past_due"
+	FieldCollectibility   = "collectibility"
+	FieldStatus           = "status"
+	FieldWriteOffAmount   = "write_off_amount"
+	FieldWriteOffDate     = "write_off_date"
+)
+
+// Column is a field of the record written in the file.
+type Column struct {
+	Field    string `yaml:"field"`
+	Header   string `yaml:"header"`   // Header of the CSV column, the field name if empty
+	Width    int    `yaml:"width"`    // Width of the fixed-width column
+	Required bool   `yaml:"required"` // Required columns must have a value, a record without is rejected
+}
+
+// Layout is the format of the bureau file.
+type Layout struct {
+	Format    string   `yaml:"format"`
+	Delimiter string   `yaml:"delimiter"` // Delimiter of the CSV columns, a comma if empty
+	Header    bool     `yaml:"header"`    // Header writes a header line in a CSV file
+	Columns   []Column `yaml:"columns"`
+}
+
+// DefaultLayout is a CSV file with a header and every field of the record.
+var DefaultLayout = Layout{
+	Format: FormatCSV,
+	Header: true,
+	Columns: []Column{
+		{Field: FieldPeriod, Width: 6, Required: true},
+		{Field: FieldLoanID, Width: 36, Required: true},
+		{Field: FieldBorrowerID, Width: 36, Required: true},
+		{Field: FieldProductCode, Width: 10},
+		{Field: FieldDisbursementDate, Width: 8, Required: true},
+		{Field: FieldMaturityDate, Width: 8, Required: true},
+		{Field: FieldAmount, Width: 15, Required: true},
+		{Field: FieldOutstanding, Width: 15, Required: true},
+		{Field: FieldArrears, Width: 15},
+		{Field: FieldDaysPastDue, Width: 4, Required: true},
+		{Field: FieldCollectibility, Width: 1, Required: true},
+		{Field: FieldStatus, Width: 11, Required: true},
+		{Field: FieldWriteOffAmount, Width: 15},
+		{Field: FieldWriteOffDate, Width: 8},
+	},
+}
+
+// Rejection is a record left out of the file because it failed validation.
+type Rejection struct {
+	LoanID  string
+	Reasons []string
+}
+
+// LoadLayout reads a YAML layout, the columns of DefaultLayout are used if it lists none.
+func LoadLayout(r io.Reader) (Layout, error) {
+	layout := Layout{Format: FormatCSV, Header: true}
+	if err := yaml.NewDecoder(r).Decode(&layout); err != nil && !errors.Is(err, io.EOF) {
+		return Layout{}, err
+	}
+	if len(layout.Columns) == 0 {
+		layout.Columns = DefaultLayout.Columns
+	}
+	if err := layout.validate(); err != nil {
+		return Layout{}, err
+	}
+	return layout, nil
+}
+
+// Load reads the YAML layout at path.
+func Load(path string) (Layout, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Layout{}, err
+	}
+	defer f.Close()
+	return LoadLayout(f)
+}
+
+func (l Layout) validate() error {
+	switch l.Format {
+	case FormatCSV:
+		if utf8.RuneCountInString(l.Delimiter) > 1 {
+			return fmt.Errorf("delimiter %q must be a single character", l.Delimiter)
+		}
+	case FormatFixed:
+	default:
+		return fmt.Errorf("unknown format %q, expected csv or fixed", l.Format)
+	}
+	for idx, column := range l.Columns {
+		if _, _, ok := (Record{}).value(column.Field, l.Format); !ok {
+			return fmt.Errorf("unknown field %q of column idx: %d", column.Field, idx)
+		}
+		if l.Format == FormatFixed && column.Width <= 0 {
+			return fmt.Errorf("width of column %s must be positive", column.Field)
+		}
+	}
+	return nil
+}
+
+// value returns a field of the record formatted for the file format, and whether it has a value.
+func (r Record) value(field, format string) (value string, present, known bool) {
+	date := func(t time.Time, csvLayout, fixedLayout string) (string, bool, bool) {
+		if t.IsZero() {
+			return "", false, true
+		}
+		if format == FormatFixed {
+			return t.Format(fixedLayout), true, true
+		}
+		return t.Format(csvLayout), true, true
+	}
+	amount := func(a float64) (string, bool, bool) {
+		if format == FormatFixed {
+			return strconv.FormatInt(int64(math.Round(a*100)), 10), true, true
+		}
+		return strconv.FormatFloat(a, 'f', 2, 64), true, true
+	}
+
+	switch field {
+	case FieldPeriod:
+		return date(r.Period, "2006-01", "200601")
+	case FieldLoanID:
+		return r.LoanID, r.LoanID != "", true
+	case FieldBorrowerID:
+		return r.BorrowerID, r.BorrowerID != "", true
+	case FieldProductCode:
+		return r.ProductCode, r.ProductCode != "", true
+	case FieldDisbursementDate:
+		return date(r.DisbursementDate, "2006-01-02", "20060102")
+	case FieldMaturityDate:
+		return date(r.MaturityDate, "2006-01-02", "20060102")
+	case FieldAmount:
+		return amount(r.Amount)
+	case FieldOutstanding:
+		return amount(r.Outstanding)
+	case FieldArrears:
+		return amount(r.Arrears)
+	case FieldDaysPastDue:
+		return strconv.Itoa(r.DaysPastDue), true, true
+	case FieldCollectibility:
+		return strconv.Itoa(r.Collectibility), r.Collectibility >= 1 && r.Collectibility <= 5, true
+	case FieldStatus:
+		return r.Status, r.Status != "", true
+	case FieldWriteOffAmount:
+		if r.WriteOffDate.IsZero() {
+			return "", false, true
+		}
+		return amount(r.WriteOffAmount)
+	case FieldWriteOffDate:
+		return date(r.WriteOffDate, "2006-01-02", "20060102")
+	}
+	return "", false, false
+}
+
+// numeric fields are right aligned and zero padded in a fixed-width file.
+func numeric(field string) bool {
+	switch field {
+	case FieldAmount, FieldOutstanding, FieldArrears, FieldDaysPastDue, FieldCollectibility, FieldWriteOffAmount:
+		return true
+	}
+	return false
+}
+
+// Validate returns why a record cannot be reported in the layout, none if it can.
+func (l Layout) Validate(r Record) []string {
+	var reasons []string
+	for _, column := range l.Columns {
+		value, present, _ := r.value(column.Field, l.Format)
+		if column.Required && !present {
+			reasons = append(reasons, fmt.Sprintf("%s is missing", column.Field))
+			continue
+		}
+		if l.Format == FormatFixed && utf8.RuneCountInString(value) > column.Width {
+			reasons = append(reasons, fmt.Sprintf("%s %q is longer than %d", column.Field, value, column.Width))
+		}
+	}
+	return reasons
+}
+
+// Write writes the records passing validation in the layout and returns the ones rejected.
+func (l Layout) Write(w io.Writer, records []Record) ([]Rejection, error) {
+	var valid []Record
+	var rejections []Rejection
+	for _, r := range records {
+		if reasons := l.Validate(r); len(reasons) > 0 {
+			rejections = append(rejections, Rejection{LoanID: r.LoanID, Reasons: reasons})
+			continue
+		}
+		valid = append(valid, r)
+	}
+
+	if l.Format == FormatFixed {
+		return rejections, l.writeFixed(w, valid)
+	}
+	return rejections, l.writeCSV(w, valid)
+}
+
+func (l Layout) writeCSV(w io.Writer, records []Record) error {
+	cw := csv.NewWriter(w)
+	if l.Delimiter != "" {
+		cw.Comma, _ = utf8.DecodeRuneInString(l.Delimiter)
+	}
+	if l.Header {
+		header := make([]string, len(l.Columns))
+		for i, column := range l.Columns {
+			header[i] = column.Header
+			if header[i] == "" {
+				header[i] = column.Field
+			}
+		}
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+	}
+	for _, r := range records {
+		row := make([]string, len(l.Columns))
+		for i, column := range l.Columns {
+			row[i], _, _ = r.value(column.Field, l.Format)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (l Layout) writeFixed(w io.Writer, records []Record) error {
+	bw := bufio.NewWriter(w)
+	for _, r := range records {
+		var line strings.Builder
+		for _, column := range l.Columns {
+			value, present, _ := r.value(column.Field, l.Format)
+			padding := column.Width - utf8.RuneCountInString(value)
+			switch {
+			case numeric(column.Field) && present:
+				line.WriteString(strings.Repeat("0", padding) + value)
+			default:
+				line.WriteString(value + strings.Repeat(" ", padding))
+			}
+		}
+		line.WriteString("\n")
+		if _, err := bw.WriteString(line.String()); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,129 @@
+// Package bureau exports the monthly repayment performance of the loans to the credit bureau.
+package bureau
+
+import (
+	"sort"
+	"time"
+
+	"gobillingengine/engine"
+)
+
+const (
+	StatusActive     = "active"      // StatusActive the loan is being repaid
+	StatusClosed     = "closed"      // StatusClosed the loan is fully paid
+	StatusRefinanced = "refinanced"  // StatusRefinanced the loan was paid off by a new loan
+	StatusWrittenOff = "written_off" // StatusWrittenOff the loan was written off as a loss
+	StatusSettled    = "settled"     // StatusSettled the written off loan was settled for less than owed
+)
+
+// Record is the performance of a loan over a reporting month, taken at the end of the month.
+type Record struct {
+	Period           time.Time // Period is the first day of the reporting month
+	LoanID           string
+	BorrowerID       string
+	ProductCode      string
+	DisbursementDate time.Time
+	MaturityDate     time.Time
+	Amount           float64
+	Outstanding      float64
+	Arrears          float64
+	DaysPastDue      int
+	Collectibility   int // Collectibility class, 1 (current) to 5 (loss)
+	Status           string
+	WriteOffAmount   float64
+	WriteOffDate     time.Time // WriteOffDate is zero unless the loan is written off
+}
+
+// Collectibility returns the collectibility class of a loan days past due: 1 current, 2 special mention
+// (up to 90 days), 3 substandard (up to 120 days), 4 doubtful (up to 180 days) and 5 loss.
+func Collectibility(daysPastDue int) int {
+	switch {
+	case daysPastDue == 0:
+		return 1
+	case daysPastDue <= 90:
+		return 2
+	case daysPastDue <= 120:
+		return 3
+	case daysPastDue <= 180:
+		return 4
+	default:
+		return 5
+	}
+}
+
+// Generate builds the records of a reporting month from the billing histories. Loans disbursed after the month or
+// closed before it are left out, a loan closed during the month is reported one last time. A written off loan is
+// reported again in the month its settlement is honoured.
+func Generate(billings []*engine.Billing, month time.Time) []Record {
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, -1)
+
+	var records []Record
+	for _, b := range billings {
+		if b.Loan.DisbursementDate.IsZero() || b.Loan.DisbursementDate.After(end) {
+			continue
+		}
+		if b.OutstandingAsOf(start.AddDate(0, 0, -1)) <= 0 && !settledBetween(b, start, end) {
+			continue
+		}
+		records = append(records, newRecord(b, start, end))
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].LoanID < records[j].LoanID
+	})
+	return records
+}
+
+func newRecord(b *engine.Billing, period, asOf time.Time) Record {
+	position := b.PositionAsOf(asOf)
+	r := Record{
+		Period:           period,
+		LoanID:           b.Loan.LoanID,
+		BorrowerID:       b.Loan.BorrowerID,
+		ProductCode:      b.Loan.ProductCode,
+		DisbursementDate: b.Loan.DisbursementDate,
+		MaturityDate:     b.DueDate(b.Loan.Weeks),
+		Amount:           b.Loan.Amount,
+		Outstanding:      position.Outstanding,
+		Arrears:          position.Arrears,
+		DaysPastDue:      position.DaysPastDue,
+		Collectibility:   Collectibility(position.DaysPastDue),
+		Status:           StatusActive,
+	}
+
+	closure := b.Closure
+	switch {
+	case closure != nil && !closure.Date.After(asOf) && closure.Reason == engine.ClosedWrittenOff:
+		// the arrears are reported as they stood when the loan was written off
+		lastDay := closure.Date.AddDate(0, 0, -1)
+		r.Status = StatusWrittenOff
+		r.Arrears = b.Arrears(lastDay)
+		r.DaysPastDue = b.DaysPastDue(lastDay)
+		r.Collectibility = 5
+		r.WriteOffAmount = closure.Outstanding
+		r.WriteOffDate = closure.Date
+		if settled := settledDate(b); !settled.IsZero() && !settled.After(asOf) {
+			r.Status = StatusSettled
+		}
+	case closure != nil && !closure.Date.After(asOf):
+		r.Status = StatusRefinanced
+	case position.Outstanding <= 0:
+		r.Status = StatusClosed
+	}
+	return r
+}
+
+// settledDate returns when the settlement of a written off billing was honoured, zero if none was.
+func settledDate(b *engine.Billing) time.Time {
+	for _, s := range b.Settlements {
+		if s.Status == engine.SettlementHonoured {
+			return s.ClosedDate
+		}
+	}
+	return time.Time{}
+}
+
+func settledBetween(b *engine.Billing, start, end time.Time) bool {
+	settled := settledDate(b)
+	return !settled.IsZero() && !settled.Before(start) && !settled.After(end)
+}
@@ -0,0 +1,75 @@
+// Package clock tells the billing logic what time it is, so the business date can be simulated and tests can control
+// the passing of time.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock returns the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// System is the clock of the machine.
+type System struct{}
+
+func (System) Now() time.Time {
+	return time.Now()
+}
+
+// Fixed is a clock stopped at a time, e.g. the simulated date of a command run with --as-of.
+type Fixed time.Time
+
+func (c Fixed) Now() time.Time {
+	return time.Time(c)
+}
+
+// Manual is a clock moved forward by hand, safe for concurrent use.
+type Manual struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewManual returns a manual clock set at now.
+func NewManual(now time.Time) *Manual {
+	return &Manual{now: now}
+}
+
+func (c *Manual) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set moves the clock to now, back in time if need be.
+func (c *Manual) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// Advance moves the clock forward by d.
+func (c *Manual) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// AdvanceDays moves the clock forward by days calendar days.
+func (c *Manual) AdvanceDays(days int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.AddDate(0, 0, days)
+}
+
+// Today returns the business date of the clock: midnight UTC of the day it is in its own location. The system
+// clock is used if c is nil.
+func Today(c Clock) time.Time {
+	if c == nil {
+		c = System{}
+	}
+	now := c.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
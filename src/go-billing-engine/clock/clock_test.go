@@ -0,0 +1,27 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToday(t *testing.T) {
+	jakarta := time.FixedZone("WIB", 7*60*60)
+	assert.Equal(t, time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), Today(Fixed(time.Date(2024, 3, 2, 1, 30, 0, 0, jakarta))))
+	assert.Equal(t, time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), Today(Fixed(time.Date(2024, 3, 2, 23, 59, 0, 0, time.UTC))))
+
+	now := time.Now()
+	assert.Equal(t, time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC), Today(nil))
+}
+
+func TestManual(t *testing.T) {
+	c := NewManual(time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC))
+	c.AdvanceDays(1)
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), Today(c))
+	c.Advance(12 * time.Hour)
+	assert.Equal(t, time.Date(2024, 2, 2, 0, 0, 0, 0, time.UTC), Today(c))
+	c.Set(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), c.Now())
+}
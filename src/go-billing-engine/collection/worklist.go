@@ -0,0 +1,179 @@
+// Package collection builds the daily worklists of the field officers.
+package collection
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"gobillingengine/engine"
+)
+
+const (
+	ReasonDelinquent = "delinquent" // ReasonDelinquent the borrower missed 2 continuous repayments or more
+	ReasonOverdue    = "overdue"    // ReasonOverdue an installment is past due
+	ReasonDueSoon    = "due_soon"   // ReasonDueSoon the next installment is due within Options.DueWithin
+)
+
+// Unassigned is the officer and area of loans without an assignment.
+const Unassigned = "unassigned"
+
+// Assignment is the field officer and area in charge of a loan.
+type Assignment struct {
+	Officer string `json:"officer"`
+	Area    string `json:"area"`
+}
+
+type Options struct {
+	AsOf        time.Time
+	DueWithin   time.Duration         // DueWithin selects loans due soon, zero leaves them out
+	Assignments map[string]Assignment // Assignments by loan ID
+}
+
+// Item is a loan to visit.
+type Item struct {
+	LoanID        string    `json:"loan_id"`
+	Reason        string    `json:"reason"`
+	DaysPastDue   int       `json:"days_past_due"`
+	MissedPayment int       `json:"missed_payment"`
+	Arrears       float64   `json:"arrears"`
+	AmountAtRisk  float64   `json:"amount_at_risk"` // AmountAtRisk is the outstanding of the loan at the date of the worklist
+	NextDueDate   time.Time `json:"next_due_date"`
+}
+
+// Group is the worklist of an officer in an area, most urgent first.
+type Group struct {
+	Assignment
+	Items []Item `json:"items"`
+}
+
+type Worklist struct {
+	AsOf   time.Time `json:"as_of"`
+	Groups []Group   `json:"groups"`
+}
+
+// Generate selects the delinquent, overdue and soon-due loans among the billings, ranks them by days past due
+// then amount at risk, and groups them by officer and area.
+func Generate(billings []*engine.Billing, opts Options) *Worklist {
+	groups := make(map[Assignment][]Item)
+	for _, b := range billings {
+		item, ok := newItem(b, opts)
+		if !ok {
+			continue
+		}
+		assignment, ok := opts.Assignments[b.Loan.LoanID]
+		if !ok {
+			assignment = Assignment{Officer: Unassigned, Area: Unassigned}
+		}
+		groups[assignment] = append(groups[assignment], item)
+	}
+
+	worklist := &Worklist{AsOf: opts.AsOf, Groups: []Group{}}
+	for assignment, items := range groups {
+		sort.Slice(items, func(i, j int) bool {
+			if items[i].DaysPastDue != items[j].DaysPastDue {
+				return items[i].DaysPastDue > items[j].DaysPastDue
+			}
+			if items[i].AmountAtRisk != items[j].AmountAtRisk {
+				return items[i].AmountAtRisk > items[j].AmountAtRisk
+			}
+			return items[i].LoanID < items[j].LoanID
+		})
+		worklist.Groups = append(worklist.Groups, Group{Assignment: assignment, Items: items})
+	}
+	sort.Slice(worklist.Groups, func(i, j int) bool {
+		gi, gj := worklist.Groups[i], worklist.Groups[j]
+		if gi.Area != gj.Area {
+			return gi.Area < gj.Area
+		}
+		return gi.Officer < gj.Officer
+	})
+	return worklist
+}
+
+func newItem(b *engine.Billing, opts Options) (Item, bool) {
+	outstanding := b.OutstandingAsOf(opts.AsOf)
+	if b.Loan.DisbursementDate.IsZero() || outstanding <= 0 {
+		return Item{}, false
+	}
+
+	item := Item{
+		LoanID:        b.Loan.LoanID,
+		DaysPastDue:   b.DaysPastDue(opts.AsOf),
+		MissedPayment: b.MissedPaymentAsOf(opts.AsOf),
+		Arrears:       b.Arrears(opts.AsOf),
+		AmountAtRisk:  outstanding,
+		NextDueDate:   b.NextDueDateAsOf(opts.AsOf),
+	}
+	switch {
+	case b.IsDelinquentAsOf(opts.AsOf):
+		item.Reason = ReasonDelinquent
+	case item.DaysPastDue > 0:
+		item.Reason = ReasonOverdue
+	case opts.DueWithin > 0 && !item.NextDueDate.Before(opts.AsOf) && !item.NextDueDate.After(opts.AsOf.Add(opts.DueWithin)):
+		item.Reason = ReasonDueSoon
+	default:
+		return Item{}, false
+	}
+	return item, true
+}
+
+// WriteJSON writes the worklist as indented JSON.
+func (w *Worklist) WriteJSON(out io.Writer) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(w)
+}
+
+// WriteCSV writes one row per loan, grouped by area and officer.
+func (w *Worklist) WriteCSV(out io.Writer) error {
+	cw := csv.NewWriter(out)
+	header := []string{"area", "officer", "rank", "loan_id", "reason", "days_past_due", "missed_payment", "arrears", "amount_at_risk", "next_due_date"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, g := range w.Groups {
+		for i, item := range g.Items {
+			err := cw.Write([]string{
+				g.Area,
+				g.Officer,
+				strconv.Itoa(i + 1),
+				item.LoanID,
+				item.Reason,
+				strconv.Itoa(item.DaysPastDue),
+				strconv.Itoa(item.MissedPayment),
+				strconv.FormatFloat(item.Arrears, 'f', 2, 64),
+				strconv.FormatFloat(item.AmountAtRisk, 'f', 2, 64),
+				item.NextDueDate.Format("2006-01-02"),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// LoadAssignments reads loan assignments from a CSV with the columns loan_id, officer and area, with a header row.
+func LoadAssignments(r io.Reader) (map[string]Assignment, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	assignments := make(map[string]Assignment)
+	for idx, row := range rows {
+		if idx == 0 {
+			continue // header
+		}
+		if len(row) != 3 {
+			return nil, fmt.Errorf("expect 3 columns at line %d, got %d", idx+1, len(row))
+		}
+		assignments[row[0]] = Assignment{Officer: row[1], Area: row[2]}
+	}
+	return assignments, nil
+}
@@ -0,0 +1,112 @@
+package collection
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gobillingengine/engine"
+	"gobillingengine/engine/enginetest"
+	"gobillingengine/model"
+)
+
+func newBilling(t *testing.T, loanID string, amount float64, payments ...float64) *engine.Billing {
+	loan := enginetest.NewLoan(loanID)
+	loan.Amount = amount
+	return enginetest.NewBilling(t, loan, payments...)
+}
+
+func TestGenerate(t *testing.T) {
+	billings := []*engine.Billing{
+		newBilling(t, "current", 5000, 1, 1, 1),       // Jan 22 is recorded, but still due at the date of the worklist
+		newBilling(t, "due-soon", 5000, 1, 1),         // next due Jan 22
+		newBilling(t, "overdue", 5000, 1),             // Jan 15 elapsed without record
+		newBilling(t, "delinquent-small", 5000, 0, 0), // missed Jan 8 and Jan 15
+		newBilling(t, "delinquent-big", 9000, 0, 0),
+		newBilling(t, "closed", 5000),
+		engine.NewBilling(model.NewLoan("unscheduled", 50, 5000, 0.1)), // nothing can be due without disbursement
+	}
+	closed := billings[len(billings)-2]
+	closed.Outstanding = 0
+
+	worklist := Generate(billings, Options{
+		AsOf:      time.Date(2024, 1, 21, 0, 0, 0, 0, time.UTC),
+		DueWithin: 2 * 24 * time.Hour,
+		Assignments: map[string]Assignment{
+			"delinquent-small": {Officer: "456", Area: "Bogor"},
+			"delinquent-big":   {Officer: "456", Area: "Bogor"},
+			"overdue":          {Officer: "456", Area: "Bogor"},
+		},
+	})
+
+	require.Len(t, worklist.Groups, 2)
+	bogor := worklist.Groups[0]
+	assert.Equal(t, Assignment{Officer: "456", Area: "Bogor"}, bogor.Assignment)
+	require.Len(t, bogor.Items, 3)
+	assert.Equal(t, "delinquent-big", bogor.Items[0].LoanID)
+	assert.Equal(t, "delinquent-small", bogor.Items[1].LoanID)
+	assert.Equal(t, ReasonDelinquent, bogor.Items[1].Reason)
+	assert.Equal(t, 13, bogor.Items[1].DaysPastDue)
+	assert.Equal(t, 220.0, bogor.Items[1].Arrears)
+	assert.Equal(t, "overdue", bogor.Items[2].LoanID)
+	assert.Equal(t, ReasonOverdue, bogor.Items[2].Reason)
+	assert.Equal(t, 6, bogor.Items[2].DaysPastDue)
+
+	unassigned := worklist.Groups[1]
+	assert.Equal(t, Unassigned, unassigned.Officer)
+	require.Len(t, unassigned.Items, 2)
+	assert.Equal(t, "current", unassigned.Items[0].LoanID)
+	assert.Equal(t, "due-soon", unassigned.Items[1].LoanID)
+	assert.Equal(t, ReasonDueSoon, unassigned.Items[1].Reason)
+	assert.Equal(t, time.Date(2024, 1, 22, 0, 0, 0, 0, time.UTC), unassigned.Items[0].NextDueDate)
+
+	var csvOut bytes.Buffer
+	require.NoError(t, worklist.WriteCSV(&csvOut))
+	lines := strings.Split(strings.TrimSpace(csvOut.String()), "\n")
+	assert.Len(t, lines, 6)
+	assert.Equal(t, "Bogor,456,1,delinquent-big,delinquent,13,2,396.00,9900.00,2024-01-22", lines[1])
+
+	var jsonOut bytes.Buffer
+	require.NoError(t, worklist.WriteJSON(&jsonOut))
+	var decoded Worklist
+	require.NoError(t, json.Unmarshal(jsonOut.Bytes(), &decoded))
+	assert.Equal(t, worklist.Groups[0].Items[0].LoanID, decoded.Groups[0].Items[0].LoanID)
+}
+
+func TestGenerate_BackDated(t *testing.T) {
+	// missed Jan 8 and Jan 15, then paid Jan 22: delinquent on Jan 16 only
+	b := newBilling(t, "1001", 5000, 0, 0, 1)
+	assert.False(t, b.IsDelinquent())
+
+	worklist := Generate([]*engine.Billing{b}, Options{AsOf: time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC)})
+	require.Len(t, worklist.Groups, 1)
+	item := worklist.Groups[0].Items[0]
+	assert.Equal(t, ReasonDelinquent, item.Reason)
+	assert.Equal(t, 2, item.MissedPayment)
+	assert.Equal(t, 8, item.DaysPastDue)
+	// the payment of Jan 22 is not made yet
+	assert.Equal(t, 5500.0, item.AmountAtRisk)
+	assert.Equal(t, time.Date(2024, 1, 22, 0, 0, 0, 0, time.UTC), item.NextDueDate)
+
+	// nor is a loan paid off after the date out of the worklist
+	b.Outstanding = 0
+	worklist = Generate([]*engine.Billing{b}, Options{AsOf: time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC)})
+	require.Len(t, worklist.Groups, 1)
+}
+
+func TestLoadAssignments(t *testing.T) {
+	assignments, err := LoadAssignments(strings.NewReader("loan_id,officer,area\n1001,456,Bogor\n1002,789,Depok\n"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Assignment{
+		"1001": {Officer: "456", Area: "Bogor"},
+		"1002": {Officer: "789", Area: "Depok"},
+	}, assignments)
+
+	_, err = LoadAssignments(strings.NewReader("loan_id,officer,area\n1001,456\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,48 @@
+// Package contract is the integration point between the loan service and the billing engine.
+package contract
+
+import (
+	"context"
+	"time"
+)
+
+// DisbursedLoan carries what the billing engine needs to know about a disbursed loan.
+type DisbursedLoan struct {
+	LoanID           string
+	BorrowerID       string
+	ProductCode      string // ProductCode sets the rate and terms of the loan when the provisioner has a catalog
+	Currency         string // Currency ISO 4217 code of the amounts, the product currency if empty
+	PrincipalAmount  float64
+	Rate             float64
+	TenorWeeks       int
+	DisbursementDate time.Time
+	Investors        []Investor // Investors who funded the loan, repaid pro rata to their amount
+	Fees             []Fee      // Fees of a loan without product, a product brings its own fees
+}
+
+// Fee is a fee charged on the loan, Kind is one of model.FeeDeducted, model.FeeFinanced and model.FeeInstallment.
+type Fee struct {
+	Code   string
+	Kind   string
+	Amount float64
+}
+
+// ProvisionedBilling is the billing the engine set up for a disbursed loan.
+type ProvisionedBilling struct {
+	LoanID             string
+	PayableAmount      float64 // PayableAmount of each installment, fees included
+	Outstanding        float64
+	NetDisbursedAmount float64 // NetDisbursedAmount to hand to the borrower, the principal minus the deducted fees
+}
+
+// Investor is the amount an investor funded a loan with.
+type Investor struct {
+	InvestorID string
+	Amount     float64
+	Currency   string // Currency of the amount, the loan currency if empty
+}
+
+// BillingProvisioner starts repayment tracking for a disbursed loan.
+type BillingProvisioner interface {
+	ProvisionBilling(ctx context.Context, loan *DisbursedLoan) (*ProvisionedBilling, error)
+}
@@ -0,0 +1,150 @@
+package contract
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gobillingengine/distribution"
+	"gobillingengine/engine"
+	"gobillingengine/model"
+	"gobillingengine/money"
+	"gobillingengine/portfolio"
+	"gobillingengine/product"
+)
+
+// LocalProvisioner provisions billings in a portfolio living in the same process.
+type LocalProvisioner struct {
+	Store   portfolio.Store
+	Ledger  *distribution.Ledger // Ledger distributes the payments to the investors, nil to leave them out
+	Catalog *product.Catalog     // Catalog of the products loans can be created from, nil to use the loan rate as is
+}
+
+func NewLocalProvisioner(store portfolio.Store) BillingProvisioner {
+	return &LocalProvisioner{
+		Store: store,
+	}
+}
+
+func (lp *LocalProvisioner) ProvisionBilling(ctx context.Context, loan *DisbursedLoan) (*ProvisionedBilling, error) {
+	if err := validateDisbursedLoan(loan); err != nil {
+		return nil, err
+	}
+
+	l, err := lp.newLoan(loan)
+	if err != nil {
+		return nil, err
+	}
+
+	b := engine.NewBilling(l)
+	investments, err := lp.investments(b, loan)
+	if err != nil {
+		return nil, fmt.Errorf("cannot distribute payments of loanID:%s with err: %w", loan.LoanID, err)
+	}
+	if err := lp.Store.Create(b); err != nil {
+		return nil, fmt.Errorf("cannot provision billing for loanID:%s with err: %w", loan.LoanID, err)
+	}
+	provisioned := &ProvisionedBilling{
+		LoanID:             l.LoanID,
+		PayableAmount:      b.PayableAmount,
+		Outstanding:        b.Outstanding,
+		NetDisbursedAmount: l.NetDisbursedAmount(),
+	}
+
+	if len(investments) == 0 {
+		return provisioned, nil
+	}
+	if _, err := lp.Ledger.Open(b, investments); err != nil {
+		return nil, fmt.Errorf("cannot distribute payments of loanID:%s with err: %w", loan.LoanID, err)
+	}
+	return provisioned, nil
+}
+
+// investments checks the distribution of the billing to the investors of the loan before the billing is created, so
+// that a loan the ledger rejects is not left in the store without one.
+func (lp *LocalProvisioner) investments(b *engine.Billing, loan *DisbursedLoan) ([]distribution.Investment, error) {
+	if lp.Ledger == nil || len(loan.Investors) == 0 {
+		return nil, nil
+	}
+	investments := make([]distribution.Investment, len(loan.Investors))
+	for i, investor := range loan.Investors {
+		investments[i] = distribution.Investment{InvestorID: investor.InvestorID, Amount: investor.Amount, Currency: investor.Currency}
+	}
+	if _, err := distribution.NewDistribution(b, investments, lp.Ledger.FeeRate); err != nil {
+		return nil, err
+	}
+	if _, ok := lp.Ledger.Get(b.Loan.LoanID); ok {
+		return nil, fmt.Errorf("loanID:%s already has a distribution", b.Loan.LoanID)
+	}
+	return investments, nil
+}
+
+func (lp *LocalProvisioner) newLoan(loan *DisbursedLoan) (*model.Loan, error) {
+	if lp.Catalog == nil || loan.ProductCode == "" {
+		l := model.NewLoan(loan.LoanID, loan.TenorWeeks, loan.PrincipalAmount, loan.Rate)
+		l.BorrowerID = loan.BorrowerID
+		l.Currency = loan.Currency
+		l.DisbursementDate = loan.DisbursementDate
+		for _, fee := range loan.Fees {
+			l.Fees = append(l.Fees, model.Fee{Code: fee.Code, Kind: fee.Kind, Amount: fee.Amount})
+		}
+		return l, nil
+	}
+	p, err := lp.Catalog.Get(loan.ProductCode)
+	if err != nil {
+		return nil, err
+	}
+	if loan.Currency != "" && loan.Currency != p.Currency {
+		return nil, fmt.Errorf("%w: loan in %q of product %s in %q", money.ErrCurrencyMismatch, loan.Currency, p.Code, p.Currency)
+	}
+	l, err := p.NewLoan(loan.LoanID, loan.PrincipalAmount, loan.TenorWeeks, loan.DisbursementDate)
+	if err != nil {
+		return nil, err
+	}
+	l.BorrowerID = loan.BorrowerID
+	return l, nil
+}
+
+func validateDisbursedLoan(loan *DisbursedLoan) error {
+	if len(loan.LoanID) == 0 {
+		return errors.New("loanID is empty")
+	}
+	if loan.PrincipalAmount <= 0 {
+		return errors.New("principal amount must be positive")
+	}
+	if loan.TenorWeeks <= 0 {
+		return errors.New("tenor weeks must be positive")
+	}
+	if loan.DisbursementDate.IsZero() {
+		return errors.New("disbursement date is empty")
+	}
+	if loan.Currency != "" {
+		if _, err := money.Lookup(loan.Currency); err != nil {
+			return err
+		}
+	}
+	var deducted float64
+	for idx, fee := range loan.Fees {
+		if fee.Kind != model.FeeDeducted && fee.Kind != model.FeeFinanced && fee.Kind != model.FeeInstallment {
+			return fmt.Errorf("unknown kind %q of fee idx: %d", fee.Kind, idx)
+		}
+		if fee.Amount <= 0 {
+			return fmt.Errorf("fee amount must be positive at idx: %d", idx)
+		}
+		if fee.Kind == model.FeeDeducted {
+			deducted += fee.Amount
+		}
+	}
+	if deducted >= loan.PrincipalAmount {
+		return errors.New("deducted fees exceed the principal amount")
+	}
+	for idx, investor := range loan.Investors {
+		if len(investor.InvestorID) == 0 || investor.Amount <= 0 {
+			return fmt.Errorf("invalid investor at idx: %d", idx)
+		}
+		if investor.Currency != "" && loan.Currency != "" && investor.Currency != loan.Currency {
+			return fmt.Errorf("%w: investor in %q at idx: %d on a loan in %q", money.ErrCurrencyMismatch, investor.Currency, idx, loan.Currency)
+		}
+	}
+	return nil
+}
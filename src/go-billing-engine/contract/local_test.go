@@ -0,0 +1,190 @@
+package contract
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"gobillingengine/distribution"
+	"gobillingengine/engine"
+	"gobillingengine/model"
+	"gobillingengine/money"
+	"gobillingengine/portfolio"
+	"gobillingengine/product"
+)
+
+func TestLocalProvisioner_ProvisionBilling(t *testing.T) {
+	store := portfolio.NewMemoryStore()
+	provisioner := NewLocalProvisioner(store)
+	disbursedAt := time.Date(2024, 5, 11, 12, 5, 0, 0, time.UTC)
+
+	loan := &DisbursedLoan{
+		LoanID:           "1001",
+		BorrowerID:       "b-1",
+		PrincipalAmount:  5000,
+		Rate:             0.1,
+		TenorWeeks:       50,
+		DisbursementDate: disbursedAt,
+	}
+	provisioned, err := provisioner.ProvisionBilling(context.Background(), loan)
+	assert.NoError(t, err)
+	assert.Equal(t, &ProvisionedBilling{LoanID: "1001", PayableAmount: 110, Outstanding: 5500, NetDisbursedAmount: 5000}, provisioned)
+
+	billing, err := store.Get("1001")
+	assert.NoError(t, err)
+	assert.Equal(t, 50, billing.RemainingWeeks)
+	assert.Equal(t, 5000*1.1, billing.Outstanding)
+	assert.Equal(t, disbursedAt, billing.Loan.DisbursementDate)
+	assert.Equal(t, "b-1", billing.Loan.BorrowerID)
+
+	// A loan is only billed once
+	_, err = provisioner.ProvisionBilling(context.Background(), loan)
+	assert.ErrorIs(t, err, portfolio.ErrAlreadyExists)
+
+	// Invalid loans are rejected
+	_, err = provisioner.ProvisionBilling(context.Background(), &DisbursedLoan{LoanID: "1002", PrincipalAmount: 5000})
+	assert.Error(t, err)
+}
+
+func TestLocalProvisioner_ProvisionBilling_Investors(t *testing.T) {
+	store := portfolio.NewMemoryStore()
+	ledger := distribution.NewLedger(0.1)
+	provisioner := &LocalProvisioner{Store: store, Ledger: ledger}
+
+	loan := &DisbursedLoan{
+		LoanID:           "1001",
+		PrincipalAmount:  5000,
+		Rate:             0.1,
+		TenorWeeks:       50,
+		DisbursementDate: time.Date(2024, 5, 11, 0, 0, 0, 0, time.UTC),
+		Investors:        []Investor{{InvestorID: "inv-1", Amount: 4000}, {InvestorID: "inv-2", Amount: 1000}},
+	}
+	_, err := provisioner.ProvisionBilling(context.Background(), loan)
+	assert.NoError(t, err)
+
+	billing, err := store.Get("1001")
+	assert.NoError(t, err)
+	assert.NoError(t, billing.MakePayment(billing.PayableAmount))
+
+	d, ok := ledger.Get("1001")
+	assert.True(t, ok)
+	accounts := d.Accounts()
+	assert.Equal(t, 0.8, accounts[0].Share)
+	assert.Greater(t, accounts[0].PaidOut, 0.0)
+
+	invalid := *loan
+	invalid.LoanID = "1002"
+	invalid.Investors = []Investor{{InvestorID: "inv-1"}}
+	_, err = provisioner.ProvisionBilling(context.Background(), &invalid)
+	assert.EqualError(t, err, "invalid investor at idx: 0")
+
+	// A loan the ledger rejects is not billed
+	distributed := *loan
+	distributed.LoanID = "1003"
+	other := model.NewLoan("1003", 50, 5000, 0.1)
+	_, err = ledger.Open(engine.NewBilling(other), []distribution.Investment{{InvestorID: "inv-3", Amount: 5000}})
+	assert.NoError(t, err)
+	_, err = provisioner.ProvisionBilling(context.Background(), &distributed)
+	assert.EqualError(t, err, "cannot distribute payments of loanID:1003 with err: loanID:1003 already has a distribution")
+	_, err = store.Get("1003")
+	assert.ErrorIs(t, err, portfolio.ErrNotFound)
+}
+
+func TestLocalProvisioner_ProvisionBilling_Product(t *testing.T) {
+	catalog, err := product.LoadCatalog(strings.NewReader(`
+products:
+  - {code: W50, currency: IDR, min_amount: 1000000, max_amount: 10000000, tenors: [50], annual_rate: 0.1}
+`))
+	assert.NoError(t, err)
+	store := portfolio.NewMemoryStore()
+	provisioner := &LocalProvisioner{Store: store, Catalog: catalog}
+
+	loan := &DisbursedLoan{
+		LoanID:           "1001",
+		ProductCode:      "W50",
+		PrincipalAmount:  5000000,
+		Rate:             10, // ignored, the product sets the rate
+		TenorWeeks:       50,
+		DisbursementDate: time.Date(2024, 5, 11, 0, 0, 0, 0, time.UTC),
+	}
+	_, err = provisioner.ProvisionBilling(context.Background(), loan)
+	assert.NoError(t, err)
+
+	billing, err := store.Get("1001")
+	assert.NoError(t, err)
+	assert.Equal(t, "W50", billing.Loan.ProductCode)
+	assert.Equal(t, "IDR", billing.Loan.Currency)
+	assert.Equal(t, 110000.0, billing.PayableAmount)
+
+	loan.LoanID, loan.TenorWeeks = "1002", 25
+	_, err = provisioner.ProvisionBilling(context.Background(), loan)
+	assert.Error(t, err)
+	loan.TenorWeeks, loan.Currency = 50, "USD"
+	_, err = provisioner.ProvisionBilling(context.Background(), loan)
+	assert.EqualError(t, err, `currency mismatch: loan in "USD" of product W50 in "IDR"`)
+	loan.Currency = ""
+	loan.LoanID, loan.ProductCode = "1003", "M12"
+	_, err = provisioner.ProvisionBilling(context.Background(), loan)
+	assert.ErrorIs(t, err, product.ErrUnknownProduct)
+}
+
+func TestLocalProvisioner_ProvisionBilling_Fees(t *testing.T) {
+	store := portfolio.NewMemoryStore()
+	provisioner := NewLocalProvisioner(store)
+
+	loan := &DisbursedLoan{
+		LoanID:           "1001",
+		PrincipalAmount:  5000,
+		Rate:             0.1,
+		TenorWeeks:       50,
+		DisbursementDate: time.Date(2024, 5, 11, 0, 0, 0, 0, time.UTC),
+		Fees: []Fee{
+			{Code: "admin", Kind: model.FeeDeducted, Amount: 50},
+			{Code: "insurance", Kind: model.FeeInstallment, Amount: 2},
+		},
+	}
+	provisioned, err := provisioner.ProvisionBilling(context.Background(), loan)
+	assert.NoError(t, err)
+	assert.Equal(t, 4950.0, provisioned.NetDisbursedAmount)
+	assert.Equal(t, 112.0, provisioned.PayableAmount)
+	assert.Equal(t, 5600.0, provisioned.Outstanding)
+
+	loan.LoanID = "1002"
+	loan.Fees = []Fee{{Code: "admin", Kind: model.FeeDeducted, Amount: 5000}}
+	_, err = provisioner.ProvisionBilling(context.Background(), loan)
+	assert.EqualError(t, err, "deducted fees exceed the principal amount")
+	loan.Fees = []Fee{{Code: "admin", Kind: "monthly", Amount: 10}}
+	_, err = provisioner.ProvisionBilling(context.Background(), loan)
+	assert.EqualError(t, err, `unknown kind "monthly" of fee idx: 0`)
+}
+
+func TestLocalProvisioner_ProvisionBilling_Currency(t *testing.T) {
+	store := portfolio.NewMemoryStore()
+	provisioner := NewLocalProvisioner(store)
+
+	loan := &DisbursedLoan{
+		LoanID:           "1001",
+		Currency:         "IDR",
+		PrincipalAmount:  5000000,
+		Rate:             0.1,
+		TenorWeeks:       50,
+		DisbursementDate: time.Date(2024, 5, 11, 0, 0, 0, 0, time.UTC),
+		Investors:        []Investor{{InvestorID: "inv-1", Amount: 5000000, Currency: "USD"}},
+	}
+	_, err := provisioner.ProvisionBilling(context.Background(), loan)
+	assert.ErrorIs(t, err, money.ErrCurrencyMismatch)
+
+	loan.Currency = "XYZ"
+	_, err = provisioner.ProvisionBilling(context.Background(), loan)
+	assert.EqualError(t, err, `unknown currency "XYZ"`)
+
+	loan.Currency, loan.Investors = "IDR", nil
+	_, err = provisioner.ProvisionBilling(context.Background(), loan)
+	assert.NoError(t, err)
+	billing, err := store.Get("1001")
+	assert.NoError(t, err)
+	assert.Equal(t, "IDR", billing.Loan.Currency)
+}
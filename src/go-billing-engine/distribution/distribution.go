@@ -0,0 +1,299 @@
+// Package distribution splits the borrower payments of a loan between its investors.
+package distribution
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"gobillingengine/engine"
+	"gobillingengine/money"
+)
+
+// Investment is the amount an investor funded a loan with.
+type Investment struct {
+	InvestorID string
+	Amount     float64
+	Currency   string // Currency of the amount, the loan currency if empty
+}
+
+// Account tracks what an investor is owed on a loan.
+type Account struct {
+	InvestorID  string
+	Invested    float64
+	Share       float64 // Share of the loan funded by the investor
+	Receivable  float64 // Receivable is the principal and return owed over the whole schedule
+	PaidOut     float64
+	Outstanding float64 // Outstanding is the receivable not paid out yet
+}
+
+// Payout is the part of a payment distributed to an investor, negative for a reversal.
+type Payout struct {
+	LoanID     string
+	InvestorID string
+	Week       int
+	Principal  float64
+	Return     float64
+	Date       time.Time
+}
+
+// Amount returns the principal and return of the payout.
+func (p Payout) Amount() float64 {
+	return round(p.Principal + p.Return)
+}
+
+// Distribution splits each payment of a billing pro rata to the investment shares. The principal goes back to
+// the investors in full, the interest goes to them minus the platform fee. The loan fees paid with the installments
+// belong to the platform.
+type Distribution struct {
+	LoanID      string
+	Currency    string  // Currency of the loan, the amounts are rounded to its minor unit
+	FeeRate     float64 // FeeRate is the platform fee as a share of the interest
+	PlatformFee float64 // PlatformFee collected so far, loan fees included
+	Payouts     []Payout
+
+	mu             sync.Mutex
+	rate           float64
+	repricings     []engine.Event // repricings of the billing, the rate of the payments due from their date on
+	installmentFee float64
+	accounts       []*Account
+}
+
+// NewDistribution prepares the distribution of a billing to its investors.
+func NewDistribution(b *engine.Billing, investments []Investment, feeRate float64) (*Distribution, error) {
+	if len(investments) == 0 {
+		return nil, errors.New("a distribution needs at least one investment")
+	}
+	if feeRate < 0 || feeRate > 1 {
+		return nil, fmt.Errorf("platform fee rate %v must be between 0 and 1", feeRate)
+	}
+
+	var invested float64
+	for idx, investment := range investments {
+		if investment.InvestorID == "" {
+			return nil, fmt.Errorf("investorID is empty at idx: %d", idx)
+		}
+		if investment.Amount <= 0 {
+			return nil, fmt.Errorf("investment amount must be positive at idx: %d", idx)
+		}
+		if investment.Currency != "" && investment.Currency != b.Loan.Currency {
+			return nil, fmt.Errorf("%w: investment in %q at idx: %d on a loan in %q",
+				money.ErrCurrencyMismatch, investment.Currency, idx, b.Loan.Currency)
+		}
+		invested += investment.Amount
+	}
+
+	d := &Distribution{
+		LoanID:   b.Loan.LoanID,
+		Currency: b.Loan.Currency,
+		FeeRate:  feeRate,
+		rate:     b.Loan.FlatInterestRate,
+	}
+	d.installmentFee = d.round(b.InstallmentFee())
+	// the receivables are those of the loan rate, moved by the repricings of the billing so far
+	scheduled := b.ScheduledTotal()
+	var repriced float64
+	for _, r := range b.Repricings {
+		scheduled -= r.Outstanding
+		net, _ := d.splitInterest(r.Outstanding)
+		repriced += net
+		d.repricings = append(d.repricings, engine.Event{Type: engine.EventRepriced, Date: r.EffectiveDate, Rate: r.NewRate})
+	}
+	principal, interest, _ := d.split(scheduled-d.installmentFee*float64(b.Loan.Weeks), b.Loan.DisbursementDate)
+	interest += repriced
+	for _, investment := range investments {
+		share := investment.Amount / invested
+		receivable := d.round(share * (principal + interest))
+		d.accounts = append(d.accounts, &Account{
+			InvestorID:  investment.InvestorID,
+			Invested:    investment.Amount,
+			Share:       share,
+			Receivable:  receivable,
+			Outstanding: receivable,
+		})
+	}
+	return d, nil
+}
+
+// split returns the principal, the interest net of the fee, and the fee of an amount paid by the borrower for
+// the period due at date, loan fees excluded.
+func (d *Distribution) split(amount float64, date time.Time) (principal, interest, fee float64) {
+	principal = d.round(amount / (1 + d.rateAt(date)))
+	interest, fee = d.splitInterest(amount - principal)
+	return principal, interest, fee
+}
+
+// splitInterest returns the interest net of the fee and the fee of an interest amount.
+func (d *Distribution) splitInterest(amount float64) (interest, fee float64) {
+	interest = d.round(amount)
+	fee = d.round(interest * d.FeeRate)
+	return d.round(interest - fee), fee
+}
+
+// rateAt returns the rate of the installment of the period due at date.
+func (d *Distribution) rateAt(date time.Time) float64 {
+	rate := d.rate
+	for _, r := range d.repricings {
+		if !r.Date.After(date) {
+			rate = r.Rate
+		}
+	}
+	return rate
+}
+
+// Reprice moves the receivables with the interest a repricing of the billing adds or takes off, net of the fee,
+// and splits the payments due from its date at its rate.
+func (d *Distribution) Reprice(event engine.Event) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.repricings = append(d.repricings, event)
+	interest, _ := d.splitInterest(event.Amount)
+	for _, account := range d.accounts {
+		account.Receivable = d.round(account.Receivable + interest*account.Share)
+		account.Outstanding = d.round(account.Receivable - account.PaidOut)
+	}
+}
+
+// Distribute pays out a payment to the investors. Rounding leftovers go to the largest investor
+// so the payouts add up to the payment net of the fee.
+func (d *Distribution) Distribute(payment *engine.Payment) []Payout {
+	return d.distribute(payment, 1)
+}
+
+// Reverse takes back the payouts of a reversed payment.
+func (d *Distribution) Reverse(payment *engine.Payment) []Payout {
+	return d.distribute(payment, -1)
+}
+
+func (d *Distribution) distribute(payment *engine.Payment, sign float64) []Payout {
+	if payment.Amount == 0 {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	principal, interest, fee := d.split(payment.Amount-d.installmentFee, payment.Date)
+	fee = d.round(fee + d.installmentFee)
+	payouts := make([]Payout, len(d.accounts))
+	largest := 0
+	var paidPrincipal, paidReturn float64
+	for i, account := range d.accounts {
+		payouts[i] = Payout{
+			LoanID:     d.LoanID,
+			InvestorID: account.InvestorID,
+			Week:       payment.Week,
+			Principal:  d.round(principal * account.Share),
+			Return:     d.round(interest * account.Share),
+			Date:       payment.Date,
+		}
+		paidPrincipal += payouts[i].Principal
+		paidReturn += payouts[i].Return
+		if account.Share > d.accounts[largest].Share {
+			largest = i
+		}
+	}
+	payouts[largest].Principal = d.round(payouts[largest].Principal + principal - paidPrincipal)
+	payouts[largest].Return = d.round(payouts[largest].Return + interest - paidReturn)
+
+	for i, account := range d.accounts {
+		payouts[i].Principal *= sign
+		payouts[i].Return *= sign
+		account.PaidOut = d.round(account.PaidOut + payouts[i].Amount())
+		account.Outstanding = d.round(account.Receivable - account.PaidOut)
+	}
+	d.PlatformFee = d.round(d.PlatformFee + sign*fee)
+	d.Payouts = append(d.Payouts, payouts...)
+	return payouts
+}
+
+// Notify distributes the payments received by the billing the distribution subscribed to, takes back the reversed
+// ones, and follows its repricings.
+func (d *Distribution) Notify(event engine.Event) {
+	switch event.Type {
+	case engine.EventPaymentReceived:
+		d.Distribute(&engine.Payment{Week: event.Week, Amount: event.Amount, Date: event.Date})
+	case engine.EventPaymentReversed:
+		d.Reverse(&engine.Payment{Week: event.Week, Amount: event.Amount, Date: event.Date})
+	case engine.EventRepriced:
+		d.Reprice(event)
+	}
+}
+
+// Accounts returns a copy of the investor accounts, in investment order.
+func (d *Distribution) Accounts() []Account {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	accounts := make([]Account, len(d.accounts))
+	for i, account := range d.accounts {
+		accounts[i] = *account
+	}
+	return accounts
+}
+
+// Ledger keeps the distributions of the loans, by loan ID.
+type Ledger struct {
+	FeeRate float64
+
+	mu            sync.RWMutex
+	distributions map[string]*Distribution
+}
+
+func NewLedger(feeRate float64) *Ledger {
+	return &Ledger{
+		FeeRate:       feeRate,
+		distributions: make(map[string]*Distribution),
+	}
+}
+
+// Open starts distributing the payments of a billing to its investors.
+func (l *Ledger) Open(b *engine.Billing, investments []Investment) (*Distribution, error) {
+	d, err := NewDistribution(b, investments, l.FeeRate)
+	if err != nil {
+		return nil, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.distributions[d.LoanID]; ok {
+		return nil, fmt.Errorf("loanID:%s already has a distribution", d.LoanID)
+	}
+	l.distributions[d.LoanID] = d
+	b.Subscribe(d)
+	return d, nil
+}
+
+// Get returns the distribution of a loan.
+func (l *Ledger) Get(loanID string) (*Distribution, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	d, ok := l.distributions[loanID]
+	return d, ok
+}
+
+// InvestorAccounts returns the accounts of an investor across the loans, by loan ID.
+func (l *Ledger) InvestorAccounts(investorID string) map[string]Account {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	accounts := make(map[string]Account)
+	for loanID, d := range l.distributions {
+		for _, account := range d.Accounts() {
+			if account.InvestorID == investorID {
+				accounts[loanID] = account
+			}
+		}
+	}
+	return accounts
+}
+
+// round rounds an amount to the minor unit of the loan currency, to the cent for a loan of an unnamed currency.
+func (d *Distribution) round(amount float64) float64 {
+	if d.Currency == "" {
+		return round(amount)
+	}
+	return money.Round(amount, d.Currency)
+}
+
+func round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
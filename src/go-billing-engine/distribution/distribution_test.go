@@ -0,0 +1,155 @@
+package distribution
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gobillingengine/engine"
+	"gobillingengine/engine/enginetest"
+	"gobillingengine/model"
+)
+
+func TestDistribute(t *testing.T) {
+	billing := enginetest.NewBilling(t, enginetest.NewLoan("100"))
+	d, err := NewDistribution(billing, []Investment{
+		{InvestorID: "inv-1", Amount: 2000},
+		{InvestorID: "inv-2", Amount: 2000},
+		{InvestorID: "inv-3", Amount: 2000},
+	}, 0.2)
+	require.NoError(t, err)
+
+	// a 110 installment is 100 of principal and 10 of interest, of which 2 is the platform fee
+	payouts := d.Distribute(&engine.Payment{Week: 1, Amount: 110, Date: billing.DueDate(1)})
+	require.Len(t, payouts, 3)
+	// the first investor takes the rounding leftovers of the thirds
+	assert.Equal(t, Payout{LoanID: "100", InvestorID: "inv-1", Week: 1, Principal: 33.34, Return: 2.66, Date: billing.DueDate(1)}, payouts[0])
+	assert.Equal(t, Payout{LoanID: "100", InvestorID: "inv-2", Week: 1, Principal: 33.33, Return: 2.67, Date: billing.DueDate(1)}, payouts[1])
+	assert.Equal(t, 108.0, payouts[0].Amount()+payouts[1].Amount()+payouts[2].Amount())
+	assert.Equal(t, 2.0, d.PlatformFee)
+
+	accounts := d.Accounts()
+	assert.Equal(t, Account{
+		InvestorID:  "inv-1",
+		Invested:    2000,
+		Share:       1.0 / 3,
+		Receivable:  1800,
+		PaidOut:     36,
+		Outstanding: 1764,
+	}, accounts[0])
+
+	d.Reverse(&engine.Payment{Week: 1, Amount: 110, Date: billing.DueDate(1)})
+	assert.Zero(t, d.Accounts()[0].PaidOut)
+	assert.Zero(t, d.PlatformFee)
+	assert.Len(t, d.Payouts, 6)
+
+	// missed payments are not distributed
+	assert.Nil(t, d.Distribute(&engine.Payment{Week: 1, Date: billing.DueDate(1)}))
+}
+
+func TestDistribute_Repriced(t *testing.T) {
+	billing := enginetest.NewBilling(t, enginetest.NewLoan("100"))
+	billing.Loan.VariableRate = true
+	ledger := NewLedger(0.2)
+	d, err := ledger.Open(billing, []Investment{{InvestorID: "inv-1", Amount: 2500}, {InvestorID: "inv-2", Amount: 2500}})
+	require.NoError(t, err)
+	require.NoError(t, billing.MakePayment(110))
+
+	// 49 weeks left at 20% instead of 10%: 490 more interest, 392 net of the fee
+	require.NoError(t, billing.ChangeRate(0.2, billing.DueDate(2)))
+	assert.Equal(t, Account{InvestorID: "inv-1", Invested: 2500, Share: 0.5, Receivable: 2896, PaidOut: 54, Outstanding: 2842},
+		d.Accounts()[0])
+
+	// a 120 installment is 100 of principal and 20 of interest, of which 4 is the platform fee
+	require.NoError(t, billing.MakePayment(120))
+	assert.Equal(t, Payout{LoanID: "100", InvestorID: "inv-2", Week: 2, Principal: 50, Return: 8, Date: billing.DueDate(2)},
+		d.Payouts[len(d.Payouts)-1])
+	assert.Equal(t, 6.0, d.PlatformFee)
+
+	// a distribution opened on the repriced billing starts from the same receivables
+	reopened, err := NewDistribution(billing, []Investment{{InvestorID: "inv-1", Amount: 2500}, {InvestorID: "inv-2", Amount: 2500}}, 0.2)
+	require.NoError(t, err)
+	assert.Equal(t, 2896.0, reopened.Accounts()[0].Receivable)
+}
+
+func TestNewDistribution_Invalid(t *testing.T) {
+	billing := enginetest.NewBilling(t, enginetest.NewLoan("100"))
+	_, err := NewDistribution(billing, nil, 0.1)
+	assert.Error(t, err)
+	_, err = NewDistribution(billing, []Investment{{InvestorID: "inv-1", Amount: 0}}, 0.1)
+	assert.EqualError(t, err, "investment amount must be positive at idx: 0")
+	_, err = NewDistribution(billing, []Investment{{InvestorID: "inv-1", Amount: 100}}, 1.5)
+	assert.Error(t, err)
+}
+
+func TestLedger(t *testing.T) {
+	ledger := NewLedger(0)
+	billing := enginetest.NewBilling(t, enginetest.NewLoan("100"))
+	_, err := ledger.Open(billing, []Investment{{InvestorID: "inv-1", Amount: 2500}, {InvestorID: "inv-2", Amount: 2500}})
+	require.NoError(t, err)
+	_, err = ledger.Open(billing, []Investment{{InvestorID: "inv-1", Amount: 5000}})
+	assert.Error(t, err)
+
+	// payments posted on the billing are distributed through the subscription
+	require.NoError(t, billing.MakePayment(billing.PayableAmount))
+	require.NoError(t, billing.MakePayment(0))
+
+	d, ok := ledger.Get("100")
+	require.True(t, ok)
+	require.Len(t, d.Payouts, 2)
+	assert.Equal(t, 110.0, d.Payouts[0].Amount()+d.Payouts[1].Amount())
+
+	accounts := ledger.InvestorAccounts("inv-2")
+	assert.Equal(t, 55.0, accounts["100"].PaidOut)
+	assert.Equal(t, 2695.0, accounts["100"].Outstanding)
+
+	// a payment reversed on the billing is taken back from the investors
+	_, err = billing.ReversePayment()
+	require.NoError(t, err)
+	_, err = billing.ReversePayment()
+	require.NoError(t, err)
+	require.Len(t, d.Payouts, 4)
+	assert.Equal(t, -55.0, d.Payouts[3].Amount())
+	accounts = ledger.InvestorAccounts("inv-2")
+	assert.Zero(t, accounts["100"].PaidOut)
+	assert.Equal(t, 2750.0, accounts["100"].Outstanding)
+}
+
+func TestDistribute_LoanFees(t *testing.T) {
+	billing := enginetest.NewBilling(t, enginetest.NewLoan("100"))
+	billing.Loan.Fees = []model.Fee{{Code: "insurance", Kind: model.FeeInstallment, Amount: 2}}
+	billing = engine.NewBilling(billing.Loan)
+	d, err := NewDistribution(billing, []Investment{{InvestorID: "inv-1", Amount: 5000}}, 0)
+	require.NoError(t, err)
+
+	// the fee part of the installment goes to the platform
+	payouts := d.Distribute(&engine.Payment{Week: 1, Amount: billing.PayableAmount, Date: billing.DueDate(1)})
+	assert.Equal(t, 110.0, payouts[0].Amount())
+	assert.Equal(t, 2.0, d.PlatformFee)
+	assert.Equal(t, 5500.0, d.Accounts()[0].Receivable)
+}
+
+func TestDistribute_Currency(t *testing.T) {
+	loan := model.NewLoan("100", 50, 5000000, 0.1)
+	loan.Currency = "IDR"
+	billing := engine.NewBilling(loan)
+
+	_, err := NewDistribution(billing, []Investment{{InvestorID: "inv-1", Amount: 5000000, Currency: "USD"}}, 0.2)
+	assert.EqualError(t, err, `currency mismatch: investment in "USD" at idx: 0 on a loan in "IDR"`)
+
+	d, err := NewDistribution(billing, []Investment{
+		{InvestorID: "inv-1", Amount: 2000000, Currency: "IDR"},
+		{InvestorID: "inv-2", Amount: 2000000},
+		{InvestorID: "inv-3", Amount: 2000000},
+	}, 0.2)
+	require.NoError(t, err)
+	assert.Equal(t, "IDR", d.Currency)
+
+	// rupiah payouts are whole, the largest investor takes the leftovers
+	payouts := d.Distribute(&engine.Payment{Week: 1, Amount: 110000, Date: billing.DueDate(1)})
+	assert.Equal(t, 33334.0, payouts[0].Principal)
+	assert.Equal(t, 33333.0, payouts[1].Principal)
+	assert.Equal(t, 2667.0, payouts[1].Return)
+	assert.Equal(t, 108000.0, payouts[0].Amount()+payouts[1].Amount()+payouts[2].Amount())
+}
@@ -0,0 +1,95 @@
+package engine
+
+import "time"
+
+// Position is the state of a billing at a date, rebuilt from the payment history.
+type Position struct {
+	AsOf           time.Time
+	Outstanding    float64 // Outstanding balance of the installments, penalties excluded
+	Penalty        float64 // Penalty charged up to the date
+	MissedPayment  int     // MissedPayment number of continuous missed payments recorded up to the date
+	RemainingWeeks int
+	Arrears        float64
+	DaysPastDue    int
+	Delinquent     bool
+}
+
+// history returns the payments of the periods due on or before asOf, oldest first.
+// A payment is dated by the due date of its period, so it counts from that date on.
+func (b *Billing) history(asOf time.Time) []*Payment {
+	payments := b.Payments()
+	for i, payment := range payments {
+		if payment.Date.After(asOf) {
+			return payments[:i]
+		}
+	}
+	return payments
+}
+
+// remainingWeeks returns the weeks of the schedule left unpaid after the given payments.
+func remainingWeeks(weeks int, payments []*Payment) int {
+	for _, payment := range payments {
+		if payment.Amount > 0 {
+			weeks--
+		}
+	}
+	return weeks
+}
+
+// OutstandingAsOf returns the outstanding balance at the end of asOf. The repricings effective after asOf are left out.
+func (b *Billing) OutstandingAsOf(asOf time.Time) float64 {
+	outstanding := b.Outstanding
+	if b.Closure != nil {
+		if !asOf.Before(b.Closure.Date) {
+			return 0
+		}
+		outstanding += b.Closure.Outstanding
+	}
+	for _, payment := range b.Payments() {
+		if payment.Date.After(asOf) {
+			outstanding += payment.Amount
+		}
+	}
+	for _, r := range b.Repricings {
+		if r.EffectiveDate.After(asOf) {
+			outstanding -= r.Outstanding
+		}
+	}
+	return outstanding
+}
+
+// MissedPaymentAsOf returns the number of continuous missed payments recorded up to asOf.
+func (b *Billing) MissedPaymentAsOf(asOf time.Time) int {
+	payments := b.history(asOf)
+	missed := 0
+	for i := len(payments) - 1; i >= 0 && payments[i].Amount == 0; i-- {
+		missed++
+	}
+	return missed
+}
+
+// IsDelinquentAsOf checks if the borrower was delinquent at asOf, like IsDelinquent does for the current state.
+func (b *Billing) IsDelinquentAsOf(asOf time.Time) bool {
+	return b.MissedPaymentAsOf(asOf) >= b.delinquentAfter()
+}
+
+// PositionAsOf returns the outstanding, arrears and delinquency status of the billing at asOf.
+func (b *Billing) PositionAsOf(asOf time.Time) Position {
+	var penalty float64
+	for _, p := range b.Penalties {
+		if !p.Date.After(asOf) {
+			penalty += p.Amount
+		}
+	}
+
+	return Position{
+		AsOf:           asOf,
+		Outstanding:    b.OutstandingAsOf(asOf),
+		Penalty:        penalty,
+		MissedPayment:  b.MissedPaymentAsOf(asOf),
+		RemainingWeeks: remainingWeeks(b.Loan.Weeks, b.history(asOf)),
+		Arrears:        b.Arrears(asOf),
+		DaysPastDue:    b.DaysPastDue(asOf),
+		Delinquent:     b.IsDelinquentAsOf(asOf),
+	}
+}
@@ -0,0 +1,83 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gobillingengine/model"
+)
+
+func TestPositionAsOf(t *testing.T) {
+	loan := model.NewLoan("1001", 50, 5000, 0.1)
+	loan.DisbursementDate = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	billing := NewBilling(loan)
+
+	// paid Jan 8, missed Jan 15 and Jan 22, paid Jan 29 and Feb 5
+	for _, amount := range []float64{110, 0, 0, 110, 110} {
+		require.NoError(t, billing.MakePayment(amount))
+	}
+	require.NoError(t, billing.ChargePenalty(5)) // dated Feb 5
+
+	// the current state is unchanged by the queries
+	assert.Equal(t, 5170.0, billing.GetOutstanding())
+	assert.False(t, billing.IsDelinquent())
+
+	before := billing.PositionAsOf(time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, 5500.0, before.Outstanding)
+	assert.Equal(t, 50, before.RemainingWeeks)
+	assert.False(t, before.Delinquent)
+
+	delinquent := billing.PositionAsOf(time.Date(2024, 1, 24, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, Position{
+		AsOf:           time.Date(2024, 1, 24, 0, 0, 0, 0, time.UTC),
+		Outstanding:    5390,
+		MissedPayment:  2,
+		RemainingWeeks: 49,
+		Arrears:        220,
+		DaysPastDue:    9,
+		Delinquent:     true,
+	}, delinquent)
+
+	cured := billing.PositionAsOf(time.Date(2024, 1, 29, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, 5280.0, cured.Outstanding)
+	assert.Zero(t, cured.MissedPayment)
+	assert.Zero(t, cured.Arrears)
+	assert.False(t, cured.Delinquent)
+	assert.Zero(t, cured.Penalty)
+
+	latest := billing.PositionAsOf(time.Date(2024, 2, 5, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, 5170.0, latest.Outstanding)
+	assert.Equal(t, 5.0, latest.Penalty)
+	assert.Equal(t, 47, latest.RemainingWeeks)
+}
+
+func TestOutstandingAsOf_Closed(t *testing.T) {
+	loan := model.NewLoan("1001", 1, 5000, 0.1)
+	loan.DisbursementDate = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	billing := NewBilling(loan)
+	require.NoError(t, billing.MakePayment(billing.PayableAmount))
+
+	assert.Equal(t, 5500.0, billing.OutstandingAsOf(time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC)))
+	assert.Zero(t, billing.OutstandingAsOf(time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestOutstandingAsOf_Repriced(t *testing.T) {
+	loan := model.NewLoan("1001", 50, 5000, 0.1)
+	loan.DisbursementDate = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	loan.VariableRate = true
+	billing := NewBilling(loan)
+	require.NoError(t, billing.MakePayment(110))
+	require.NoError(t, billing.MakePayment(110))
+	require.NoError(t, billing.ChangeRate(0.2, billing.DueDate(3)))
+	assert.Equal(t, 5760.0, billing.GetOutstanding())
+
+	// the 48 weeks left cost 10 more each from the effective date on
+	assert.Equal(t, 5280.0, billing.OutstandingAsOf(billing.DueDate(2)))
+	assert.Equal(t, 5760.0, billing.OutstandingAsOf(billing.DueDate(3)))
+	require.NoError(t, billing.MakePayment(120))
+	assert.Equal(t, 5390.0, billing.OutstandingAsOf(billing.DueDate(1)))
+	assert.Equal(t, 5640.0, billing.PositionAsOf(billing.DueDate(3)).Outstanding)
+}
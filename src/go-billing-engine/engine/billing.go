@@ -5,35 +5,68 @@ import (
 	"fmt"
 	"github.com/emirpasic/gods/stacks"
 	lls "github.com/emirpasic/gods/stacks/linkedliststack"
+	"gobillingengine/clock"
 	"gobillingengine/model"
+	"gobillingengine/money"
+	"math"
+	"time"
 )
 
 type Payment struct {
 	Week   int
 	Amount float64
+	Date   time.Time // Date is the due date of the weekly period the payment was made in
+	Fee    float64   // Fee part of the amount, set on the schedule entries
+}
+
+// Penalty is a charge on top of the installments, e.g. a late fee for a missed payment.
+type Penalty struct {
+	Week   int
+	Amount float64
+	Date   time.Time
 }
 
 type Billing struct {
 	Loan           *model.Loan
-	PayableAmount  float64      // PayableAmount of each payment needs to be made
-	Outstanding    float64      // Outstanding balance of the loan
-	MissedPayment  int          // MissedPayment number of continuous missed payments (Some customers may miss repayments, If they miss 2 continuous repayments they are delinquent borrowers.)
-	RemainingWeeks int          // RemainingWeeks of outstanding amoutn
-	PaymentRecord  stacks.Stack // PaymentRecord of paid load
+	PayableAmount  float64       // PayableAmount of each payment needs to be made
+	Outstanding    float64       // Outstanding balance of the loan
+	MissedPayment  int           // MissedPayment number of continuous missed payments (Some customers may miss repayments, If they miss 2 continuous repayments they are delinquent borrowers.)
+	RemainingWeeks int           // RemainingWeeks of outstanding amoutn
+	PaymentRecord  stacks.Stack  // PaymentRecord of paid load
+	Penalties      []*Penalty    // Penalties charged on the loan, oldest first
+	Closure        *Closure      // Closure of the billing before the end of its schedule, nil while it runs
+	RateChanges    []*RateChange // RateChanges of a variable-rate loan not effective yet, oldest first
+	Repricings     []*Repricing  // Repricings of the remaining schedule by the rate changes, oldest first
+	Settlements    []*Settlement // Settlements agreed on a written off billing, oldest first
+	Clock          clock.Clock   // Clock tells the billing what day it is, the system clock if nil
+
+	subscribers []Subscriber
 }
 
+// NewBilling starts the billing of a loan. The outstanding and the installments are rounded to the minor unit of the
+// loan currency, the last installment takes the rounding remainder.
 func NewBilling(loan *model.Loan) *Billing {
-	outstanding := loan.Amount * loan.FlatInterestRate
-	payableAmount := outstanding / float64(loan.Weeks)
+	outstanding := loan.Amount * (1 + loan.FlatInterestRate) // flat interest over the whole term
+	outstanding += loan.FeeTotal(model.FeeFinanced) + loan.FeeTotal(model.FeeInstallment)*float64(loan.Weeks)
 
-	return &Billing{
+	b := &Billing{
 		Loan:           loan,
-		PayableAmount:  payableAmount,
-		Outstanding:    outstanding,
+		Outstanding:    money.Round(outstanding, loan.Currency),
 		MissedPayment:  0,
 		RemainingWeeks: loan.Weeks,
 		PaymentRecord:  lls.New(),
 	}
+	b.PayableAmount = b.payableAmount(loan.FlatInterestRate)
+	return b
+}
+
+// DueAmount returns the amount of the next installment: the payable amount, or what is left of the outstanding for
+// the last one.
+func (b *Billing) DueAmount() float64 {
+	if b.RemainingWeeks <= 1 || b.PayableAmount > b.Outstanding {
+		return b.Outstanding
+	}
+	return b.PayableAmount
 }
 
 // GenerateLoanSchedule generates the billing schedule for the loan.
@@ -42,7 +75,8 @@ func (b *Billing) GenerateLoanSchedule() *lls.Stack {
 	for week := b.Loan.Weeks; week > 0; week-- {
 		loanSchedule.Push(&Payment{
 			Week:   week,
-			Amount: b.PayableAmount,
+			Amount: b.installment(week),
+			Fee:    b.InstallmentFee(),
 		})
 	}
 	return loanSchedule
@@ -53,53 +87,146 @@ func (b *Billing) GenerateRemainingLoanSchedule() *lls.Stack {
 	for week := b.Loan.Weeks; week > (b.Loan.Weeks - b.RemainingWeeks); week-- {
 		loanSchedule.Push(&Payment{
 			Week:   week,
-			Amount: b.PayableAmount,
+			Amount: b.installment(week),
+			Fee:    b.InstallmentFee(),
 		})
 	}
 	return loanSchedule
 }
 
+// installment returns the installment of a week: the amount paid for a paid week, the payable amount of the period it
+// falls due in when the coming periods are paid otherwise, rate changes included.
+func (b *Billing) installment(week int) float64 {
+	paid := b.Loan.Weeks - b.RemainingWeeks
+	if week <= paid {
+		for _, payment := range b.Payments() {
+			if payment.Week == week && payment.Amount > 0 {
+				return payment.Amount
+			}
+		}
+	}
+	if week == paid+1 && b.RemainingWeeks == 1 {
+		return b.DueAmount() // the last one takes the rounding remainder
+	}
+	return b.PayableAmountAt(b.DueDate(b.PaymentRecord.Size() + week - paid))
+}
+
 func (b *Billing) PrintPayment(s *lls.Stack) (string, error) {
 	var text string
 	for !s.Empty() {
 		if val, ok := s.Pop(); !ok {
-			errors.New("expect stack element is not empty")
+			return text, errors.New("expect stack element is not empty")
 		} else {
 			bill := val.(*Payment)
-			text += fmt.Sprintf("Week: %d, Payable amount: %f\n", bill.Week, bill.Amount)
+			text += fmt.Sprintf("Week: %d, Payable amount: %f", bill.Week, bill.Amount)
+			if bill.Fee > 0 {
+				text += fmt.Sprintf(" (fee %f)", bill.Fee)
+			}
+			text += "\n"
 		}
 	}
 	return text, nil
 }
 
+// InstallmentFee returns the part of each installment paying the financed and per-installment fees.
+func (b *Billing) InstallmentFee() float64 {
+	return b.Loan.FeeTotal(model.FeeFinanced)/float64(b.Loan.Weeks) + b.Loan.FeeTotal(model.FeeInstallment)
+}
+
 // GetOutstanding returns the current outstanding balance on the loan.
 func (b *Billing) GetOutstanding() float64 {
 	return b.Outstanding
 }
 
-// IsDelinquent checks if the borrower is delinquent (missed 2 continuous repayments).
+// IsDelinquent checks if the borrower is delinquent (missed 2 continuous repayments, or the product's policy).
 func (b *Billing) IsDelinquent() bool {
-	return b.MissedPayment >= 2
+	return b.MissedPayment >= b.delinquentAfter()
+}
+
+func (b *Billing) delinquentAfter() int {
+	if b.Loan == nil || b.Loan.DelinquentAfter <= 0 {
+		return model.DefaultDelinquentAfter
+	}
+	return b.Loan.DelinquentAfter
 }
 
 func (b *Billing) ResetMissedPayment() {
 	b.MissedPayment = 0
 }
 
-// MakePayment makes a payment of a certain amount on the loan.
+// ErrPaidOff is returned for a miss recorded on a billing with nothing left to pay, a payment exceeds its outstanding.
+var ErrPaidOff = errors.New("billing is paid off")
+
+// MakePayment makes a payment of a certain amount on the loan, in the loan currency. The amount must be the due
+// amount, rounded to the minor unit of the currency if the loan has one, or 0 for a missed payment.
 func (b *Billing) MakePayment(amount float64) error {
-	if amount > b.Outstanding {
+	if b.IsClosed() {
+		return ErrClosed
+	}
+	due := b.DueAmount()
+	wasDelinquent := b.IsDelinquent()
+	switch {
+	case amount == 0 && b.Outstanding <= 0:
+		return ErrPaidOff
+	case amount == 0:
+		b.makeZeroPayment()
+	case b.Round(amount) == b.Round(due) || math.Abs(amount-due) < drift:
+		b.makePayablePayment(due)
+	case amount > b.Outstanding:
 		return errors.New("payment exceeds outstanding amount")
+	default:
+		return errors.New(fmt.Sprintf("payment should be %f or 0", due))
 	}
-	if amount == b.PayableAmount {
-		b.makePayablePayment(amount)
-		return nil
+	b.applyRateChanges() // the rate changes effective by the next period reprice it before it comes due
+	b.emitTransitions(b.lastPayment(), wasDelinquent)
+	return nil
+}
+
+// MakePaymentIn makes a payment of an amount in a currency, which must be the currency of the loan.
+func (b *Billing) MakePaymentIn(amount money.Money) error {
+	if amount.Currency != b.Loan.Currency {
+		return fmt.Errorf("%w: payment in %q on a loan in %q", money.ErrCurrencyMismatch, amount.Currency, b.Loan.Currency)
 	}
-	if amount == 0 {
-		b.makeZeroPayment()
-		return nil
+	return b.MakePayment(amount.Amount)
+}
+
+// Round rounds an amount to the minor unit of the loan currency, an amount of an unnamed currency is left as it is.
+func (b *Billing) Round(amount float64) float64 {
+	return money.Round(amount, b.Loan.Currency)
+}
+
+// ReversePayment undoes the latest payment, paid or missed, and returns it.
+func (b *Billing) ReversePayment() (*Payment, error) {
+	if b.IsClosed() {
+		return nil, ErrClosed
+	}
+	val, ok := b.PaymentRecord.Pop()
+	if !ok {
+		return nil, errors.New("no payment to reverse")
+	}
+	payment := val.(*Payment)
+	b.unapplyRateChanges() // repriced from the next period, undone before the payment of the period before
+	if payment.Amount > 0 {
+		b.Outstanding += payment.Amount
+		b.RemainingWeeks += 1
+		b.emit(EventPaymentReversed, payment.Week, payment.Amount, payment.Date)
 	}
-	return errors.New(fmt.Sprintf("payment should be %f or 0", b.PayableAmount))
+
+	// the continuous missed payments are the trailing misses of what is left in the history
+	wasDelinquent := b.IsDelinquent()
+	b.ResetMissedPayment()
+	payments := b.Payments()
+	for i := len(payments) - 1; i >= 0 && payments[i].Amount == 0; i-- {
+		b.MissedPayment += 1
+	}
+
+	switch {
+	case !wasDelinquent && b.IsDelinquent():
+		b.emit(EventBecameDelinquent, payment.Week, 0, payment.Date)
+	case wasDelinquent && !b.IsDelinquent():
+		b.emit(EventCured, payment.Week, 0, payment.Date)
+	}
+	return payment, nil
 }
 
 func (b *Billing) makeZeroPayment() {
@@ -109,9 +236,9 @@ func (b *Billing) makeZeroPayment() {
 
 func (b *Billing) makePayablePayment(amount float64) {
 	b.Outstanding -= amount
-	b.ResetMissedPayment() // assumption: resetting the continuous missed payments when payment occur
+	b.ResetMissedPayment()  // assumption: resetting the continuous missed payments when payment occur
+	b.recordPayment(amount) // recorded before moving on so the payment carries the week it pays
 	b.RemainingWeeks -= 1
-	b.recordPayment(amount)
 }
 
 func (b *Billing) recordPayment(paidAmount float64) {
@@ -119,6 +246,55 @@ func (b *Billing) recordPayment(paidAmount float64) {
 	payment := &Payment{
 		Week:   week,
 		Amount: paidAmount,
+		Date:   b.DueDate(b.PaymentRecord.Size() + 1),
 	}
 	b.PaymentRecord.Push(payment)
 }
+
+func (b *Billing) lastPayment() *Payment {
+	val, _ := b.PaymentRecord.Peek()
+	return val.(*Payment)
+}
+
+// DueDate returns the due date of the given weekly period, counted from the disbursement date.
+// Every MakePayment call, paid or missed, settles one period.
+func (b *Billing) DueDate(period int) time.Time {
+	return b.Loan.DisbursementDate.AddDate(0, 0, 7*period)
+}
+
+// Payments returns the payment history, oldest first.
+func (b *Billing) Payments() []*Payment {
+	values := b.PaymentRecord.Values() // top of the stack first
+	payments := make([]*Payment, len(values))
+	for i, val := range values {
+		payments[len(values)-1-i] = val.(*Payment)
+	}
+	return payments
+}
+
+// ChargePenalty charges a penalty in the current period. Penalties are tracked apart from the installments
+// so the exact payable amount of each week is unaffected.
+func (b *Billing) ChargePenalty(amount float64) error {
+	if amount <= 0 {
+		return errors.New("penalty must be positive")
+	}
+	if b.IsClosed() {
+		return ErrClosed
+	}
+	period := b.PaymentRecord.Size()
+	b.Penalties = append(b.Penalties, &Penalty{
+		Week:   (b.Loan.Weeks - b.RemainingWeeks) + 1,
+		Amount: amount,
+		Date:   b.DueDate(period),
+	})
+	return nil
+}
+
+// GetPenalty returns the total of penalties charged on the loan.
+func (b *Billing) GetPenalty() float64 {
+	var total float64
+	for _, p := range b.Penalties {
+		total += p.Amount
+	}
+	return total
+}
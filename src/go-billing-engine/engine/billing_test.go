@@ -3,11 +3,13 @@ package engine
 import (
 	"errors"
 	"testing"
+	"time"
 
 	lls "github.com/emirpasic/gods/stacks/linkedliststack"
 	"github.com/stretchr/testify/assert"
 
 	"gobillingengine/model"
+	"gobillingengine/money"
 )
 
 func TestNewBilling(t *testing.T) {
@@ -21,14 +23,36 @@ func TestNewBilling(t *testing.T) {
 
 	assert.NotNil(t, billing)
 	assert.Equal(t, loan, billing.Loan)
-	assert.Equal(t, 5000*0.1, billing.Outstanding)
-	assert.Equal(t, 5000*0.1/float64(50), billing.PayableAmount)
+	assert.Equal(t, 5000*1.1, billing.Outstanding)
+	assert.Equal(t, 5000*1.1/float64(50), billing.PayableAmount)
 	assert.Zero(t, billing.MissedPayment)
 	assert.Equal(t, loan.Weeks, billing.RemainingWeeks)
 	assert.NotNil(t, billing.PaymentRecord)
 	assert.IsType(t, &lls.Stack{}, billing.PaymentRecord)
 }
 
+func TestNewBilling_Fees(t *testing.T) {
+	loan := model.NewLoan("1001", 50, 5000, 0.1)
+	loan.Fees = []model.Fee{
+		{Code: "admin", Kind: model.FeeDeducted, Amount: 50},
+		{Code: "origination", Kind: model.FeeFinanced, Amount: 100},
+		{Code: "insurance", Kind: model.FeeInstallment, Amount: 2},
+	}
+	billing := NewBilling(loan)
+
+	// the deducted fee is kept at disbursement, the others are repaid with the installments
+	assert.Equal(t, 4950.0, loan.NetDisbursedAmount())
+	assert.Equal(t, 5700.0, billing.Outstanding)
+	assert.Equal(t, 114.0, billing.PayableAmount)
+	assert.Equal(t, 4.0, billing.InstallmentFee())
+
+	val, _ := billing.GenerateLoanSchedule().Peek()
+	assert.Equal(t, &Payment{Week: 1, Amount: 114, Fee: 4}, val)
+	text, err := billing.PrintPayment(billing.GenerateRemainingLoanSchedule())
+	assert.NoError(t, err)
+	assert.Contains(t, text, "Week: 1, Payable amount: 114.000000 (fee 4.000000)")
+}
+
 func TestGenerateLoanSchedule(t *testing.T) {
 	loan := &model.Loan{
 		LoanID:           "1001",
@@ -91,7 +115,7 @@ func TestMakePayment(t *testing.T) {
 	// Test case 2: Invalid payment amount
 	err = billing.MakePayment(20)
 	assert.Error(t, err)
-	assert.Equal(t, errors.New("payment should be 10.000000 or 0"), err)
+	assert.Equal(t, errors.New("payment should be 110.000000 or 0"), err)
 
 	// Test case 3: Payable payment
 	err = billing.MakePayment(billing.PayableAmount)
@@ -137,3 +161,170 @@ func TestIsDelinquent(t *testing.T) {
 	billing.MissedPayment = 2
 	assert.True(t, billing.IsDelinquent())
 }
+
+func TestPayments(t *testing.T) {
+	disbursedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	loan := model.NewLoan("1001", 50, 5000, 0.1)
+	loan.DisbursementDate = disbursedAt
+	billing := NewBilling(loan)
+
+	assert.NoError(t, billing.MakePayment(billing.PayableAmount))
+	assert.NoError(t, billing.MakePayment(0))
+	assert.NoError(t, billing.MakePayment(billing.PayableAmount))
+
+	payments := billing.Payments()
+	assert.Len(t, payments, 3)
+	assert.Equal(t, &Payment{Week: 1, Amount: 110, Date: disbursedAt.AddDate(0, 0, 7)}, payments[0])
+	assert.Equal(t, &Payment{Week: 2, Amount: 0, Date: disbursedAt.AddDate(0, 0, 14)}, payments[1])
+	assert.Equal(t, &Payment{Week: 2, Amount: 110, Date: disbursedAt.AddDate(0, 0, 21)}, payments[2])
+}
+
+func TestChargePenalty(t *testing.T) {
+	loan := model.NewLoan("1001", 50, 5000, 0.1)
+	billing := NewBilling(loan)
+
+	assert.Error(t, billing.ChargePenalty(0))
+
+	assert.NoError(t, billing.MakePayment(0))
+	assert.NoError(t, billing.ChargePenalty(5))
+	assert.NoError(t, billing.ChargePenalty(2.5))
+
+	assert.Len(t, billing.Penalties, 2)
+	assert.Equal(t, billing.DueDate(1), billing.Penalties[0].Date)
+	assert.Equal(t, 7.5, billing.GetPenalty())
+	// penalties do not change the installment outstanding
+	assert.Equal(t, 5500.0, billing.GetOutstanding())
+}
+
+func TestReversePayment(t *testing.T) {
+	loan := model.NewLoan("1001", 50, 5000, 0.1)
+	billing := NewBilling(loan)
+
+	_, err := billing.ReversePayment()
+	assert.Error(t, err)
+
+	assert.NoError(t, billing.MakePayment(0))
+	assert.NoError(t, billing.MakePayment(0))
+	assert.NoError(t, billing.MakePayment(billing.PayableAmount))
+	assert.False(t, billing.IsDelinquent())
+
+	// Reversing the payment restores the balance and the delinquency
+	payment, err := billing.ReversePayment()
+	assert.NoError(t, err)
+	assert.Equal(t, 110.0, payment.Amount)
+	assert.Equal(t, 5500.0, billing.Outstanding)
+	assert.Equal(t, 50, billing.RemainingWeeks)
+	assert.Equal(t, 2, billing.MissedPayment)
+	assert.True(t, billing.IsDelinquent())
+
+	// Reversing a missed payment
+	payment, err = billing.ReversePayment()
+	assert.NoError(t, err)
+	assert.Zero(t, payment.Amount)
+	assert.Equal(t, 1, billing.MissedPayment)
+	assert.Equal(t, 1, billing.PaymentRecord.Size())
+}
+
+type recordingSubscriber struct {
+	events []Event
+}
+
+func (r *recordingSubscriber) Notify(event Event) {
+	r.events = append(r.events, event)
+}
+
+func TestSubscribe(t *testing.T) {
+	loan := model.NewLoan("1001", 50, 5000, 0.1)
+	billing := NewBilling(loan)
+	subscriber := &recordingSubscriber{}
+	billing.Subscribe(subscriber)
+
+	assert.NoError(t, billing.MakePayment(0))
+	assert.NoError(t, billing.MakePayment(0))
+	assert.Equal(t, EventBecameDelinquent, subscriber.events[1].Type)
+	assert.Equal(t, 2, subscriber.events[1].MissedPayment)
+
+	// reversing a miss cures the delinquency
+	subscriber.events = nil
+	_, err := billing.ReversePayment()
+	assert.NoError(t, err)
+	assert.Equal(t, []Event{{Type: EventCured, LoanID: "1001", Week: 1, Outstanding: 5500, MissedPayment: 1, Date: billing.DueDate(2)}}, subscriber.events)
+
+	// reversing a payment emits the amount taken back
+	assert.NoError(t, billing.MakePayment(billing.PayableAmount))
+	subscriber.events = nil
+	_, err = billing.ReversePayment()
+	assert.NoError(t, err)
+	assert.Equal(t, []Event{{Type: EventPaymentReversed, LoanID: "1001", Week: 1, Amount: 110, Outstanding: 5500, MissedPayment: 0,
+		Date: billing.DueDate(2)}}, subscriber.events)
+
+	// rejected payments emit nothing
+	subscriber.events = nil
+	assert.Error(t, billing.MakePayment(3))
+	assert.Empty(t, subscriber.events)
+}
+
+func TestMakePaymentIn(t *testing.T) {
+	loan := model.NewLoan("1001", 50, 1000000, 0.055)
+	loan.Currency = "IDR"
+	billing := NewBilling(loan)
+	assert.Equal(t, 21100.0, billing.PayableAmount)
+
+	err := billing.MakePaymentIn(money.Money{Amount: 21100, Currency: "USD"})
+	assert.ErrorIs(t, err, money.ErrCurrencyMismatch)
+	assert.EqualError(t, err, `currency mismatch: payment in "USD" on a loan in "IDR"`)
+	assert.NoError(t, billing.MakePaymentIn(money.Money{Amount: 21100, Currency: "IDR"}))
+	assert.Equal(t, 49, billing.RemainingWeeks)
+}
+
+func TestMakePayment_MinorUnits(t *testing.T) {
+	loan := model.NewLoan("1001", 3, 100, 0)
+	loan.Currency = "USD"
+	billing := NewBilling(loan)
+
+	// 33.333... is paid as 33.33, the payable amount is what is recorded
+	assert.NoError(t, billing.MakePayment(33.33))
+	assert.Equal(t, billing.PayableAmount, billing.Payments()[0].Amount)
+	assert.Error(t, billing.MakePayment(33.3))
+
+	// the last installment takes the rounding remainder
+	assert.NoError(t, billing.MakePayment(33.33))
+	assert.Equal(t, 33.34, billing.DueAmount())
+	assert.EqualError(t, billing.MakePayment(33.33), "payment should be 33.340000 or 0")
+	assert.NoError(t, billing.MakePayment(33.34))
+	assert.Zero(t, billing.Outstanding)
+
+	// a loan of an unnamed currency takes the exact amount only
+	unnamed := NewBilling(model.NewLoan("1002", 3, 100, 0))
+	assert.Error(t, unnamed.MakePayment(33.33))
+}
+
+func TestMakePayment_PaidOff(t *testing.T) {
+	loan := model.NewLoan("1001", 3, 1000000, 0.1)
+	loan.Currency = "IDR"
+	billing := NewBilling(loan)
+	assert.Equal(t, 366667.0, billing.PayableAmount)
+
+	// 366667 twice, then the 366666 left
+	assert.NoError(t, billing.MakePayment(366667))
+	assert.NoError(t, billing.MakePayment(billing.PayableAmount))
+	assert.Equal(t, 366666.0, billing.DueAmount())
+	assert.NoError(t, billing.MakePayment(366666))
+	assert.Zero(t, billing.Outstanding)
+	assert.Zero(t, billing.RemainingWeeks)
+
+	// nothing more can be paid or missed, and nothing is emitted
+	subscriber := &recordingSubscriber{}
+	billing.Subscribe(subscriber)
+	assert.ErrorIs(t, billing.MakePayment(0), ErrPaidOff)
+	assert.EqualError(t, billing.MakePayment(366666), "payment exceeds outstanding amount")
+	assert.Equal(t, 3, billing.PaymentRecord.Size())
+	assert.Empty(t, subscriber.events)
+
+	// an unnamed currency pays its float installments to zero too
+	unnamed := NewBilling(model.NewLoan("1002", 3, 1000000, 0.1))
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, unnamed.MakePayment(unnamed.PayableAmount))
+	}
+	assert.Zero(t, unnamed.Outstanding)
+}
@@ -0,0 +1,57 @@
+package engine
+
+import "time"
+
+// NextDueDate returns the due date of the first period without a payment record.
+func (b *Billing) NextDueDate() time.Time {
+	return b.DueDate(b.PaymentRecord.Size() + 1)
+}
+
+// NextDueDateAsOf returns the due date of the first period without a payment record at asOf.
+func (b *Billing) NextDueDateAsOf(asOf time.Time) time.Time {
+	return b.DueDate(len(b.history(asOf)) + 1)
+}
+
+// overdue returns the number of installments due before asOf and not paid, and the due date of the oldest one.
+// They are the trailing misses of the history up to asOf plus the periods elapsed since the last recorded one.
+// Nothing is due on a loan without disbursement date.
+func (b *Billing) overdue(asOf time.Time) (count int, oldest time.Time) {
+	if b.Loan.DisbursementDate.IsZero() || b.OutstandingAsOf(asOf) <= 0 {
+		return 0, time.Time{}
+	}
+
+	payments := b.history(asOf)
+	for i := len(payments) - 1; i >= 0 && payments[i].Amount == 0; i-- {
+		count++
+		oldest = payments[i].Date
+	}
+	for period := len(payments) + 1; b.DueDate(period).Before(asOf); period++ {
+		if count == 0 {
+			oldest = b.DueDate(period)
+		}
+		count++
+	}
+	if remaining := remainingWeeks(b.Loan.Weeks, payments); count > remaining {
+		count = remaining
+	}
+	return count, oldest
+}
+
+// DaysPastDue returns how many days the oldest unpaid installment is overdue at asOf, 0 if none is.
+func (b *Billing) DaysPastDue(asOf time.Time) int {
+	count, oldest := b.overdue(asOf)
+	if count == 0 || !oldest.Before(asOf) {
+		return 0
+	}
+	return int(asOf.Sub(oldest).Hours() / 24)
+}
+
+// Arrears returns the amount of the installments overdue at asOf, each at the rate of its period.
+func (b *Billing) Arrears(asOf time.Time) float64 {
+	count, oldest := b.overdue(asOf)
+	var arrears float64
+	for i := 0; i < count; i++ {
+		arrears += b.PayableAmountAt(oldest.AddDate(0, 0, 7*i)) // the overdue periods are consecutive
+	}
+	return arrears
+}
@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gobillingengine/model"
+)
+
+func TestDaysPastDue(t *testing.T) {
+	loan := model.NewLoan("1001", 50, 5000, 0.1)
+	loan.DisbursementDate = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	billing := NewBilling(loan)
+
+	// nothing is due before the first due date
+	assert.Equal(t, time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC), billing.NextDueDate())
+	assert.Equal(t, time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC), billing.NextDueDateAsOf(time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)))
+	assert.Zero(t, billing.DaysPastDue(time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)))
+	assert.Zero(t, billing.Arrears(time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)))
+
+	// a period elapsed without any record is overdue
+	assert.Equal(t, 2, billing.DaysPastDue(time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 110.0, billing.Arrears(time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)))
+
+	// paid week 1 on Jan 8, missed Jan 15 and Jan 22
+	assert.NoError(t, billing.MakePayment(110))
+	assert.NoError(t, billing.MakePayment(0))
+	assert.NoError(t, billing.MakePayment(0))
+	asOf := time.Date(2024, 1, 30, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, 15, billing.DaysPastDue(asOf))
+	assert.Equal(t, 330.0, billing.Arrears(asOf)) // Jan 15, Jan 22 and Jan 29
+
+	// like MissedPayment, a payment clears the overdue installments, the missed weeks move to the end of the schedule
+	assert.NoError(t, billing.MakePayment(110))
+	assert.Zero(t, billing.DaysPastDue(asOf))
+	assert.Zero(t, billing.Arrears(asOf))
+}
+
+func TestArrears_Repriced(t *testing.T) {
+	loan := model.NewLoan("1001", 50, 5000, 0.1)
+	loan.DisbursementDate = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	loan.VariableRate = true
+	billing := NewBilling(loan)
+	require.NoError(t, billing.MakePayment(0))
+	require.NoError(t, billing.ChangeRate(0.2, billing.DueDate(2)))
+
+	// missed Jan 8 at 110, Jan 15 elapsed at the new 120
+	assert.Equal(t, 230.0, billing.Arrears(time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestDaysPastDue_Unscheduled(t *testing.T) {
+	billing := NewBilling(model.NewLoan("1001", 50, 5000, 0.1))
+
+	assert.Zero(t, billing.DaysPastDue(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)))
+	assert.Zero(t, billing.Arrears(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestDaysPastDue_Closed(t *testing.T) {
+	loan := model.NewLoan("1001", 1, 5000, 0.1)
+	billing := NewBilling(loan)
+	assert.NoError(t, billing.MakePayment(billing.PayableAmount))
+
+	assert.Zero(t, billing.DaysPastDue(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)))
+	assert.Zero(t, billing.Arrears(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)))
+}
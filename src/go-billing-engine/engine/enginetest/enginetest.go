@@ -0,0 +1,36 @@
+// Package enginetest builds the billings the tests of the packages working on a portfolio start from.
+package enginetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"gobillingengine/engine"
+	"gobillingengine/model"
+)
+
+// DisbursedAt is the disbursement date of NewLoan, its installments fall due every Monday from 8 January 2024.
+var DisbursedAt = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// NewLoan returns a loan of 5000 at 10% over 50 weeks disbursed at DisbursedAt, repaid in installments of 110.
+func NewLoan(loanID string) *model.Loan {
+	loan := model.NewLoan(loanID, 50, 5000, 0.1)
+	loan.DisbursementDate = DisbursedAt
+	return loan
+}
+
+// NewBilling returns the billing of the loan after the payments, in period order: 0 misses the period and any other
+// amount pays its installment.
+func NewBilling(t testing.TB, loan *model.Loan, payments ...float64) *engine.Billing {
+	t.Helper()
+	b := engine.NewBilling(loan)
+	for _, p := range payments {
+		if p > 0 {
+			p = b.DueAmount()
+		}
+		require.NoError(t, b.MakePayment(p))
+	}
+	return b
+}
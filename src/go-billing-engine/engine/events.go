@@ -0,0 +1,92 @@
+package engine
+
+import "time"
+
+// EventType names a billing status transition.
+type EventType string
+
+const (
+	EventInstallmentDue   EventType = "installment_due"
+	EventPaymentReceived  EventType = "payment_received"
+	EventPaymentReversed  EventType = "payment_reversed"
+	EventBecameDelinquent EventType = "became_delinquent"
+	EventCured            EventType = "cured"
+	EventClosed           EventType = "closed"
+	EventRepriced         EventType = "repriced"
+)
+
+// Event is emitted by a Billing to its subscribers when its status changes.
+type Event struct {
+	Type          EventType `json:"type"`
+	LoanID        string    `json:"loan_id"`
+	Week          int       `json:"week"`
+	Amount        float64   `json:"amount"`         // Amount paid or due, the change of the outstanding for EventRepriced
+	Rate          float64   `json:"rate,omitempty"` // Rate of the installments from the event date on, set on EventRepriced
+	Outstanding   float64   `json:"outstanding"`
+	MissedPayment int       `json:"missed_payment"`
+	Date          time.Time `json:"date"`
+}
+
+// Subscriber is notified of the events of the billings it subscribed to. Notify is called synchronously
+// while the billing is being updated, so implementations must not block.
+type Subscriber interface {
+	Notify(event Event)
+}
+
+// Subscribe registers a subscriber for the events of the billing, a subscriber already registered is not added twice.
+func (b *Billing) Subscribe(s Subscriber) {
+	for _, subscriber := range b.subscribers {
+		if subscriber == s {
+			return
+		}
+	}
+	b.subscribers = append(b.subscribers, s)
+}
+
+func (b *Billing) emit(eventType EventType, week int, amount float64, date time.Time) {
+	b.notify(b.event(eventType, week, amount, date))
+}
+
+// emitRepricing emits the repricing of the installments due from date at rate.
+func (b *Billing) emitRepricing(week int, amount, rate float64, date time.Time) {
+	event := b.event(EventRepriced, week, amount, date)
+	event.Rate = rate
+	b.notify(event)
+}
+
+func (b *Billing) event(eventType EventType, week int, amount float64, date time.Time) Event {
+	return Event{
+		Type:          eventType,
+		LoanID:        b.Loan.LoanID,
+		Week:          week,
+		Amount:        amount,
+		Outstanding:   b.Outstanding,
+		MissedPayment: b.MissedPayment,
+		Date:          date,
+	}
+}
+
+func (b *Billing) notify(event Event) {
+	for _, s := range b.subscribers {
+		s.Notify(event)
+	}
+}
+
+// emitTransitions emits the events following a change of the payment history.
+func (b *Billing) emitTransitions(payment *Payment, wasDelinquent bool) {
+	if payment.Amount > 0 {
+		b.emit(EventPaymentReceived, payment.Week, payment.Amount, payment.Date)
+	}
+	switch {
+	case !wasDelinquent && b.IsDelinquent():
+		b.emit(EventBecameDelinquent, payment.Week, 0, payment.Date)
+	case wasDelinquent && !b.IsDelinquent():
+		b.emit(EventCured, payment.Week, 0, payment.Date)
+	}
+	if b.Outstanding <= 0 {
+		b.emit(EventClosed, payment.Week, 0, payment.Date)
+		return
+	}
+	next := b.PaymentRecord.Size() + 1
+	b.emit(EventInstallmentDue, (b.Loan.Weeks-b.RemainingWeeks)+1, b.DueAmount(), b.DueDate(next))
+}
@@ -0,0 +1,155 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gobillingengine/model"
+)
+
+var ErrFixedRate = errors.New("loan has a fixed rate")
+
+// RateChange is a change of the rate of a variable-rate loan. It reprices the installments of the periods due on or
+// after its effective date.
+type RateChange struct {
+	Rate          float64
+	EffectiveDate time.Time
+}
+
+// Repricing records a rate change applied to the remaining schedule of a billing.
+type Repricing struct {
+	Week          int       // Week of the first repriced installment
+	Date          time.Time // Date is the due date of the first repriced period
+	EffectiveDate time.Time
+	OldRate       float64
+	NewRate       float64
+	PayableAmount float64 // PayableAmount of the installments from Week on
+	Outstanding   float64 // Outstanding added by the new rate over the remaining weeks, negative for a cut
+}
+
+// Rate returns the flat interest rate of the installments left, the rate of the loan until a rate change reprices them.
+func (b *Billing) Rate() float64 {
+	if n := len(b.Repricings); n > 0 {
+		return b.Repricings[n-1].NewRate
+	}
+	return b.Loan.FlatInterestRate
+}
+
+// RateAt returns the rate of the installment of the period due at date, scheduled rate changes included.
+func (b *Billing) RateAt(date time.Time) float64 {
+	rate := b.Loan.FlatInterestRate
+	for _, r := range b.Repricings {
+		if !r.EffectiveDate.After(date) {
+			rate = r.NewRate
+		}
+	}
+	for _, c := range b.RateChanges {
+		if !c.EffectiveDate.After(date) {
+			rate = c.Rate
+		}
+	}
+	return rate
+}
+
+// PayableAmountAt returns the installment of the period due at date.
+func (b *Billing) PayableAmountAt(date time.Time) float64 {
+	if rate := b.RateAt(date); rate != b.Rate() {
+		return b.payableAmount(rate)
+	}
+	return b.PayableAmount
+}
+
+// ScheduledTotal returns the amount repayable over the whole schedule, repricings included.
+func (b *Billing) ScheduledTotal() float64 {
+	total := b.payableAmount(b.Loan.FlatInterestRate) * float64(b.Loan.Weeks)
+	for _, r := range b.Repricings {
+		total += r.Outstanding
+	}
+	return total
+}
+
+// payableAmount returns the installment of the loan at a rate: the principal and interest of a week, with the fees.
+func (b *Billing) payableAmount(rate float64) float64 {
+	loan := b.Loan
+	total := loan.Amount*(1+rate) + loan.FeeTotal(model.FeeFinanced) + loan.FeeTotal(model.FeeInstallment)*float64(loan.Weeks)
+	return b.Round(total / float64(loan.Weeks))
+}
+
+// ChangeRate changes the rate of a variable-rate loan from an effective date. The installments already settled keep
+// their rate, the others are repriced once the first period due on or after the effective date is the next one,
+// right away if it already is. A later change effective at the same date replaces a scheduled one.
+func (b *Billing) ChangeRate(rate float64, effective time.Time) error {
+	if b.IsClosed() {
+		return ErrClosed
+	}
+	if !b.Loan.VariableRate {
+		return ErrFixedRate
+	}
+	if rate < 0 || rate > 1 {
+		return fmt.Errorf("rate %v must be a fraction between 0 and 1", rate)
+	}
+	settled := b.DueDate(b.PaymentRecord.Size())
+	if !effective.After(settled) {
+		return fmt.Errorf("rate change must be effective after %s, the due date of the last settled period",
+			settled.Format("2006-01-02"))
+	}
+	if n := len(b.Repricings); n > 0 && effective.Before(b.Repricings[n-1].EffectiveDate) {
+		return fmt.Errorf("rate change must be effective from %s, the date of the latest repricing",
+			b.Repricings[n-1].EffectiveDate.Format("2006-01-02"))
+	}
+
+	i := sort.Search(len(b.RateChanges), func(i int) bool {
+		return !b.RateChanges[i].EffectiveDate.Before(effective)
+	})
+	change := &RateChange{Rate: rate, EffectiveDate: effective}
+	if i < len(b.RateChanges) && b.RateChanges[i].EffectiveDate.Equal(effective) {
+		b.RateChanges[i] = change
+	} else {
+		b.RateChanges = append(b.RateChanges[:i], append([]*RateChange{change}, b.RateChanges[i:]...)...)
+	}
+	b.applyRateChanges()
+	return nil
+}
+
+// applyRateChanges reprices the remaining schedule with the rate changes effective by the due date of the next period.
+func (b *Billing) applyRateChanges() {
+	if b.IsClosed() || b.Outstanding <= 0 {
+		return
+	}
+	due := b.DueDate(b.PaymentRecord.Size() + 1)
+	for len(b.RateChanges) > 0 && !b.RateChanges[0].EffectiveDate.After(due) {
+		change := b.RateChanges[0]
+		b.RateChanges = b.RateChanges[1:]
+
+		r := &Repricing{
+			Week:          (b.Loan.Weeks - b.RemainingWeeks) + 1,
+			Date:          due,
+			EffectiveDate: change.EffectiveDate,
+			OldRate:       b.Rate(),
+			NewRate:       change.Rate,
+			PayableAmount: b.payableAmount(change.Rate),
+		}
+		// the interest of each remaining week moves with the rate, flat over the term like the original one
+		r.Outstanding = b.Round(float64(b.RemainingWeeks) * b.Loan.Amount * (r.NewRate - r.OldRate) / float64(b.Loan.Weeks))
+		b.Outstanding += r.Outstanding
+		b.PayableAmount = r.PayableAmount
+		b.Repricings = append(b.Repricings, r)
+		b.emitRepricing(r.Week, r.Outstanding, r.NewRate, r.EffectiveDate)
+	}
+}
+
+// unapplyRateChanges undoes the repricings no longer effective by the due date of the next period after a reversal.
+// Their rate changes are scheduled again.
+func (b *Billing) unapplyRateChanges() {
+	due := b.DueDate(b.PaymentRecord.Size() + 1)
+	for n := len(b.Repricings); n > 0 && b.Repricings[n-1].EffectiveDate.After(due); n-- {
+		r := b.Repricings[n-1]
+		b.Repricings = b.Repricings[:n-1]
+		b.Outstanding -= r.Outstanding
+		b.PayableAmount = b.payableAmount(r.OldRate)
+		b.RateChanges = append([]*RateChange{{Rate: r.NewRate, EffectiveDate: r.EffectiveDate}}, b.RateChanges...)
+		b.emitRepricing(r.Week, -r.Outstanding, r.OldRate, r.EffectiveDate)
+	}
+}
@@ -0,0 +1,81 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gobillingengine/model"
+)
+
+func TestChangeRate(t *testing.T) {
+	loan := model.NewLoan("1001", 50, 5000, 0.1)
+	loan.DisbursementDate = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fixed := NewBilling(loan)
+	assert.ErrorIs(t, fixed.ChangeRate(0.2, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)), ErrFixedRate)
+
+	loan.VariableRate = true
+	b := NewBilling(loan)
+	subscriber := &recordingSubscriber{}
+	b.Subscribe(subscriber)
+	require.NoError(t, b.MakePayment(110))
+	require.NoError(t, b.MakePayment(110))
+	assert.EqualError(t, b.ChangeRate(0.2, b.DueDate(2)),
+		"rate change must be effective after 2024-01-15, the due date of the last settled period")
+	assert.EqualError(t, b.ChangeRate(1.2, b.DueDate(3)), "rate 1.2 must be a fraction between 0 and 1")
+
+	// effective Mar 1, the change reprices the period due Mar 4 on
+	effective := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, b.ChangeRate(0.2, effective))
+	assert.Equal(t, 110.0, b.PayableAmount)
+	assert.Equal(t, 1, len(b.RateChanges))
+	assert.Equal(t, 0.1, b.RateAt(b.DueDate(8)))
+	assert.Equal(t, 0.2, b.RateAt(b.DueDate(9)))
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, b.MakePayment(110))
+	}
+	subscriber.events = nil
+	require.NoError(t, b.MakePayment(0)) // period 8 is missed, period 9 comes due at the new rate
+	assert.Empty(t, b.RateChanges)
+	require.Equal(t, []*Repricing{{
+		Week:          8,
+		Date:          b.DueDate(9),
+		EffectiveDate: effective,
+		OldRate:       0.1,
+		NewRate:       0.2,
+		PayableAmount: 120,
+		Outstanding:   430, // 43 weeks left, 10 more interest each
+	}}, b.Repricings)
+	assert.Equal(t, 5160.0, b.Outstanding)
+	assert.Equal(t, 0.2, b.Rate())
+	assert.Equal(t, EventRepriced, subscriber.events[0].Type)
+	assert.Equal(t, 430.0, subscriber.events[0].Amount)
+	assert.Equal(t, 0.2, subscriber.events[0].Rate)
+	assert.Equal(t, Event{Type: EventInstallmentDue, LoanID: "1001", Week: 8, Amount: 120, Outstanding: 5160, MissedPayment: 1,
+		Date: b.DueDate(9)}, subscriber.events[1])
+
+	// the installments paid keep their rate
+	schedule, err := b.PrintPayment(b.GenerateLoanSchedule())
+	require.NoError(t, err)
+	assert.Contains(t, schedule, "Week: 7, Payable amount: 110.000000\nWeek: 8, Payable amount: 120.000000\n")
+	assert.EqualError(t, b.MakePayment(110), "payment should be 120.000000 or 0")
+	assert.EqualError(t, b.ChangeRate(0.15, effective.AddDate(0, 0, -1)),
+		"rate change must be effective from 2024-03-01, the date of the latest repricing")
+
+	// reversing the miss takes the period due Mar 4 out of reach again
+	_, err = b.ReversePayment()
+	require.NoError(t, err)
+	assert.Empty(t, b.Repricings)
+	assert.Equal(t, []*RateChange{{Rate: 0.2, EffectiveDate: effective}}, b.RateChanges)
+	assert.Equal(t, 4730.0, b.Outstanding)
+	assert.Equal(t, 110.0, b.PayableAmount)
+
+	require.NoError(t, b.MakePayment(0))
+	require.NoError(t, b.MakePayment(120))
+	assert.Equal(t, 5040.0, b.Outstanding)
+	assert.Equal(t, 5930.0, b.ScheduledTotal())
+	assert.Equal(t, 110.0, b.PayableAmountAt(b.DueDate(8)))
+}
@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gobillingengine/model"
+	"gobillingengine/money"
+)
+
+// ClosedRefinanced is the closure reason of a billing paid off by a new loan.
+const ClosedRefinanced = "refinanced"
+
+var ErrClosed = errors.New("billing is closed")
+
+// Payoff is the amount settling a billing at a date. The interest of the installments not yet due is waived.
+type Payoff struct {
+	LoanID    string
+	Date      time.Time
+	Principal float64 // Principal left, with the financed fees left
+	Interest  float64 // Interest of the installments overdue at the date
+	Fees      float64 // Fees charged with the installments overdue at the date
+	Penalty   float64
+	Amount    float64
+}
+
+// Closure records how a billing was closed before its schedule ended.
+type Closure struct {
+	Reason       string // Reason is ClosedRefinanced or ClosedWrittenOff
+	Date         time.Time
+	LinkedLoanID string  // LinkedLoanID of the loan the billing was refinanced into
+	Outstanding  float64 // Outstanding cleared by the closure, the written off amount of a write-off
+	Payoff       Payoff  // Payoff received, none for a write-off
+}
+
+// IsClosed checks if the billing was closed before its schedule ended.
+func (b *Billing) IsClosed() bool {
+	return b.Closure != nil
+}
+
+// Payoff returns the amount settling the billing at asOf: the principal and financed fees of the installments left,
+// the interest and fees of the overdue ones, and the penalties charged.
+func (b *Billing) Payoff(asOf time.Time) Payoff {
+	weeks := float64(b.Loan.Weeks)
+	overdue, _ := b.overdue(asOf)
+	payoff := Payoff{
+		LoanID:    b.Loan.LoanID,
+		Date:      asOf,
+		Principal: float64(b.RemainingWeeks) * (b.Loan.Amount + b.Loan.FeeTotal(model.FeeFinanced)) / weeks,
+		Interest:  float64(overdue) * b.Loan.Amount * b.Rate() / weeks,
+		Fees:      float64(overdue) * b.Loan.FeeTotal(model.FeeInstallment),
+		Penalty:   b.GetPenalty(),
+	}
+	payoff.Amount = payoff.Principal + payoff.Interest + payoff.Fees + payoff.Penalty
+	return payoff
+}
+
+// Refinance pays off the billing with a new loan disbursed at date and returns the billing of the new loan.
+// The payoff is netted out of the amount handed to the borrower, the old billing is closed as refinanced and
+// the two loans are linked to each other.
+func Refinance(old *Billing, loan *model.Loan, date time.Time) (*Billing, error) {
+	if old.IsClosed() {
+		return nil, ErrClosed
+	}
+	if old.Outstanding <= 0 {
+		return nil, errors.New("billing has nothing left to refinance")
+	}
+	if loan.LoanID == old.Loan.LoanID {
+		return nil, errors.New("new loan must have its own loan ID")
+	}
+	// the loan is completed on a copy, the caller's is left as it was if the refinancing fails
+	refinancing := *loan
+	if refinancing.BorrowerID == "" {
+		refinancing.BorrowerID = old.Loan.BorrowerID
+	}
+	if refinancing.BorrowerID != old.Loan.BorrowerID {
+		return nil, fmt.Errorf("new loan is for borrower %s, not %s", refinancing.BorrowerID, old.Loan.BorrowerID)
+	}
+	if refinancing.Currency == "" {
+		refinancing.Currency = old.Loan.Currency
+	}
+	if refinancing.Currency != old.Loan.Currency {
+		return nil, fmt.Errorf("%w: new loan in %q refinancing a loan in %q", money.ErrCurrencyMismatch, refinancing.Currency, old.Loan.Currency)
+	}
+	if refinancing.DisbursementDate.IsZero() {
+		refinancing.DisbursementDate = date
+	}
+
+	payoff := old.Payoff(date)
+	refinancing.RefinancedLoanID = old.Loan.LoanID
+	refinancing.PayoffAmount = payoff.Amount
+	if refinancing.NetDisbursedAmount() <= 0 {
+		return nil, fmt.Errorf("loan amount %f does not cover the payoff %f", refinancing.Amount, payoff.Amount)
+	}
+
+	*loan = refinancing
+	week := (old.Loan.Weeks - old.RemainingWeeks) + 1
+	old.Closure = &Closure{
+		Reason:       ClosedRefinanced,
+		Date:         date,
+		LinkedLoanID: loan.LoanID,
+		Outstanding:  old.Outstanding,
+		Payoff:       payoff,
+	}
+	old.Outstanding = 0
+	old.RemainingWeeks = 0
+	old.ResetMissedPayment()
+	old.emit(EventClosed, week, payoff.Amount, date)
+	return NewBilling(loan), nil
+}
@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gobillingengine/model"
+	"gobillingengine/money"
+)
+
+func TestRefinance(t *testing.T) {
+	loan := model.NewLoan("1001", 50, 5000, 0.1)
+	loan.BorrowerID = "b-1"
+	loan.DisbursementDate = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	old := NewBilling(loan)
+	subscriber := &recordingSubscriber{}
+	old.Subscribe(subscriber)
+
+	// paid Jan 8 and Jan 15, missed Jan 22, nothing recorded for Jan 29
+	for _, amount := range []float64{110, 110, 0} {
+		require.NoError(t, old.MakePayment(amount))
+	}
+	require.NoError(t, old.ChargePenalty(5))
+	date := time.Date(2024, 1, 30, 0, 0, 0, 0, time.UTC)
+
+	// the interest of the 46 installments not yet due is waived
+	payoff := old.Payoff(date)
+	assert.Equal(t, Payoff{LoanID: "1001", Date: date, Principal: 4800, Interest: 20, Penalty: 5, Amount: 4825}, payoff)
+
+	refinanced, err := Refinance(old, model.NewLoan("1002", 50, 10000, 0.1), date)
+	require.NoError(t, err)
+	assert.Equal(t, "1001", refinanced.Loan.RefinancedLoanID)
+	assert.Equal(t, "b-1", refinanced.Loan.BorrowerID)
+	assert.Equal(t, date, refinanced.Loan.DisbursementDate)
+	assert.Equal(t, 5175.0, refinanced.Loan.NetDisbursedAmount())
+	assert.Equal(t, 11000.0, refinanced.Outstanding)
+
+	assert.True(t, old.IsClosed())
+	assert.Equal(t, &Closure{Reason: ClosedRefinanced, Date: date, LinkedLoanID: "1002", Outstanding: 5280, Payoff: payoff}, old.Closure)
+	assert.Zero(t, old.GetOutstanding())
+	assert.Equal(t, 5280.0, old.OutstandingAsOf(date.AddDate(0, 0, -1)))
+	assert.Zero(t, old.OutstandingAsOf(date))
+	assert.Zero(t, old.DaysPastDue(date))
+	last := subscriber.events[len(subscriber.events)-1]
+	assert.Equal(t, EventClosed, last.Type)
+	assert.Equal(t, 4825.0, last.Amount)
+
+	// a closed billing takes no more payments
+	assert.ErrorIs(t, old.MakePayment(0), ErrClosed)
+	_, err = old.ReversePayment()
+	assert.ErrorIs(t, err, ErrClosed)
+	_, err = Refinance(old, model.NewLoan("1003", 50, 10000, 0.1), date)
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestRefinance_Invalid(t *testing.T) {
+	loan := model.NewLoan("1001", 50, 5000, 0.1)
+	loan.BorrowerID = "b-1"
+	loan.DisbursementDate = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	old := NewBilling(loan)
+	date := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	small := model.NewLoan("1002", 50, 5000, 0.1)
+	_, err := Refinance(old, small, date)
+	assert.EqualError(t, err, "loan amount 5000.000000 does not cover the payoff 5000.000000")
+	assert.Equal(t, model.NewLoan("1002", 50, 5000, 0.1), small) // left as it was
+
+	other := model.NewLoan("1002", 50, 10000, 0.1)
+	other.BorrowerID = "b-2"
+	_, err = Refinance(old, other, date)
+	assert.EqualError(t, err, "new loan is for borrower b-2, not b-1")
+
+	dollars := model.NewLoan("1002", 50, 10000, 0.1)
+	dollars.Currency = "USD"
+	_, err = Refinance(old, dollars, date)
+	assert.ErrorIs(t, err, money.ErrCurrencyMismatch)
+
+	_, err = Refinance(old, model.NewLoan("1001", 50, 10000, 0.1), date)
+	assert.Error(t, err)
+	assert.False(t, old.IsClosed())
+}
@@ -0,0 +1,204 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	SettlementOpen     = "open"     // SettlementOpen the borrower is paying the agreed amount
+	SettlementHonoured = "honoured" // SettlementHonoured the agreed amount was paid in full by the deadline
+	SettlementBroken   = "broken"   // SettlementBroken the agreed amount was not paid, the balance left is owed again
+)
+
+var ErrNoSettlement = errors.New("billing has no open settlement")
+
+// tolerance absorbs the float rounding of the settlement installments.
+const tolerance = 0.005
+
+// drift absorbs the float error of the amounts of an unnamed currency, which are not rounded.
+const drift = 1e-9
+
+// Settlement is a discounted amount agreed with the borrower of a written off billing, paid in weekly installments
+// by a deadline. It is tracked apart from the schedule of the loan, its payments are recoveries of the write-off.
+type Settlement struct {
+	Date         time.Time // Date the settlement was agreed
+	Amount       float64   // Amount agreed to settle the billing
+	Installments int
+	Deadline     time.Time // Deadline to pay the agreed amount, the last installment is due on or before it
+	Balance      float64   // Balance owed at the agreement, the written off amount and penalties less the recoveries
+	Payments     []*SettlementPayment
+	Status       string
+	ClosedDate   time.Time // ClosedDate is when the settlement was honoured or broken, zero while open
+}
+
+// SettlementPayment is a payment made under a settlement.
+type SettlementPayment struct {
+	Amount float64
+	Date   time.Time
+}
+
+// Installment returns the amount of each installment of the settlement.
+func (s *Settlement) Installment() float64 {
+	return s.Amount / float64(s.Installments)
+}
+
+// DueDate returns the due date of an installment of the settlement, weekly from the date it was agreed.
+func (s *Settlement) DueDate(installment int) time.Time {
+	return s.Date.AddDate(0, 0, 7*installment)
+}
+
+// Paid returns the amount paid under the settlement.
+func (s *Settlement) Paid() float64 {
+	var paid float64
+	for _, p := range s.Payments {
+		paid += p.Amount
+	}
+	return paid
+}
+
+// Remaining returns the agreed amount left to pay.
+func (s *Settlement) Remaining() float64 {
+	return s.Amount - s.Paid()
+}
+
+// Discount returns the part of the balance forgiven if the settlement is honoured.
+func (s *Settlement) Discount() float64 {
+	return s.Balance - s.Amount
+}
+
+// Arrears returns the installments due by asOf and not paid yet.
+func (s *Settlement) Arrears(asOf time.Time) float64 {
+	var due float64
+	for n := 1; n <= s.Installments && !s.DueDate(n).After(asOf); n++ {
+		due += s.Installment()
+	}
+	if arrears := due - s.Paid(); arrears > 0 {
+		return arrears
+	}
+	return 0
+}
+
+// StatusAsOf returns the status of the settlement at the end of asOf.
+func (s *Settlement) StatusAsOf(asOf time.Time) string {
+	if s.Status != SettlementOpen && !s.ClosedDate.After(asOf) {
+		return s.Status
+	}
+	return SettlementOpen
+}
+
+// IsOverdue checks if the settlement is still open after its deadline.
+func (s *Settlement) IsOverdue(asOf time.Time) bool {
+	return s.Status == SettlementOpen && asOf.After(s.Deadline)
+}
+
+// OpenSettlement returns the settlement being paid, nil if none.
+func (b *Billing) OpenSettlement() *Settlement {
+	if n := len(b.Settlements); n > 0 && b.Settlements[n-1].Status == SettlementOpen {
+		return b.Settlements[n-1]
+	}
+	return nil
+}
+
+// Recovered returns the amount recovered by the settlements of a written off billing, broken ones included.
+func (b *Billing) Recovered() float64 {
+	var recovered float64
+	for _, s := range b.Settlements {
+		recovered += s.Paid()
+	}
+	return recovered
+}
+
+// RecoveryBalance returns what the borrower of a written off billing still owes: the written off amount and the
+// penalties, less the recoveries. Nothing is owed once a settlement is honoured.
+func (b *Billing) RecoveryBalance() float64 {
+	if !b.IsWrittenOff() {
+		return 0
+	}
+	for _, s := range b.Settlements {
+		if s.Status == SettlementHonoured {
+			return 0
+		}
+	}
+	return b.Closure.Outstanding + b.GetPenalty() - b.Recovered()
+}
+
+// Settle agrees a settlement of a written off billing at date: amount paid in weekly installments by the deadline.
+func (b *Billing) Settle(amount float64, installments int, date, deadline time.Time) (*Settlement, error) {
+	if !b.IsWrittenOff() {
+		return nil, errors.New("only a written off billing can be settled")
+	}
+	for _, s := range b.Settlements {
+		switch s.Status {
+		case SettlementOpen:
+			return nil, errors.New("billing already has an open settlement")
+		case SettlementHonoured:
+			return nil, fmt.Errorf("billing was settled on %s", s.ClosedDate.Format("2006-01-02"))
+		}
+	}
+	if date.Before(b.Closure.Date) {
+		return nil, fmt.Errorf("settlement cannot be agreed before the write-off on %s", b.Closure.Date.Format("2006-01-02"))
+	}
+	balance := b.RecoveryBalance()
+	if amount <= 0 || amount > balance+tolerance {
+		return nil, fmt.Errorf("settlement amount %f must be positive and at most the balance %f", amount, balance)
+	}
+	if installments < 1 {
+		return nil, errors.New("settlement needs at least 1 installment")
+	}
+	s := &Settlement{
+		Date:         date,
+		Amount:       amount,
+		Installments: installments,
+		Deadline:     deadline,
+		Balance:      balance,
+		Status:       SettlementOpen,
+	}
+	if last := s.DueDate(installments); deadline.Before(last) {
+		return nil, fmt.Errorf("settlement deadline %s is before the last installment due %s",
+			deadline.Format("2006-01-02"), last.Format("2006-01-02"))
+	}
+	b.Settlements = append(b.Settlements, s)
+	return s, nil
+}
+
+// PaySettlement records a payment of the open settlement at date. The settlement is honoured once the agreed amount
+// is paid.
+func (b *Billing) PaySettlement(amount float64, date time.Time) error {
+	s := b.OpenSettlement()
+	if s == nil {
+		return ErrNoSettlement
+	}
+	if amount <= 0 {
+		return errors.New("settlement payment must be positive")
+	}
+	if date.Before(s.Date) || date.After(s.Deadline) {
+		return fmt.Errorf("settlement payment on %s is outside of the agreement, from %s to %s",
+			date.Format("2006-01-02"), s.Date.Format("2006-01-02"), s.Deadline.Format("2006-01-02"))
+	}
+	if amount > s.Remaining()+tolerance {
+		return fmt.Errorf("settlement payment %f exceeds the remaining %f", amount, s.Remaining())
+	}
+	s.Payments = append(s.Payments, &SettlementPayment{Amount: amount, Date: date})
+	if s.Remaining() < tolerance {
+		s.Status = SettlementHonoured
+		s.ClosedDate = date
+	}
+	return nil
+}
+
+// BreakSettlement marks the open settlement as broken at date, e.g. when its deadline passed or the borrower walked
+// away from it. What was paid stays recovered.
+func (b *Billing) BreakSettlement(date time.Time) error {
+	s := b.OpenSettlement()
+	if s == nil {
+		return ErrNoSettlement
+	}
+	if date.Before(s.Date) {
+		return fmt.Errorf("settlement cannot be broken before it was agreed on %s", s.Date.Format("2006-01-02"))
+	}
+	s.Status = SettlementBroken
+	s.ClosedDate = date
+	return nil
+}
@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gobillingengine/model"
+)
+
+func TestSettle(t *testing.T) {
+	loan := model.NewLoan("1001", 50, 5000, 0.1)
+	loan.DisbursementDate = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := NewBilling(loan)
+	for _, amount := range []float64{110, 0, 0, 0} {
+		require.NoError(t, b.MakePayment(amount))
+	}
+	require.NoError(t, b.ChargePenalty(10))
+	date := func(month time.Month, day int) time.Time { return time.Date(2024, month, day, 0, 0, 0, 0, time.UTC) }
+
+	_, err := b.Settle(2700, 3, date(2, 1), date(3, 1))
+	assert.EqualError(t, err, "only a written off billing can be settled")
+	require.NoError(t, b.WriteOff(date(1, 29)))
+	assert.Equal(t, 5400.0, b.RecoveryBalance())
+
+	_, err = b.Settle(2700, 3, date(2, 1), date(2, 15))
+	assert.EqualError(t, err, "settlement deadline 2024-02-15 is before the last installment due 2024-02-22")
+	_, err = b.Settle(6000, 3, date(2, 1), date(3, 1))
+	assert.EqualError(t, err, "settlement amount 6000.000000 must be positive and at most the balance 5400.000000")
+
+	// half of the balance in 3 weekly installments, the borrower walks away after 2
+	s, err := b.Settle(2700, 3, date(2, 1), date(3, 1))
+	require.NoError(t, err)
+	assert.Equal(t, 900.0, s.Installment())
+	assert.Equal(t, 2700.0, s.Discount())
+	assert.Equal(t, 900.0, s.Arrears(date(2, 8)))
+	_, err = b.Settle(100, 1, date(2, 2), date(3, 1))
+	assert.EqualError(t, err, "billing already has an open settlement")
+
+	require.NoError(t, b.PaySettlement(900, date(2, 8)))
+	assert.EqualError(t, b.PaySettlement(2000, date(2, 15)), "settlement payment 2000.000000 exceeds the remaining 1800.000000")
+	assert.EqualError(t, b.PaySettlement(900, date(3, 2)), "settlement payment on 2024-03-02 is outside of the agreement, from 2024-02-01 to 2024-03-01")
+	require.NoError(t, b.PaySettlement(900, date(2, 15)))
+	assert.True(t, s.IsOverdue(date(3, 2)))
+	require.NoError(t, b.BreakSettlement(date(3, 2)))
+	assert.Equal(t, SettlementBroken, s.Status)
+	assert.Equal(t, SettlementOpen, s.StatusAsOf(date(3, 1)))
+	assert.Equal(t, 1800.0, b.Recovered())
+	assert.Equal(t, 3600.0, b.RecoveryBalance())
+	assert.ErrorIs(t, b.BreakSettlement(date(3, 3)), ErrNoSettlement)
+
+	// a new agreement on what is left, honoured this time
+	s, err = b.Settle(1800, 2, date(3, 5), date(3, 31))
+	require.NoError(t, err)
+	assert.Equal(t, 3600.0, s.Balance)
+	require.NoError(t, b.PaySettlement(900, date(3, 12)))
+	require.NoError(t, b.PaySettlement(900, date(3, 19)))
+	assert.Equal(t, SettlementHonoured, s.Status)
+	assert.Equal(t, date(3, 19), s.ClosedDate)
+	assert.Equal(t, 3600.0, b.Recovered())
+	assert.Zero(t, b.RecoveryBalance())
+	assert.Nil(t, b.OpenSettlement())
+	assert.ErrorIs(t, b.PaySettlement(100, date(3, 20)), ErrNoSettlement)
+	_, err = b.Settle(100, 1, date(3, 20), date(4, 30))
+	assert.EqualError(t, err, "billing was settled on 2024-03-19")
+}
@@ -0,0 +1,22 @@
+package engine
+
+import (
+	"time"
+
+	"gobillingengine/clock"
+)
+
+// Today returns the business date of the billing's clock.
+func (b *Billing) Today() time.Time {
+	return clock.Today(b.Clock)
+}
+
+// Position returns the position of the billing at the end of today.
+func (b *Billing) Position() Position {
+	return b.PositionAsOf(b.Today())
+}
+
+// IsPastDue checks if an installment due before today is left unpaid.
+func (b *Billing) IsPastDue() bool {
+	return b.DaysPastDue(b.Today()) > 0
+}
@@ -0,0 +1,33 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"gobillingengine/clock"
+	"gobillingengine/model"
+)
+
+func TestToday(t *testing.T) {
+	loan := model.NewLoan("1001", 50, 5000, 0.1)
+	loan.DisbursementDate = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	billing := NewBilling(loan)
+	c := clock.NewManual(time.Date(2024, 1, 8, 9, 0, 0, 0, time.UTC))
+	billing.Clock = c
+
+	// the first installment is due today, not past due yet
+	assert.Equal(t, time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC), billing.Today())
+	assert.False(t, billing.IsPastDue())
+	assert.Equal(t, 5500.0, billing.Position().Outstanding)
+
+	c.AdvanceDays(3)
+	assert.True(t, billing.IsPastDue())
+	assert.Equal(t, 3, billing.Position().DaysPastDue)
+	assert.Equal(t, 110.0, billing.Position().Arrears)
+
+	assert.NoError(t, billing.MakePayment(110))
+	assert.False(t, billing.IsPastDue())
+	assert.Equal(t, 5390.0, billing.Position().Outstanding)
+}
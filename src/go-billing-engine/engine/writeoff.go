@@ -0,0 +1,37 @@
+package engine
+
+import (
+	"errors"
+	"time"
+)
+
+// ClosedWrittenOff is the closure reason of a billing written off as a loss.
+const ClosedWrittenOff = "written_off"
+
+// IsWrittenOff checks if the billing was written off.
+func (b *Billing) IsWrittenOff() bool {
+	return b.Closure != nil && b.Closure.Reason == ClosedWrittenOff
+}
+
+// WriteOff closes the billing as a loss at date. The outstanding left is kept in the closure as the written off amount.
+func (b *Billing) WriteOff(date time.Time) error {
+	if b.IsClosed() {
+		return ErrClosed
+	}
+	if b.Outstanding <= 0 {
+		return errors.New("billing has nothing left to write off")
+	}
+
+	week := (b.Loan.Weeks - b.RemainingWeeks) + 1
+	b.Closure = &Closure{
+		Reason:      ClosedWrittenOff,
+		Date:        date,
+		Outstanding: b.Outstanding,
+		Payoff:      Payoff{LoanID: b.Loan.LoanID, Date: date},
+	}
+	b.Outstanding = 0
+	b.RemainingWeeks = 0
+	b.ResetMissedPayment()
+	b.emit(EventClosed, week, 0, date)
+	return nil
+}
@@ -0,0 +1,33 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gobillingengine/model"
+)
+
+func TestWriteOff(t *testing.T) {
+	loan := model.NewLoan("1001", 50, 5000, 0.1)
+	loan.DisbursementDate = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	billing := NewBilling(loan)
+	for _, amount := range []float64{110, 0, 0} {
+		require.NoError(t, billing.MakePayment(amount))
+	}
+	date := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	require.NoError(t, billing.WriteOff(date))
+	assert.True(t, billing.IsWrittenOff())
+	assert.Equal(t, 5390.0, billing.Closure.Outstanding)
+	assert.Zero(t, billing.GetOutstanding())
+	assert.False(t, billing.IsDelinquent())
+	assert.Equal(t, 5390.0, billing.OutstandingAsOf(date.AddDate(0, 0, -1)))
+	assert.Zero(t, billing.OutstandingAsOf(date))
+
+	assert.ErrorIs(t, billing.WriteOff(date), ErrClosed)
+	assert.ErrorIs(t, billing.MakePayment(110), ErrClosed)
+	assert.ErrorIs(t, billing.ChargePenalty(5), ErrClosed)
+}
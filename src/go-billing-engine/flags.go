@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"gobillingengine/clock"
+)
+
+const dateLayout = "2006-01-02"
+
+// appClock is the clock of the commands, stopped at the --as-of date when one is given.
+var appClock clock.Clock = clock.System{}
+
+// today returns the business date the commands run at.
+func today() time.Time {
+	return clock.Today(appClock)
+}
+
+// setAsOf stops the clock of the commands at the --as-of date, to run them on a simulated date.
+func setAsOf(cmd *cobra.Command) error {
+	if value, _ := cmd.Flags().GetString("as-of"); value != "" {
+		asOf, err := getDate(cmd, "as-of")
+		if err != nil {
+			return err
+		}
+		appClock = clock.Fixed(asOf)
+	}
+	return nil
+}
+
+// getDate reads a YYYY-MM-DD flag, an empty flag is today.
+func getDate(cmd *cobra.Command, name string) (time.Time, error) {
+	value, _ := cmd.Flags().GetString(name)
+	if value == "" {
+		return today(), nil
+	}
+	date, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --%s %q, expected YYYY-MM-DD", name, value)
+	}
+	return date, nil
+}
+
+// getOutput opens the file of an --output flag, an empty flag is the command output.
+func getOutput(cmd *cobra.Command) (io.Writer, func() error, error) {
+	path, _ := cmd.Flags().GetString("output")
+	if path == "" {
+		return cmd.OutOrStdout(), func() error { return nil }, nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return f, f.Close, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"gobillingengine/accounting"
+	"gobillingengine/portfolio"
+)
+
+func newJournalCmd() *cobra.Command {
+	journalCmd := &cobra.Command{
+		Use:   "journal",
+		Short: "Export the accounting journal or trial balance of a persisted portfolio",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, _ := cmd.Flags().GetString("portfolio")
+			chartPath, _ := cmd.Flags().GetString("chart")
+			trialBalance, _ := cmd.Flags().GetBool("trial-balance")
+
+			chart := accounting.DefaultChart
+			if chartPath != "" {
+				f, err := os.Open(chartPath)
+				if err != nil {
+					return err
+				}
+				chart, err = accounting.LoadChart(f)
+				f.Close()
+				if err != nil {
+					return fmt.Errorf("cannot load chart of accounts with err: %v", err)
+				}
+			}
+
+			store, err := portfolio.OpenFileStore(path)
+			if err != nil {
+				return err
+			}
+			billings, err := store.List()
+			if err != nil {
+				return err
+			}
+			journal := accounting.NewJournal(chart)
+			for _, b := range billings {
+				if err := journal.Replay(b); err != nil {
+					return fmt.Errorf("loanID:%s - %v", b.Loan.LoanID, err)
+				}
+			}
+
+			out, closeOut, err := getOutput(cmd)
+			if err != nil {
+				return err
+			}
+			if trialBalance {
+				err = journal.WriteTrialBalanceCSV(out)
+			} else {
+				err = journal.WriteCSV(out)
+			}
+			if errClose := closeOut(); err == nil {
+				err = errClose
+			}
+			return err
+		},
+	}
+	journalCmd.Flags().String("portfolio", "portfolio.json", "The persisted portfolio file")
+	journalCmd.Flags().String("chart", "", "YAML chart of accounts, the default chart if empty")
+	journalCmd.Flags().Bool("trial-balance", false, "Export the trial balance instead of the journal lines")
+	journalCmd.Flags().StringP("output", "o", "", "Write the export to this file instead of stdout")
+	return journalCmd
+}
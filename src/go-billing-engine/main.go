@@ -2,10 +2,7 @@ package main
 
 import (
 	"fmt"
-	"github.com/google/uuid"
 	"github.com/spf13/cobra"
-	"gobillingengine/engine"
-	"gobillingengine/model"
 	"os"
 )
 
@@ -143,64 +140,7 @@ import (
 //	b.PaymentRecord.Push(payment)
 //}
 
-func runQuickScenario() {
-	loanID := uuid.New().String()
-	loanAmount := 5000000.00
-	flatInterestRate := 10.0
-	weeks := 50
-
-	// Create a new loan & billing
-	loan := model.NewLoan(loanID, weeks, loanAmount, flatInterestRate)
-	billing := engine.NewBilling(loan)
-
-	// Generate billing schedule
-	billingSchedule := billing.GenerateLoanSchedule()
-	s, err := billing.PrintPayment(billingSchedule)
-	if err != nil {
-		panic(err)
-	}
-	fmt.Printf("\nGenerateLoanSchedule: \n %s\n", s)
-
-	var payments []float64
-	payments = append(payments, 1000000.000000)
-	payments = append(payments, 1000000.000000)
-	payments = append(payments, 1000000.000000)
-	payments = append(payments, 1000000.000000)
-	payments = append(payments, 0)
-	payments = append(payments, 200.00)
-
-	for _, p := range payments {
-		err := billing.MakePayment(p)
-		if err != nil {
-			fmt.Printf("could not make payment with amount: %f with err: %v\n", p, err)
-		}
-	}
-
-	// Generate billing schedule
-	remainingLoanSchedule := billing.GenerateRemainingLoanSchedule()
-	s, err = billing.PrintPayment(remainingLoanSchedule)
-	if err != nil {
-		panic(err)
-	}
-	fmt.Printf("\nremaining loan schedule: \n %s\n", s)
-
-	err = billing.MakePayment(0)
-	if err != nil {
-		panic(err)
-	}
-	err = billing.MakePayment(0)
-	if err != nil {
-		panic(err)
-	}
-	fmt.Printf("\nIsDelinquent: %t\n", billing.IsDelinquent())
-
-	err = billing.MakePayment(1000000.000000)
-	fmt.Printf("\nIsDelinquent: %t\n", billing.IsDelinquent())
-}
-
 func main() {
-	// runQuickScenario() // uncomment this to run a quick example scenario
-
 	rootCmd := &cobra.Command{
 		Use:   "myapp",
 		Short: "A simple command-line application",
@@ -208,7 +148,11 @@ func main() {
 			// This function will be executed when the root command is called
 			fmt.Println("Welcome to myapp!")
 		},
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return setAsOf(cmd)
+		},
 	}
+	rootCmd.PersistentFlags().String("as-of", "", "Run the command on a simulated date (YYYY-MM-DD), today by default")
 
 	// Define a subcommand
 	greetCmd := &cobra.Command{
@@ -226,6 +170,25 @@ func main() {
 
 	// Add the subcommand to the root command
 	rootCmd.AddCommand(greetCmd)
+	rootCmd.AddCommand(newServeCmd())
+	rootCmd.AddCommand(newSimulateCmd())
+	rootCmd.AddCommand(newAuditCmd())
+	rootCmd.AddCommand(newWorklistCmd())
+	rootCmd.AddCommand(newRemindCmd())
+	rootCmd.AddCommand(newReconcileCmd())
+	rootCmd.AddCommand(newMetricsCmd())
+	rootCmd.AddCommand(newJournalCmd())
+	rootCmd.AddCommand(newProductsCmd())
+	rootCmd.AddCommand(newBatchCmd())
+	rootCmd.AddCommand(newBorrowersCmd())
+	rootCmd.AddCommand(newRefinanceCmd())
+	rootCmd.AddCommand(newWriteOffCmd())
+	rootCmd.AddCommand(newSettleCmd())
+	rootCmd.AddCommand(newRecoveriesCmd())
+	rootCmd.AddCommand(newRepriceCmd())
+	rootCmd.AddCommand(newBureauCmd())
+	rootCmd.AddCommand(newSnapshotCmd())
+	rootCmd.AddCommand(newReplCmd())
 
 	// Execute the root command
 
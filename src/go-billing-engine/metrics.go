@@ -0,0 +1,57 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"gobillingengine/metrics"
+	"gobillingengine/portfolio"
+)
+
+func newMetricsCmd() *cobra.Command {
+	metricsCmd := &cobra.Command{
+		Use:   "metrics",
+		Short: "Report the portfolio at risk, collection efficiency and roll rates of a persisted portfolio",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, _ := cmd.Flags().GetString("portfolio")
+
+			to, err := getDate(cmd, "to")
+			if err != nil {
+				return err
+			}
+			from := to.AddDate(0, -1, 0)
+			if value, _ := cmd.Flags().GetString("from"); value != "" {
+				if from, err = getDate(cmd, "from"); err != nil {
+					return err
+				}
+			}
+
+			store, err := portfolio.OpenFileStore(path)
+			if err != nil {
+				return err
+			}
+			billings, err := store.List()
+			if err != nil {
+				return err
+			}
+			report, err := metrics.Generate(billings, from, to)
+			if err != nil {
+				return err
+			}
+
+			out, closeOut, err := getOutput(cmd)
+			if err != nil {
+				return err
+			}
+			err = report.WriteJSON(out)
+			if errClose := closeOut(); err == nil {
+				err = errClose
+			}
+			return err
+		},
+	}
+	metricsCmd.Flags().String("portfolio", "portfolio.json", "The persisted portfolio file")
+	metricsCmd.Flags().String("from", "", "The start of the period (YYYY-MM-DD), a month before --to by default")
+	metricsCmd.Flags().String("to", "", "The end of the period (YYYY-MM-DD), today by default")
+	metricsCmd.Flags().StringP("output", "o", "", "Write the report to this file instead of stdout")
+	return metricsCmd
+}
@@ -0,0 +1,192 @@
+// Package metrics computes the quality metrics of a loan portfolio.
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"gobillingengine/engine"
+)
+
+const (
+	BucketCurrent = "current" // BucketCurrent no installment is past due
+	Bucket1To7    = "1-7"     // Bucket1To7 1 to 7 days past due
+	Bucket8To30   = "8-30"    // Bucket8To30 8 to 30 days past due
+	Bucket31To60  = "31-60"   // Bucket31To60 31 to 60 days past due
+	Bucket61To90  = "61-90"   // Bucket61To90 61 to 90 days past due
+	Bucket90Plus  = "90+"     // Bucket90Plus more than 90 days past due
+	BucketClosed  = "closed"  // BucketClosed the loan is fully paid
+)
+
+// Buckets are the delinquency buckets from the best to the worst, closed last.
+var Buckets = []string{BucketCurrent, Bucket1To7, Bucket8To30, Bucket31To60, Bucket61To90, Bucket90Plus, BucketClosed}
+
+// parDays are the days past due thresholds of the portfolio at risk.
+var parDays = []int{1, 7, 30}
+
+// PAR is the portfolio at risk: the outstanding of the loans at least Days past due.
+type PAR struct {
+	Days        int     `json:"days"`
+	Outstanding float64 `json:"outstanding"`
+	Ratio       float64 `json:"ratio"` // Ratio to the outstanding of the portfolio
+}
+
+// Collection compares what was collected with what was due in the period.
+type Collection struct {
+	Due        float64 `json:"due"`
+	Collected  float64 `json:"collected"`
+	Efficiency float64 `json:"efficiency"`
+}
+
+// RollRate is the share of the outstanding of a bucket at the start of the period found in another bucket at its end.
+type RollRate struct {
+	From        string  `json:"from"`
+	To          string  `json:"to"`
+	Loans       int     `json:"loans"`
+	Outstanding float64 `json:"outstanding"` // Outstanding at the start of the period of the loans that rolled
+	Rate        float64 `json:"rate"`
+}
+
+type Report struct {
+	From        time.Time  `json:"from"`
+	To          time.Time  `json:"to"`
+	Loans       int        `json:"loans"`       // Loans with an outstanding at To
+	Outstanding float64    `json:"outstanding"` // Outstanding of the portfolio at To
+	PAR         []PAR      `json:"par"`
+	Collection  Collection `json:"collection"`
+	RollRates   []RollRate `json:"roll_rates"`
+}
+
+// Bucket returns the delinquency bucket of a billing at asOf.
+func Bucket(b *engine.Billing, asOf time.Time) string {
+	if b.OutstandingAsOf(asOf) <= 0 {
+		return BucketClosed
+	}
+	dpd := b.DaysPastDue(asOf)
+	switch {
+	case dpd == 0:
+		return BucketCurrent
+	case dpd <= 7:
+		return Bucket1To7
+	case dpd <= 30:
+		return Bucket8To30
+	case dpd <= 60:
+		return Bucket31To60
+	case dpd <= 90:
+		return Bucket61To90
+	default:
+		return Bucket90Plus
+	}
+}
+
+// Generate computes the portfolio at risk at to, the collection efficiency between from and to, both inclusive,
+// and the roll rates of the buckets from from to to. Loans disbursed after from are left out of the roll rates, loans
+// without disbursement date of the whole report.
+func Generate(billings []*engine.Billing, from, to time.Time) (*Report, error) {
+	if to.Before(from) {
+		return nil, errors.New("report end date must not be before its start date")
+	}
+
+	report := &Report{From: from, To: to, PAR: []PAR{}, RollRates: []RollRate{}}
+	pars := make([]PAR, len(parDays))
+	for i, days := range parDays {
+		pars[i].Days = days
+	}
+	type roll struct{ from, to string }
+	rolls := make(map[roll]*RollRate)
+	bucketOutstanding := make(map[string]float64)
+
+	for _, b := range billings {
+		if b.Loan.DisbursementDate.IsZero() {
+			continue
+		}
+		outstanding := b.OutstandingAsOf(to)
+		if outstanding > 0 {
+			report.Loans++
+			report.Outstanding += outstanding
+			dpd := b.DaysPastDue(to)
+			for i := range pars {
+				if dpd >= pars[i].Days {
+					pars[i].Outstanding += outstanding
+				}
+			}
+		}
+
+		due, collected := collection(b, from, to)
+		report.Collection.Due += due
+		report.Collection.Collected += collected
+
+		if b.Loan.DisbursementDate.After(from) || b.OutstandingAsOf(from) <= 0 {
+			continue
+		}
+		key := roll{from: Bucket(b, from), to: Bucket(b, to)}
+		rate, ok := rolls[key]
+		if !ok {
+			rate = &RollRate{From: key.from, To: key.to}
+			rolls[key] = rate
+		}
+		rate.Loans++
+		rate.Outstanding += b.OutstandingAsOf(from)
+		bucketOutstanding[key.from] += b.OutstandingAsOf(from)
+	}
+
+	for i := range pars {
+		pars[i].Ratio = ratio(pars[i].Outstanding, report.Outstanding)
+	}
+	report.PAR = pars
+	report.Collection.Efficiency = ratio(report.Collection.Collected, report.Collection.Due)
+	for _, fromBucket := range Buckets {
+		for _, toBucket := range Buckets {
+			if rate, ok := rolls[roll{from: fromBucket, to: toBucket}]; ok {
+				rate.Rate = ratio(rate.Outstanding, bucketOutstanding[fromBucket])
+				report.RollRates = append(report.RollRates, *rate)
+			}
+		}
+	}
+	return report, nil
+}
+
+// collection returns the installments due and the payments collected between from and to.
+// A period is due when the loan still had an outstanding before its due date, and was not closed by then.
+func collection(b *engine.Billing, from, to time.Time) (due, collected float64) {
+	payments := b.Payments() // the record of period n is payments[n-1]
+	outstanding := b.Outstanding
+	for _, payment := range payments {
+		outstanding += payment.Amount
+	}
+	if b.Closure != nil {
+		// written off or refinanced, the outstanding left at the closure was due until then
+		outstanding += b.Closure.Outstanding
+		if b.Closure.Date.Before(to) {
+			to = b.Closure.Date
+		}
+	}
+	for period := 1; outstanding > 0 && !b.DueDate(period).After(to); period++ {
+		if !b.DueDate(period).Before(from) {
+			due += b.PayableAmountAt(b.DueDate(period))
+		}
+		if period <= len(payments) {
+			outstanding -= payments[period-1].Amount
+			if !payments[period-1].Date.Before(from) {
+				collected += payments[period-1].Amount
+			}
+		}
+	}
+	return due, collected
+}
+
+func ratio(part, total float64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return part / total
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gobillingengine/engine"
+	"gobillingengine/engine/enginetest"
+	"gobillingengine/model"
+)
+
+func newBilling(t *testing.T, loanID string, disbursedAt time.Time, payments ...float64) *engine.Billing {
+	loan := enginetest.NewLoan(loanID)
+	loan.DisbursementDate = disbursedAt
+	return enginetest.NewBilling(t, loan, payments...)
+}
+
+func TestGenerate(t *testing.T) {
+	jan1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	jan31 := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	billings := []*engine.Billing{
+		newBilling(t, "paying", jan1, 110, 110, 110, 110),
+		newBilling(t, "missing", jan1, 110, 0, 0, 0),                       // oldest miss due Jan 15
+		newBilling(t, "silent", jan1),                                      // nothing recorded since Jan 8
+		newBilling(t, "new", time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC)), // first due Jan 27
+		newBilling(t, "unscheduled", time.Time{}),                          // left out
+	}
+
+	report, err := Generate(billings, jan1, jan31)
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, report.Loans)
+	assert.Equal(t, 21450.0, report.Outstanding)
+	assert.Equal(t, []PAR{
+		{Days: 1, Outstanding: 16390, Ratio: 16390.0 / 21450},
+		{Days: 7, Outstanding: 10890, Ratio: 10890.0 / 21450},
+		{Days: 30, Outstanding: 0, Ratio: 0},
+	}, report.PAR)
+	assert.Equal(t, Collection{Due: 1430, Collected: 550, Efficiency: 550.0 / 1430}, report.Collection)
+	assert.Equal(t, []RollRate{
+		{From: BucketCurrent, To: BucketCurrent, Loans: 1, Outstanding: 5500, Rate: 1.0 / 3},
+		{From: BucketCurrent, To: Bucket8To30, Loans: 2, Outstanding: 11000, Rate: 2.0 / 3},
+	}, report.RollRates)
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteJSON(&buf))
+	var decoded Report
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, report.RollRates, decoded.RollRates)
+}
+
+func TestGenerate_WrittenOff(t *testing.T) {
+	b := newBilling(t, "1001", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 110, 0, 0)
+	require.NoError(t, b.WriteOff(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)))
+
+	// due Jan 8 to Jan 29, the periods after the write-off are not
+	report, err := Generate([]*engine.Billing{b}, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, Collection{Due: 440, Collected: 110, Efficiency: 0.25}, report.Collection)
+	assert.Zero(t, report.Loans)
+}
+
+func TestGenerate_InvalidRange(t *testing.T) {
+	_, err := Generate(nil, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.Error(t, err)
+}
+
+func TestBucket(t *testing.T) {
+	jan1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	billing := newBilling(t, "silent", jan1) // first due Jan 8
+
+	assert.Equal(t, BucketCurrent, Bucket(billing, time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, Bucket1To7, Bucket(billing, time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, Bucket31To60, Bucket(billing, time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, Bucket90Plus, Bucket(billing, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)))
+
+	closed := engine.NewBilling(model.NewLoan("closed", 1, 5000, 0.1))
+	require.NoError(t, closed.MakePayment(closed.PayableAmount))
+	assert.Equal(t, BucketClosed, Bucket(closed, jan1))
+}
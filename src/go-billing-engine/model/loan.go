@@ -1,14 +1,42 @@
 package model
 
+import "time"
+
 // Loan represents a loan with its details.
 type Loan struct {
 	LoanID           string
+	BorrowerID       string  // BorrowerID of the borrower holding the loan, empty if unknown
+	Currency         string  // Currency ISO 4217 code of the amounts of the loan, empty for loans of an unnamed currency
 	Amount           float64 // Total loan amount
 	FlatInterestRate float64
+	VariableRate     bool // VariableRate loans have their rate changed over the term by rate changes
 	Weeks            int
+	DisbursementDate time.Time // DisbursementDate is when the money was handed to the borrower, zero if unknown
+	ProductCode      string    // ProductCode of the product the loan was created from, empty for ad hoc loans
+	DelinquentAfter  int       // DelinquentAfter continuous missed payments the borrower is delinquent, DefaultDelinquentAfter if zero
+	Fees             []Fee
+	RefinancedLoanID string  // RefinancedLoanID of the loan paid off by this one, empty if none
+	PayoffAmount     float64 // PayoffAmount of the refinanced loan, netted out of the disbursement
 }
 
-// NewLoan creates a new loan instance.
+const (
+	FeeDeducted    = "deducted"    // FeeDeducted the fee is taken from the disbursed amount
+	FeeFinanced    = "financed"    // FeeFinanced the fee is added to the amount repaid over the schedule
+	FeeInstallment = "installment" // FeeInstallment the fee is charged with every installment
+)
+
+// Fee is a fee charged on a loan. The Amount of a FeeInstallment is charged with each installment.
+type Fee struct {
+	Code   string
+	Kind   string
+	Amount float64
+}
+
+// DefaultDelinquentAfter is the number of continuous missed payments making a borrower delinquent.
+const DefaultDelinquentAfter = 2
+
+// NewLoan creates a new loan instance. The flat interest rate applies to the whole term and is a fraction,
+// 0.1 for 10%: the borrower repays amount * (1 + flatInterestRate).
 func NewLoan(loanID string, weeks int, amount, flatInterestRate float64) *Loan {
 	return &Loan{
 		LoanID:           loanID,
@@ -17,3 +45,20 @@ func NewLoan(loanID string, weeks int, amount, flatInterestRate float64) *Loan {
 		FlatInterestRate: flatInterestRate,
 	}
 }
+
+// FeeTotal returns the total amount of the fees of a kind, per installment for FeeInstallment.
+func (l *Loan) FeeTotal(kind string) float64 {
+	var total float64
+	for _, fee := range l.Fees {
+		if fee.Kind == kind {
+			total += fee.Amount
+		}
+	}
+	return total
+}
+
+// NetDisbursedAmount returns the amount handed to the borrower, the loan amount minus the deducted fees
+// and the payoff of a refinanced loan.
+func (l *Loan) NetDisbursedAmount() float64 {
+	return l.Amount - l.FeeTotal(FeeDeducted) - l.PayoffAmount
+}
@@ -0,0 +1,152 @@
+// Package money names the currency of the amounts and rounds them to the minor unit of their currency.
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// ErrCurrencyMismatch is returned when amounts of different currencies are combined.
+var ErrCurrencyMismatch = errors.New("currency mismatch")
+
+const (
+	RoundHalfUp   = "half_up"   // RoundHalfUp rounds halves away from zero
+	RoundHalfEven = "half_even" // RoundHalfEven rounds halves to the even minor unit, banker's rounding
+)
+
+// Currency is an ISO 4217 currency with the precision its amounts are kept at.
+type Currency struct {
+	Code       string
+	MinorUnits int    // MinorUnits are the decimals of the amounts
+	Rounding   string // Rounding of the amounts to the minor unit, RoundHalfUp if empty
+}
+
+var (
+	mu         sync.RWMutex
+	currencies = map[string]Currency{
+		"IDR": {Code: "IDR", MinorUnits: 0}, // the sen is out of use, rupiah amounts are whole
+		"USD": {Code: "USD", MinorUnits: 2},
+		"EUR": {Code: "EUR", MinorUnits: 2},
+		"SGD": {Code: "SGD", MinorUnits: 2},
+		"MYR": {Code: "MYR", MinorUnits: 2},
+		"PHP": {Code: "PHP", MinorUnits: 2},
+		"THB": {Code: "THB", MinorUnits: 2},
+		"INR": {Code: "INR", MinorUnits: 2},
+		"GBP": {Code: "GBP", MinorUnits: 2},
+		"JPY": {Code: "JPY", MinorUnits: 0},
+		"VND": {Code: "VND", MinorUnits: 0},
+		"KWD": {Code: "KWD", MinorUnits: 3},
+		"BHD": {Code: "BHD", MinorUnits: 3},
+	}
+)
+
+// Register adds a currency, or replaces the rules of a known one.
+func Register(c Currency) error {
+	if len(c.Code) != 3 {
+		return fmt.Errorf("currency %q is not an ISO 4217 code", c.Code)
+	}
+	if c.MinorUnits < 0 || c.MinorUnits > 4 {
+		return fmt.Errorf("currency %s: minor units must be 0 to 4", c.Code)
+	}
+	if c.Rounding != "" && c.Rounding != RoundHalfUp && c.Rounding != RoundHalfEven {
+		return fmt.Errorf("currency %s: unknown rounding %q, expected half_up or half_even", c.Code, c.Rounding)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	currencies[c.Code] = c
+	return nil
+}
+
+// Lookup returns the currency of a code.
+func Lookup(code string) (Currency, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := currencies[code]
+	if !ok {
+		return Currency{}, fmt.Errorf("unknown currency %q", code)
+	}
+	return c, nil
+}
+
+// Codes returns the codes of the known currencies, sorted.
+func Codes() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	codes := make([]string, 0, len(currencies))
+	for code := range currencies {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Round rounds an amount to the minor unit of the currency.
+func (c Currency) Round(amount float64) float64 {
+	scale := math.Pow10(c.MinorUnits)
+	scaled := amount * scale
+	// the float error of the scaling is cleared first so 1.005 is a half, not 1.00499...
+	scaled = math.Round(scaled*1e6) / 1e6
+	if c.Rounding == RoundHalfEven {
+		return math.RoundToEven(scaled) / scale
+	}
+	return math.Round(scaled) / scale
+}
+
+// Format formats an amount with the decimals of the currency.
+func (c Currency) Format(amount float64) string {
+	return strconv.FormatFloat(c.Round(amount), 'f', c.MinorUnits, 64)
+}
+
+// Round rounds an amount to the minor unit of the currency of code. Amounts of an unnamed or unknown currency are
+// left as they are.
+func Round(amount float64, code string) float64 {
+	c, err := Lookup(code)
+	if err != nil {
+		return amount
+	}
+	return c.Round(amount)
+}
+
+// Money is an amount in a currency.
+type Money struct {
+	Amount   float64
+	Currency string
+}
+
+// New returns an amount of a known currency, rounded to its minor unit.
+func New(amount float64, code string) (Money, error) {
+	c, err := Lookup(code)
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: c.Round(amount), Currency: code}, nil
+}
+
+// Add returns the sum of two amounts of the same currency.
+func (m Money) Add(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: cannot add %s to %s", ErrCurrencyMismatch, other.Currency, m.Currency)
+	}
+	return Money{Amount: Round(m.Amount+other.Amount, m.Currency), Currency: m.Currency}, nil
+}
+
+// Sub returns the difference of two amounts of the same currency.
+func (m Money) Sub(other Money) (Money, error) {
+	if m.Currency != other.Currency {
+		return Money{}, fmt.Errorf("%w: cannot subtract %s from %s", ErrCurrencyMismatch, other.Currency, m.Currency)
+	}
+	return Money{Amount: Round(m.Amount-other.Amount, m.Currency), Currency: m.Currency}, nil
+}
+
+// String formats the amount with the decimals of its currency, e.g. "IDR 110000" or "USD 12.50".
+func (m Money) String() string {
+	c, err := Lookup(m.Currency)
+	if err != nil {
+		return strconv.FormatFloat(m.Amount, 'f', -1, 64)
+	}
+	return m.Currency + " " + c.Format(m.Amount)
+}
@@ -0,0 +1,66 @@
+package money
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCurrency_Round(t *testing.T) {
+	tests := []struct {
+		code   string
+		amount float64
+		want   float64
+	}{
+		{"IDR", 110000.5, 110001},
+		{"IDR", 21.1, 21},
+		{"USD", 1.005, 1.01},
+		{"USD", -1.005, -1.01},
+		{"USD", 0.1 + 0.2, 0.3},
+		{"JPY", 1234.4, 1234},
+		{"KWD", 1.0005, 1.001},
+	}
+	for _, tc := range tests {
+		c, err := Lookup(tc.code)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, c.Round(tc.amount), "%s %v", tc.code, tc.amount)
+	}
+
+	halfEven := Currency{Code: "USD", MinorUnits: 2, Rounding: RoundHalfEven}
+	assert.Equal(t, 1.02, halfEven.Round(1.025))
+	assert.Equal(t, 1.04, halfEven.Round(1.035))
+}
+
+func TestRound_UnknownCurrency(t *testing.T) {
+	assert.Equal(t, 110.123456, Round(110.123456, ""))
+	assert.Equal(t, 110.12, Round(110.123456, "USD"))
+}
+
+func TestMoney(t *testing.T) {
+	price, err := New(12.499, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, "USD 12.50", price.String())
+
+	_, err = New(1, "XXX")
+	assert.EqualError(t, err, `unknown currency "XXX"`)
+
+	sum, err := price.Add(Money{Amount: 0.004, Currency: "USD"})
+	require.NoError(t, err)
+	assert.Equal(t, 12.5, sum.Amount)
+
+	_, err = price.Sub(Money{Amount: 1, Currency: "IDR"})
+	assert.ErrorIs(t, err, ErrCurrencyMismatch)
+	assert.Equal(t, "IDR 110000", Money{Amount: 110000.2, Currency: "IDR"}.String())
+}
+
+func TestRegister(t *testing.T) {
+	require.NoError(t, Register(Currency{Code: "CHF", MinorUnits: 2, Rounding: RoundHalfEven}))
+	c, err := Lookup("CHF")
+	require.NoError(t, err)
+	assert.Equal(t, "0.12", c.Format(0.125))
+	assert.Contains(t, Codes(), "CHF")
+
+	assert.EqualError(t, Register(Currency{Code: "CH"}), `currency "CH" is not an ISO 4217 code`)
+	assert.EqualError(t, Register(Currency{Code: "CHF", Rounding: "up"}), `currency CHF: unknown rounding "up", expected half_up or half_even`)
+}
@@ -0,0 +1,179 @@
+// Package notify delivers billing events to external systems.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"gobillingengine/engine"
+)
+
+const (
+	// SignatureHeader carries "sha256=" and the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the webhook secret.
+	SignatureHeader = "X-Billing-Signature"
+	// TimestampHeader carries the unix time the payload was signed at, to let receivers reject replays.
+	TimestampHeader = "X-Billing-Timestamp"
+	// EventHeader carries the event type.
+	EventHeader = "X-Billing-Event"
+)
+
+// WebhookConfig configures a WebhookDispatcher.
+type WebhookConfig struct {
+	URL         string
+	Secret      string
+	MaxAttempts int           // MaxAttempts per event, including the first one
+	Backoff     time.Duration // Backoff before the first retry, doubled after every failed attempt
+	QueueSize   int           // QueueSize of events waiting for delivery before Notify drops them
+	Client      *http.Client
+}
+
+// WebhookDispatcher is an engine.Subscriber posting every event as signed JSON to an HTTP endpoint.
+// Events are delivered in order by a background worker, so Notify never blocks the billing.
+type WebhookDispatcher struct {
+	cfg    WebhookConfig
+	queue  chan engine.Event
+	done   chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.RWMutex // mu guards closed against Notify racing Close
+	closed bool
+}
+
+func NewWebhookDispatcher(cfg WebhookConfig) *WebhookDispatcher {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 5
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = 500 * time.Millisecond
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1024
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &WebhookDispatcher{
+		cfg:    cfg,
+		queue:  make(chan engine.Event, cfg.QueueSize),
+		done:   make(chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	go d.run()
+	return d
+}
+
+// Notify queues the event for delivery.
+func (d *WebhookDispatcher) Notify(event engine.Event) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		log.Printf("webhook dispatcher is closed, dropping %s event of loanID:%s", event.Type, event.LoanID)
+		return
+	}
+	select {
+	case d.queue <- event:
+	default:
+		log.Printf("webhook queue is full, dropping %s event of loanID:%s", event.Type, event.LoanID)
+	}
+}
+
+// Close delivers the queued events and stops the dispatcher. Pending retries are abandoned once ctx is done.
+func (d *WebhookDispatcher) Close(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.mu.Unlock()
+
+	select {
+	case <-d.done:
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-d.done
+		return ctx.Err()
+	}
+}
+
+func (d *WebhookDispatcher) run() {
+	defer close(d.done)
+	for event := range d.queue {
+		if err := d.Deliver(d.ctx, event); err != nil {
+			log.Printf("cannot deliver %s event of loanID:%s with err: %v", event.Type, event.LoanID, err)
+		}
+	}
+}
+
+// Deliver posts the event, retrying with exponential backoff until it is accepted with a 2xx status.
+func (d *WebhookDispatcher) Deliver(ctx context.Context, event engine.Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	backoff := d.cfg.Backoff
+	for attempt := 1; ; attempt++ {
+		err = d.post(ctx, event, body)
+		if err == nil || attempt == d.cfg.MaxAttempts {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (d *WebhookDispatcher) post(ctx context.Context, event engine.Event, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(event.Type))
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(d.cfg.Secret, timestamp, body))
+
+	resp, err := d.cfg.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the signature of a payload as sent in SignatureHeader.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature received in SignatureHeader, for receivers written in Go.
+func Verify(secret, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
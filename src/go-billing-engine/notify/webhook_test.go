@@ -0,0 +1,107 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gobillingengine/engine"
+	"gobillingengine/model"
+)
+
+type receiver struct {
+	mu       sync.Mutex
+	failures int // failures left before the receiver accepts requests
+	attempts int
+	events   []engine.Event
+	verified []bool
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.attempts++
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	body, _ := io.ReadAll(req.Body)
+	var event engine.Event
+	_ = json.Unmarshal(body, &event)
+	r.events = append(r.events, event)
+	r.verified = append(r.verified, Verify("secret", req.Header.Get(TimestampHeader), body, req.Header.Get(SignatureHeader)) &&
+		req.Header.Get(EventHeader) == string(event.Type))
+}
+
+func TestWebhookDispatcher(t *testing.T) {
+	recv := &receiver{failures: 2}
+	srv := httptest.NewServer(recv)
+	defer srv.Close()
+
+	dispatcher := NewWebhookDispatcher(WebhookConfig{URL: srv.URL, Secret: "secret", Backoff: time.Millisecond})
+
+	billing := engine.NewBilling(model.NewLoan("1001", 2, 5000, 0.1))
+	billing.Subscribe(dispatcher)
+
+	require.NoError(t, billing.MakePayment(0))
+	require.NoError(t, billing.MakePayment(0))
+	require.NoError(t, billing.MakePayment(billing.PayableAmount))
+	require.NoError(t, billing.MakePayment(billing.PayableAmount))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, dispatcher.Close(ctx))
+
+	var types []engine.EventType
+	for _, event := range recv.events {
+		types = append(types, event.Type)
+	}
+	assert.Equal(t, []engine.EventType{
+		engine.EventInstallmentDue,
+		engine.EventBecameDelinquent,
+		engine.EventInstallmentDue,
+		engine.EventPaymentReceived,
+		engine.EventCured,
+		engine.EventInstallmentDue,
+		engine.EventPaymentReceived,
+		engine.EventClosed,
+	}, types)
+	assert.NotContains(t, recv.verified, false)
+	// the first event was retried twice before being accepted
+	assert.Equal(t, len(recv.events)+2, recv.attempts)
+
+	// events after close are dropped instead of panicking
+	dispatcher.Notify(engine.Event{Type: engine.EventClosed})
+}
+
+func TestWebhookDispatcher_GivesUp(t *testing.T) {
+	recv := &receiver{failures: 10}
+	srv := httptest.NewServer(recv)
+	defer srv.Close()
+
+	dispatcher := NewWebhookDispatcher(WebhookConfig{URL: srv.URL, Secret: "secret", MaxAttempts: 3, Backoff: time.Millisecond})
+	defer dispatcher.Close(context.Background())
+
+	err := dispatcher.Deliver(context.Background(), engine.Event{Type: engine.EventClosed, LoanID: "1001"})
+	assert.EqualError(t, err, "webhook responded with status 503")
+	assert.Equal(t, 3, recv.attempts)
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"type":"closed"}`)
+	signature := Sign("secret", "1715400000", body)
+
+	assert.True(t, Verify("secret", "1715400000", body, signature))
+	assert.False(t, Verify("other", "1715400000", body, signature))
+	assert.False(t, Verify("secret", "1715400001", body, signature))
+}
@@ -0,0 +1,232 @@
+package portfolio
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"gobillingengine/engine"
+	"gobillingengine/snapshot"
+)
+
+// FileStore is a Store persisted as a JSON file. The whole portfolio is kept in memory. Create and Save rewrite the
+// file, SaveAll appends the billings to a log next to it (<path>.log) which is folded into the file once it outgrows it,
+// so that saving a portfolio window by window stays linear.
+type FileStore struct {
+	*MemoryStore
+
+	path     string
+	mu       sync.Mutex // mu serialises writes of the file and the log
+	seq      int64      // seq of the last billing saved in the log
+	fileSize int64
+	logSize  int64
+}
+
+// OpenFileStore loads the portfolio stored at path, an absent file is an empty portfolio.
+func OpenFileStore(path string) (*FileStore, error) {
+	store := &FileStore{
+		MemoryStore: NewMemoryStore(),
+		path:        path,
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		var file portfolioFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, err
+		}
+		for idx, raw := range file.Billings {
+			b, err := snapshot.UnmarshalJSON(raw)
+			if err != nil {
+				return nil, fmt.Errorf("cannot load billing idx: %d with err: %v", idx, err)
+			}
+			store.billings[b.Loan.LoanID] = b
+		}
+		store.seq = file.Seq
+		store.fileSize = int64(len(data))
+	}
+
+	torn, err := store.replay()
+	if err != nil {
+		return nil, err
+	}
+	if torn {
+		// nothing can be appended after a truncated entry, the log is folded into the file right away
+		if err := store.flush(); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// replay applies the billings saved in the log after the file was last written. A crash while appending may leave the
+// last entry truncated, it is reported as torn and left out: its SaveAll never returned.
+func (s *FileStore) replay() (torn bool, err error) {
+	f, err := os.Open(s.logPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	for {
+		var entry logEntry
+		err := dec.Decode(&entry)
+		if err == io.EOF {
+			break
+		}
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return true, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("cannot read %s with err: %v", s.logPath(), err)
+		}
+		if entry.Seq <= s.seq {
+			continue // already in the file, written before the log was removed
+		}
+		b, err := snapshot.UnmarshalJSON(entry.Billing)
+		if err != nil {
+			return false, fmt.Errorf("cannot load billing seq: %d with err: %v", entry.Seq, err)
+		}
+		s.billings[b.Loan.LoanID] = b
+		s.seq = entry.Seq
+	}
+	s.logSize = dec.InputOffset()
+	return false, nil
+}
+
+func (s *FileStore) Create(b *engine.Billing) error {
+	if err := s.MemoryStore.Create(b); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+func (s *FileStore) Save(b *engine.Billing) error {
+	if err := s.MemoryStore.Save(b); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+// SaveAll saves several billings at once, appended to the log unless it would outgrow the file.
+func (s *FileStore) SaveAll(billings []*engine.Billing) error {
+	if err := s.MemoryStore.SaveAll(billings); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	seq := s.seq
+	for _, b := range billings {
+		raw, err := snapshot.MarshalJSON(b)
+		if err != nil {
+			return err
+		}
+		seq++
+		if err := enc.Encode(logEntry{Seq: seq, Billing: raw}); err != nil {
+			return err
+		}
+	}
+	if s.logSize+int64(buf.Len()) > s.fileSize {
+		return s.write()
+	}
+
+	f, err := os.OpenFile(s.logPath(), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	s.seq = seq
+	s.logSize += int64(buf.Len())
+	return nil
+}
+
+func (s *FileStore) flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.write()
+}
+
+// write writes the portfolio to a temporary file first so a crash never leaves a truncated file behind, then removes
+// the log it folds in. The file records the last billing of the log it holds, so that a crash before the log is
+// removed does not replay older billings over it.
+func (s *FileStore) write() error {
+	billings, err := s.List()
+	if err != nil {
+		return err
+	}
+	file := portfolioFile{Seq: s.seq, Billings: make([]json.RawMessage, len(billings))}
+	for i, b := range billings {
+		if file.Billings[i], err = snapshot.MarshalJSON(b); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return err
+	}
+	s.fileSize = int64(len(data))
+	if err := os.Remove(s.logPath()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	s.logSize = 0
+	return nil
+}
+
+func (s *FileStore) logPath() string {
+	return s.path + ".log"
+}
+
+// portfolioFile is the stored portfolio, a snapshot per billing. The snapshots of a file written by an older
+// engine are migrated when it is loaded, and written back in the current version on the next change.
+type portfolioFile struct {
+	Seq      int64             `json:"seq,omitempty"` // Seq of the last billing of the log folded into the file
+	Billings []json.RawMessage `json:"billings"`
+}
+
+// logEntry is a billing saved in the log, one JSON object per line.
+type logEntry struct {
+	Seq     int64           `json:"seq"`
+	Billing json.RawMessage `json:"billing"`
+}
@@ -0,0 +1,182 @@
+package portfolio
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gobillingengine/engine"
+	"gobillingengine/model"
+)
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "portfolio.json")
+
+	store, err := OpenFileStore(path)
+	require.NoError(t, err)
+
+	loan := model.NewLoan("1001", 50, 5000, 0.1)
+	loan.DisbursementDate = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	loan.BorrowerID = "b-1"
+	loan.ProductCode = "W50"
+	loan.DelinquentAfter = 3
+	loan.Fees = []model.Fee{{Code: "admin", Kind: model.FeeDeducted, Amount: 50}}
+	billing := engine.NewBilling(loan)
+	require.NoError(t, store.Create(billing))
+
+	require.NoError(t, billing.MakePayment(billing.PayableAmount))
+	require.NoError(t, billing.MakePayment(0))
+	require.NoError(t, billing.ChargePenalty(1))
+	require.NoError(t, store.Save(billing))
+
+	reopened, err := OpenFileStore(path)
+	require.NoError(t, err)
+
+	got, err := reopened.Get("1001")
+	require.NoError(t, err)
+	assert.Equal(t, billing.Loan, got.Loan)
+	assert.Equal(t, billing.PayableAmount, got.PayableAmount)
+	assert.Equal(t, billing.Outstanding, got.Outstanding)
+	assert.Equal(t, billing.MissedPayment, got.MissedPayment)
+	assert.Equal(t, billing.RemainingWeeks, got.RemainingWeeks)
+	assert.Equal(t, billing.Payments(), got.Payments())
+	assert.Equal(t, billing.Penalties, got.Penalties)
+
+	// the reopened billing keeps working as a billing
+	require.NoError(t, got.MakePayment(got.PayableAmount))
+	assert.Equal(t, 5280.0, got.Outstanding)
+}
+
+func TestFileStore_Refinanced(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "portfolio.json")
+	store, err := OpenFileStore(path)
+	require.NoError(t, err)
+
+	loan := model.NewLoan("1001", 50, 5000, 0.1)
+	loan.DisbursementDate = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	old := engine.NewBilling(loan)
+	require.NoError(t, old.MakePayment(old.PayableAmount))
+	refinanced, err := engine.Refinance(old, model.NewLoan("1002", 50, 10000, 0.1), old.DueDate(2))
+	require.NoError(t, err)
+	require.NoError(t, store.SaveAll([]*engine.Billing{old, refinanced}))
+
+	reopened, err := OpenFileStore(path)
+	require.NoError(t, err)
+	got, err := reopened.Get("1001")
+	require.NoError(t, err)
+	assert.Equal(t, old.Closure, got.Closure)
+	got, err = reopened.Get("1002")
+	require.NoError(t, err)
+	assert.Equal(t, refinanced.Loan, got.Loan)
+}
+
+func TestFileStore_MigratesOldFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "portfolio.json")
+	old := `{"billings":[{"loan":{"loan_id":"1001","amount":5000,"flat_interest_rate":0.1,"weeks":50,` +
+		`"disbursement_date":"2024-01-01T00:00:00Z"},"payable_amount":110,"outstanding":5390,"missed_payment":0,` +
+		`"remaining_weeks":49,"payments":[{"week":1,"amount":110,"date":"2024-01-08T00:00:00Z"}]}]}`
+	require.NoError(t, os.WriteFile(path, []byte(old), 0o644))
+
+	store, err := OpenFileStore(path)
+	require.NoError(t, err)
+	got, err := store.Get("1001")
+	require.NoError(t, err)
+	assert.Equal(t, 5390.0, got.Outstanding)
+	assert.Equal(t, 1, len(got.Payments()))
+
+	// the next change writes the file in the current snapshot version
+	require.NoError(t, got.MakePayment(got.PayableAmount))
+	require.NoError(t, store.Save(got))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"version": 5`)
+}
+
+func TestFileStore_SaveAllLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "portfolio.json")
+	store, err := OpenFileStore(path)
+	require.NoError(t, err)
+	billings := make([]*engine.Billing, 20)
+	for i := range billings {
+		loan := model.NewLoan(fmt.Sprintf("%04d", i), 50, 5000, 0.1)
+		loan.DisbursementDate = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		billings[i] = engine.NewBilling(loan)
+	}
+	// the log of the first save would outgrow the empty file, so it is written in full
+	require.NoError(t, store.SaveAll(billings))
+	_, err = os.Stat(path + ".log")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	written, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	// a small window is appended to the log, the file is left as it was
+	require.NoError(t, billings[0].MakePayment(billings[0].PayableAmount))
+	require.NoError(t, store.SaveAll(billings[:1]))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, written, data)
+
+	reopened, err := OpenFileStore(path)
+	require.NoError(t, err)
+	got, err := reopened.Get("0000")
+	require.NoError(t, err)
+	assert.Equal(t, 5390.0, got.Outstanding)
+
+	// a crash while appending leaves a truncated entry, which is left out and folded away
+	require.NoError(t, billings[1].MakePayment(billings[1].PayableAmount))
+	require.NoError(t, store.SaveAll(billings[1:2]))
+	log, err := os.ReadFile(path + ".log")
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path+".log", log[:len(log)-10], 0o644))
+	reopened, err = OpenFileStore(path)
+	require.NoError(t, err)
+	got, err = reopened.Get("0001")
+	require.NoError(t, err)
+	assert.Equal(t, 5500.0, got.Outstanding)
+	got, err = reopened.Get("0000")
+	require.NoError(t, err)
+	assert.Equal(t, 5390.0, got.Outstanding)
+	_, err = os.Stat(path + ".log")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// a log left behind by a crash after the file was written is not replayed over it
+	require.NoError(t, reopened.SaveAll([]*engine.Billing{got}))
+	log, err = os.ReadFile(path + ".log")
+	require.NoError(t, err)
+	require.NoError(t, got.MakePayment(got.PayableAmount))
+	require.NoError(t, reopened.Save(got))
+	require.NoError(t, os.WriteFile(path+".log", log, 0o644))
+	reopened, err = OpenFileStore(path)
+	require.NoError(t, err)
+	got, err = reopened.Get("0000")
+	require.NoError(t, err)
+	assert.Equal(t, 5280.0, got.Outstanding)
+}
+
+// BenchmarkFileStore_SaveAll saves a portfolio window by window, the way a batch run does.
+func BenchmarkFileStore_SaveAll(b *testing.B) {
+	const loans, window = 20000, 1000
+	billings := make([]*engine.Billing, loans)
+	for i := range billings {
+		loan := model.NewLoan(fmt.Sprintf("%06d", i), 50, 5000, 0.1)
+		loan.DisbursementDate = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		billings[i] = engine.NewBilling(loan)
+	}
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		store, err := OpenFileStore(filepath.Join(b.TempDir(), "portfolio.json"))
+		require.NoError(b, err)
+		require.NoError(b, store.SaveAll(billings))
+		b.StartTimer()
+
+		for start := 0; start < loans; start += window {
+			require.NoError(b, store.SaveAll(billings[start:start+window]))
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package portfolio
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"gobillingengine/engine"
+)
+
+var (
+	ErrNotFound      = errors.New("billing not found")
+	ErrAlreadyExists = errors.New("billing already exists")
+)
+
+// Store keeps billings addressable by their loan ID.
+type Store interface {
+	Get(loanID string) (*engine.Billing, error)
+	Create(b *engine.Billing) error
+	Save(b *engine.Billing) error
+	List() ([]*engine.Billing, error)
+}
+
+// MemoryStore is a Store backed by a map, safe for concurrent use.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	billings map[string]*engine.Billing
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		billings: make(map[string]*engine.Billing),
+	}
+}
+
+func (s *MemoryStore) Get(loanID string) (*engine.Billing, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	b, ok := s.billings[loanID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return b, nil
+}
+
+// Create adds a new billing and fails if the loan ID is already taken.
+func (s *MemoryStore) Create(b *engine.Billing) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.billings[b.Loan.LoanID]; ok {
+		return ErrAlreadyExists
+	}
+	s.billings[b.Loan.LoanID] = b
+	return nil
+}
+
+// Save adds or replaces the billing stored under its loan ID.
+func (s *MemoryStore) Save(b *engine.Billing) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.billings[b.Loan.LoanID] = b
+	return nil
+}
+
+// SaveAll adds or replaces several billings at once.
+func (s *MemoryStore) SaveAll(billings []*engine.Billing) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, b := range billings {
+		s.billings[b.Loan.LoanID] = b
+	}
+	return nil
+}
+
+// List returns every billing ordered by loan ID.
+func (s *MemoryStore) List() ([]*engine.Billing, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	billings := make([]*engine.Billing, 0, len(s.billings))
+	for _, b := range s.billings {
+		billings = append(billings, b)
+	}
+	sort.Slice(billings, func(i, j int) bool {
+		return billings[i].Loan.LoanID < billings[j].Loan.LoanID
+	})
+	return billings, nil
+}
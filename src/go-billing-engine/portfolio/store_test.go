@@ -0,0 +1,51 @@
+package portfolio
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"gobillingengine/engine"
+	"gobillingengine/model"
+)
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+
+	_, err := store.Get("1001")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	b2 := engine.NewBilling(model.NewLoan("1002", 50, 5000, 0.1))
+	b1 := engine.NewBilling(model.NewLoan("1001", 50, 5000, 0.1))
+	assert.NoError(t, store.Create(b2))
+	assert.NoError(t, store.Create(b1))
+	assert.ErrorIs(t, store.Create(b1), ErrAlreadyExists)
+
+	got, err := store.Get("1001")
+	assert.NoError(t, err)
+	assert.Same(t, b1, got)
+
+	billings, err := store.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []*engine.Billing{b1, b2}, billings)
+}
+
+type countingSubscriber struct{ events int }
+
+func (s *countingSubscriber) Notify(engine.Event) { s.events++ }
+
+func TestSubscribedStore(t *testing.T) {
+	subscriber := &countingSubscriber{}
+	store := &SubscribedStore{Store: NewMemoryStore(), Subscribers: []engine.Subscriber{subscriber}}
+	assert.NoError(t, store.Create(engine.NewBilling(model.NewLoan("1001", 50, 5000, 0.1))))
+
+	// the memory store hands out the same billing every time, it is subscribed once
+	for i := 0; i < 2; i++ {
+		b, err := store.Get("1001")
+		assert.NoError(t, err)
+		assert.NoError(t, b.MakePayment(110))
+	}
+	_, err := store.List()
+	assert.NoError(t, err)
+	assert.Equal(t, 4, subscriber.events) // payment_received and installment_due of each payment
+}
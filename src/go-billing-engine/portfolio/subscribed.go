@@ -0,0 +1,54 @@
+package portfolio
+
+import "gobillingengine/engine"
+
+// SubscribedStore is a Store registering its subscribers on every billing it hands out or creates, e.g. to deliver the
+// events of the billings served by a command to a webhook.
+type SubscribedStore struct {
+	Store
+	Subscribers []engine.Subscriber
+}
+
+func (s *SubscribedStore) Get(loanID string) (*engine.Billing, error) {
+	b, err := s.Store.Get(loanID)
+	if err != nil {
+		return nil, err
+	}
+	s.subscribe(b)
+	return b, nil
+}
+
+func (s *SubscribedStore) Create(b *engine.Billing) error {
+	s.subscribe(b)
+	return s.Store.Create(b)
+}
+
+func (s *SubscribedStore) List() ([]*engine.Billing, error) {
+	billings, err := s.Store.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range billings {
+		s.subscribe(b)
+	}
+	return billings, nil
+}
+
+// SaveAll saves several billings at once when the wrapped store can, one by one otherwise.
+func (s *SubscribedStore) SaveAll(billings []*engine.Billing) error {
+	if saver, ok := s.Store.(interface{ SaveAll([]*engine.Billing) error }); ok {
+		return saver.SaveAll(billings)
+	}
+	for _, b := range billings {
+		if err := s.Store.Save(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SubscribedStore) subscribe(b *engine.Billing) {
+	for _, subscriber := range s.Subscribers {
+		b.Subscribe(subscriber)
+	}
+}
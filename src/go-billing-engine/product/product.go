@@ -0,0 +1,212 @@
+// Package product defines the loan products offered to borrowers and creates loans from them.
+package product
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"gobillingengine/model"
+	"gobillingengine/money"
+)
+
+const (
+	ProrationNone   = "none"   // ProrationNone the annual rate applies flat to the whole term
+	ProrationWeekly = "weekly" // ProrationWeekly the annual rate is prorated by the weeks of the term over 52
+)
+
+// FrequencyWeekly is the only repayment frequency the billing engine supports.
+const FrequencyWeekly = "weekly"
+
+var ErrUnknownProduct = errors.New("unknown product")
+
+// Fee is a fee charged on the loans of a product, either a flat Amount or a Rate of the loan amount.
+// Kind is one of model.FeeDeducted, model.FeeFinanced and model.FeeInstallment.
+type Fee struct {
+	Code   string  `yaml:"code"`
+	Kind   string  `yaml:"kind"`
+	Amount float64 `yaml:"amount,omitempty"`
+	Rate   float64 `yaml:"rate,omitempty"`
+}
+
+// DelinquencyPolicy decides when a borrower is delinquent and what a missed payment costs.
+type DelinquencyPolicy struct {
+	MissedPayments int     `yaml:"missed_payments"` // MissedPayments continuous missed payments make the borrower delinquent
+	LateFee        float64 `yaml:"late_fee"`        // LateFee charged as a penalty for each missed payment
+}
+
+type Product struct {
+	Code        string            `yaml:"code"`
+	Name        string            `yaml:"name"`
+	Currency    string            `yaml:"currency"`
+	MinAmount   float64           `yaml:"min_amount"`
+	MaxAmount   float64           `yaml:"max_amount"`
+	Tenors      []int             `yaml:"tenors"`      // Tenors allowed, in weeks
+	AnnualRate  float64           `yaml:"annual_rate"` // AnnualRate is a fraction, 0.1 for 10% per annum
+	Proration   string            `yaml:"proration"`
+	Variable    bool              `yaml:"variable"` // Variable rate of the loans, repriced over their term by rate changes
+	Frequency   string            `yaml:"frequency"`
+	Fees        []Fee             `yaml:"fees"`
+	Delinquency DelinquencyPolicy `yaml:"delinquency"`
+}
+
+// TermRate returns the flat interest rate of a loan of the given tenor.
+func (p *Product) TermRate(weeks int) float64 {
+	return p.Prorate(p.AnnualRate, weeks)
+}
+
+// Prorate returns the flat interest rate over a tenor of an annual rate, e.g. the new rate of a variable-rate loan.
+func (p *Product) Prorate(annualRate float64, weeks int) float64 {
+	if p.Proration == ProrationWeekly {
+		return annualRate * float64(weeks) / 52
+	}
+	return annualRate
+}
+
+// NewLoan creates a loan of the product after checking the amount and tenor are offered.
+func (p *Product) NewLoan(loanID string, amount float64, weeks int, disbursedAt time.Time) (*model.Loan, error) {
+	if amount < p.MinAmount || amount > p.MaxAmount {
+		return nil, fmt.Errorf("product %s lends between %.2f and %.2f, got %.2f", p.Code, p.MinAmount, p.MaxAmount, amount)
+	}
+	if !slices.Contains(p.Tenors, weeks) {
+		return nil, fmt.Errorf("product %s does not offer a %d weeks tenor, allowed: %v", p.Code, weeks, p.Tenors)
+	}
+
+	loan := model.NewLoan(loanID, weeks, amount, p.TermRate(weeks))
+	loan.DisbursementDate = disbursedAt
+	loan.ProductCode = p.Code
+	loan.Currency = p.Currency
+	loan.VariableRate = p.Variable
+	loan.DelinquentAfter = p.Delinquency.MissedPayments
+	for _, fee := range p.Fees {
+		loan.Fees = append(loan.Fees, fee.loanFee(amount, p.Currency))
+	}
+	if loan.NetDisbursedAmount() <= 0 {
+		return nil, fmt.Errorf("product %s: the deducted fees exceed the amount %.2f", p.Code, amount)
+	}
+	return loan, nil
+}
+
+// loanFee resolves the fee for a loan of the given amount, rounded to the minor unit of the currency, to the cent if
+// the currency is unknown.
+func (f Fee) loanFee(amount float64, currency string) model.Fee {
+	fee := model.Fee{Code: f.Code, Kind: f.Kind, Amount: f.Amount}
+	if f.Rate > 0 {
+		fee.Amount = math.Round(f.Rate*amount*100) / 100
+		if c, err := money.Lookup(currency); err == nil {
+			fee.Amount = c.Round(f.Rate * amount)
+		}
+	}
+	return fee
+}
+
+func (p *Product) validate() error {
+	switch {
+	case p.Code == "":
+		return errors.New("product code is empty")
+	case len(p.Currency) != 3:
+		return fmt.Errorf("product %s: currency %q is not an ISO 4217 code", p.Code, p.Currency)
+	case p.MinAmount <= 0 || p.MaxAmount < p.MinAmount:
+		return fmt.Errorf("product %s: invalid amount range %.2f-%.2f", p.Code, p.MinAmount, p.MaxAmount)
+	case len(p.Tenors) == 0:
+		return fmt.Errorf("product %s: no tenor", p.Code)
+	case p.AnnualRate < 0 || p.AnnualRate > 1:
+		// rates are fractions, a rate of 10 would mean 1000%
+		return fmt.Errorf("product %s: annual rate %v must be a fraction between 0 and 1", p.Code, p.AnnualRate)
+	case p.Proration != ProrationNone && p.Proration != ProrationWeekly:
+		return fmt.Errorf("product %s: unknown proration %q", p.Code, p.Proration)
+	case p.Frequency != FrequencyWeekly:
+		return fmt.Errorf("product %s: unsupported frequency %q, only %s is supported", p.Code, p.Frequency, FrequencyWeekly)
+	case p.Delinquency.MissedPayments < 1:
+		return fmt.Errorf("product %s: delinquency needs at least 1 missed payment", p.Code)
+	case p.Delinquency.LateFee < 0:
+		return fmt.Errorf("product %s: late fee must not be negative", p.Code)
+	}
+	for _, tenor := range p.Tenors {
+		if tenor <= 0 {
+			return fmt.Errorf("product %s: invalid tenor %d", p.Code, tenor)
+		}
+	}
+	for idx, fee := range p.Fees {
+		if fee.Kind != model.FeeDeducted && fee.Kind != model.FeeFinanced && fee.Kind != model.FeeInstallment {
+			return fmt.Errorf("product %s: unknown kind %q of fee idx: %d", p.Code, fee.Kind, idx)
+		}
+		if fee.Amount < 0 || fee.Rate < 0 || (fee.Amount == 0) == (fee.Rate == 0) {
+			return fmt.Errorf("product %s: fee idx: %d needs either an amount or a rate", p.Code, idx)
+		}
+	}
+	return nil
+}
+
+// Catalog is the set of products on offer, by code.
+type Catalog struct {
+	products map[string]*Product
+	codes    []string
+}
+
+// Get returns the product of the given code.
+func (c *Catalog) Get(code string) (*Product, error) {
+	p, ok := c.products[code]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownProduct, code)
+	}
+	return p, nil
+}
+
+// Products returns the products in catalog order.
+func (c *Catalog) Products() []*Product {
+	products := make([]*Product, len(c.codes))
+	for i, code := range c.codes {
+		products[i] = c.products[code]
+	}
+	return products
+}
+
+// LoadCatalog reads a YAML catalog, a list of products under the products key.
+// Proration defaults to none and frequency to weekly.
+func LoadCatalog(r io.Reader) (*Catalog, error) {
+	var file struct {
+		Products []*Product `yaml:"products"`
+	}
+	if err := yaml.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+
+	catalog := &Catalog{products: make(map[string]*Product)}
+	for _, p := range file.Products {
+		if p.Proration == "" {
+			p.Proration = ProrationNone
+		}
+		if p.Frequency == "" {
+			p.Frequency = FrequencyWeekly
+		}
+		if p.Delinquency.MissedPayments == 0 {
+			p.Delinquency.MissedPayments = model.DefaultDelinquentAfter
+		}
+		if err := p.validate(); err != nil {
+			return nil, err
+		}
+		if _, ok := catalog.products[p.Code]; ok {
+			return nil, fmt.Errorf("product %s is defined twice", p.Code)
+		}
+		catalog.products[p.Code] = p
+		catalog.codes = append(catalog.codes, p.Code)
+	}
+	return catalog, nil
+}
+
+// Load reads the YAML catalog at path.
+func Load(path string) (*Catalog, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadCatalog(f)
+}
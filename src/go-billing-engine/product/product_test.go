@@ -0,0 +1,105 @@
+package product
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gobillingengine/engine"
+	"gobillingengine/model"
+)
+
+const catalogYAML = `
+products:
+  - code: W50
+    currency: IDR
+    min_amount: 1000000
+    max_amount: 10000000
+    tenors: [50]
+    annual_rate: 0.1
+  - code: W26
+    currency: IDR
+    min_amount: 500000
+    max_amount: 5000000
+    tenors: [26, 52]
+    annual_rate: 0.2
+    proration: weekly
+    variable: true
+    fees:
+      - {code: admin, kind: deducted, rate: 0.01}
+    delinquency:
+      missed_payments: 3
+      late_fee: 5000
+`
+
+func TestLoadCatalog(t *testing.T) {
+	catalog, err := LoadCatalog(strings.NewReader(catalogYAML))
+	require.NoError(t, err)
+	require.Len(t, catalog.Products(), 2)
+
+	w50, err := catalog.Get("W50")
+	require.NoError(t, err)
+	assert.Equal(t, ProrationNone, w50.Proration)
+	assert.Equal(t, FrequencyWeekly, w50.Frequency)
+	assert.Equal(t, 2, w50.Delinquency.MissedPayments)
+
+	_, err = catalog.Get("M12")
+	assert.ErrorIs(t, err, ErrUnknownProduct)
+}
+
+func TestLoadCatalog_Invalid(t *testing.T) {
+	for name, yaml := range map[string]string{
+		"percent rate":   "products: [{code: A, currency: IDR, min_amount: 1, max_amount: 2, tenors: [50], annual_rate: 10.0}]",
+		"monthly":        "products: [{code: A, currency: IDR, min_amount: 1, max_amount: 2, tenors: [50], frequency: monthly}]",
+		"no tenor":       "products: [{code: A, currency: IDR, min_amount: 1, max_amount: 2}]",
+		"bad currency":   "products: [{code: A, currency: RUPIAH, min_amount: 1, max_amount: 2, tenors: [50]}]",
+		"ambiguous fee":  "products: [{code: A, currency: IDR, min_amount: 1, max_amount: 2, tenors: [50], fees: [{code: f, kind: financed, amount: 1, rate: 0.1}]}]",
+		"duplicate code": "products: [{code: A, currency: IDR, min_amount: 1, max_amount: 2, tenors: [50]}, {code: A, currency: IDR, min_amount: 1, max_amount: 2, tenors: [50]}]",
+	} {
+		_, err := LoadCatalog(strings.NewReader(yaml))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestNewLoan(t *testing.T) {
+	catalog, err := LoadCatalog(strings.NewReader(catalogYAML))
+	require.NoError(t, err)
+	disbursedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// the requirement example: 5,000,000 over 50 weeks at 10% is repaid as 50 x 110,000
+	w50, _ := catalog.Get("W50")
+	loan, err := w50.NewLoan("100", 5000000, 50, disbursedAt)
+	require.NoError(t, err)
+	assert.Equal(t, 0.1, loan.FlatInterestRate)
+	assert.Equal(t, "W50", loan.ProductCode)
+	assert.Equal(t, disbursedAt, loan.DisbursementDate)
+	billing := engine.NewBilling(loan)
+	assert.Equal(t, 110000.0, billing.PayableAmount)
+	assert.Equal(t, 5500000.0, billing.Outstanding)
+
+	// 20% a year prorated over half a year
+	w26, _ := catalog.Get("W26")
+	loan, err = w26.NewLoan("101", 1000000, 26, disbursedAt)
+	require.NoError(t, err)
+	assert.Equal(t, 0.1, loan.FlatInterestRate)
+	assert.True(t, loan.VariableRate)
+	billing = engine.NewBilling(loan)
+	for i := 0; i < 2; i++ {
+		require.NoError(t, billing.MakePayment(0))
+	}
+	assert.False(t, billing.IsDelinquent()) // the product tolerates 2 missed payments
+	require.NoError(t, billing.MakePayment(0))
+	assert.True(t, billing.IsDelinquent())
+
+	// the admin fee is 1% of the amount, deducted at disbursement
+	assert.Equal(t, []model.Fee{{Code: "admin", Kind: model.FeeDeducted, Amount: 10000}}, loan.Fees)
+	assert.Equal(t, 990000.0, loan.NetDisbursedAmount())
+
+	_, err = w50.NewLoan("102", 500000, 50, disbursedAt)
+	assert.EqualError(t, err, "product W50 lends between 1000000.00 and 10000000.00, got 500000.00")
+	_, err = w50.NewLoan("102", 5000000, 25, disbursedAt)
+	assert.EqualError(t, err, "product W50 does not offer a 25 weeks tenor, allowed: [50]")
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"gobillingengine/engine"
+	"gobillingengine/product"
+)
+
+func newProductsCmd() *cobra.Command {
+	productsCmd := &cobra.Command{
+		Use:   "products",
+		Short: "List the loan products of a catalog with an example installment",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, _ := cmd.Flags().GetString("catalog")
+			catalog, err := product.Load(path)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			for _, p := range catalog.Products() {
+				rate := "annual rate"
+				if p.Variable {
+					rate = "variable annual rate"
+				}
+				fmt.Fprintf(out, "%s %s (%s): %.2f-%.2f, tenors %v weeks, %s %.4g (proration %s), delinquent after %d missed payments\n",
+					p.Code, p.Name, p.Currency, p.MinAmount, p.MaxAmount, p.Tenors, rate, p.AnnualRate, p.Proration, p.Delinquency.MissedPayments)
+				for _, weeks := range p.Tenors {
+					loan, err := p.NewLoan("example", p.MaxAmount, weeks, time.Time{})
+					if err != nil {
+						return err
+					}
+					b := engine.NewBilling(loan)
+					fmt.Fprintf(out, "  %d weeks: %.2f repaid as %d x %.2f (term rate %.4g)\n",
+						weeks, p.MaxAmount, weeks, b.PayableAmount, loan.FlatInterestRate)
+				}
+				if len(p.Fees) > 0 {
+					fees := make([]string, len(p.Fees))
+					for i, fee := range p.Fees {
+						fees[i] = fmt.Sprintf("%s (%s)", fee.Code, fee.Kind)
+					}
+					fmt.Fprintf(out, "  fees: %s\n", strings.Join(fees, ", "))
+				}
+			}
+			return nil
+		},
+	}
+	productsCmd.Flags().String("catalog", "examples/products.yaml", "The YAML product catalog")
+	return productsCmd
+}
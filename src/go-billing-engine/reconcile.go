@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"gobillingengine/portfolio"
+	"gobillingengine/reconcile"
+)
+
+func newReconcileCmd() *cobra.Command {
+	reconcileCmd := &cobra.Command{
+		Use:   "reconcile <mutation file>",
+		Short: "Post a bank mutation file to the portfolio and report the reconciliation",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, _ := cmd.Flags().GetString("portfolio")
+			accountsPath, _ := cmd.Flags().GetString("accounts")
+			postedPath, _ := cmd.Flags().GetString("posted")
+			if postedPath == "" {
+				postedPath = path + ".posted"
+			}
+			format, _ := cmd.Flags().GetString("format")
+
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			var mutations []reconcile.Mutation
+			switch format {
+			case "csv":
+				mutations, err = reconcile.ParseCSV(f)
+			case "fixed":
+				mutations, err = reconcile.ParseFixedWidth(f, reconcile.DefaultFixedWidthLayout)
+			default:
+				err = fmt.Errorf("unknown format %q, expected csv or fixed", format)
+			}
+			f.Close()
+			if err != nil {
+				return err
+			}
+
+			store, err := portfolio.OpenFileStore(path)
+			if err != nil {
+				return err
+			}
+			importer := &reconcile.Importer{Store: store}
+			if accountsPath != "" {
+				f, err := os.Open(accountsPath)
+				if err != nil {
+					return err
+				}
+				importer.VirtualAccounts, err = reconcile.LoadVirtualAccounts(f)
+				f.Close()
+				if err != nil {
+					return fmt.Errorf("cannot load virtual accounts with err: %v", err)
+				}
+			}
+
+			if data, err := os.ReadFile(postedPath); err == nil {
+				if importer.Posted, err = reconcile.LoadPosted(bytes.NewReader(data)); err != nil {
+					return fmt.Errorf("cannot load posted transactions with err: %v", err)
+				}
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+
+			report, err := importer.Import(mutations)
+			// what was posted before a failure is kept too
+			if errPosted := writePosted(postedPath, importer.Posted); err == nil {
+				err = errPosted
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(cmd.ErrOrStderr(), report.Summary())
+
+			out, closeOut, err := getOutput(cmd)
+			if err != nil {
+				return err
+			}
+			err = report.WriteCSV(out)
+			if errClose := closeOut(); err == nil {
+				err = errClose
+			}
+			return err
+		},
+	}
+	reconcileCmd.Flags().String("portfolio", "portfolio.json", "The persisted portfolio file")
+	reconcileCmd.Flags().String("accounts", "", "CSV of virtual_account,loan_id mapping virtual accounts to loans")
+	reconcileCmd.Flags().String("posted", "", "The file of the transaction IDs already posted, <portfolio>.posted by default")
+	reconcileCmd.Flags().String("format", "csv", "The mutation file format, csv or fixed")
+	reconcileCmd.Flags().StringP("output", "o", "", "Write the reconciliation report to this file instead of stdout")
+	return reconcileCmd
+}
+
+func writePosted(path string, posted map[string]bool) error {
+	var buf bytes.Buffer
+	if err := reconcile.WritePosted(&buf, posted); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
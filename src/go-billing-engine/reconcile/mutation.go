@@ -0,0 +1,133 @@
+// Package reconcile posts bank mutations to the billings and reports what could not be matched.
+package reconcile
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Mutation is a credit line of a bank statement.
+type Mutation struct {
+	Line           int // Line of the file, starting at 1
+	Date           time.Time
+	VirtualAccount string
+	TransactionID  string
+	Reference      string
+	Amount         float64
+	Invalid        string // Invalid is why the line could not be parsed, empty for a valid line
+}
+
+// ParseCSV reads a mutation CSV with the header date,virtual_account,transaction_id,reference,amount.
+// Dates are YYYY-MM-DD and amounts are decimal numbers.
+func ParseCSV(r io.Reader) ([]Mutation, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var mutations []Mutation
+	for idx, row := range rows {
+		if idx == 0 {
+			continue // header
+		}
+		m := Mutation{Line: idx + 1}
+		if len(row) != 5 {
+			m.Invalid = fmt.Sprintf("expect 5 columns, got %d", len(row))
+			mutations = append(mutations, m)
+			continue
+		}
+		m.VirtualAccount = strings.TrimSpace(row[1])
+		m.TransactionID = strings.TrimSpace(row[2])
+		m.Reference = strings.TrimSpace(row[3])
+		if m.Date, err = time.Parse("2006-01-02", strings.TrimSpace(row[0])); err != nil {
+			m.Invalid = fmt.Sprintf("invalid date %q", row[0])
+		} else if m.Amount, err = strconv.ParseFloat(strings.TrimSpace(row[4]), 64); err != nil {
+			m.Invalid = fmt.Sprintf("invalid amount %q", row[4])
+		}
+		mutations = append(mutations, m)
+	}
+	return mutations, nil
+}
+
+// Field is a column of a fixed-width line, Start is 1-based like in the bank specifications.
+type Field struct {
+	Start  int
+	Length int
+}
+
+func (f Field) slice(line string) (string, bool) {
+	end := f.Start - 1 + f.Length
+	if f.Start < 1 || end > len(line) {
+		return "", false
+	}
+	return strings.TrimSpace(line[f.Start-1 : end]), true
+}
+
+// FixedWidthLayout positions the fields of a fixed-width mutation file.
+// Dates are YYYYMMDD and amounts are integers in cents.
+type FixedWidthLayout struct {
+	Date           Field
+	VirtualAccount Field
+	Amount         Field
+	TransactionID  Field
+	Reference      Field
+}
+
+// DefaultFixedWidthLayout is the layout of the daily virtual account mutation file of our bank.
+var DefaultFixedWidthLayout = FixedWidthLayout{
+	Date:           Field{Start: 1, Length: 8},
+	VirtualAccount: Field{Start: 9, Length: 16},
+	Amount:         Field{Start: 25, Length: 15},
+	TransactionID:  Field{Start: 40, Length: 20},
+	Reference:      Field{Start: 60, Length: 40},
+}
+
+// ParseFixedWidth reads a fixed-width mutation file, skipping blank lines.
+func ParseFixedWidth(r io.Reader, layout FixedWidthLayout) ([]Mutation, error) {
+	var mutations []Mutation
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		mutations = append(mutations, parseFixedWidthLine(line, text, layout))
+	}
+	return mutations, scanner.Err()
+}
+
+func parseFixedWidthLine(line int, text string, layout FixedWidthLayout) Mutation {
+	m := Mutation{Line: line}
+	date, ok1 := layout.Date.slice(text)
+	amount, ok2 := layout.Amount.slice(text)
+	va, ok3 := layout.VirtualAccount.slice(text)
+	txID, ok4 := layout.TransactionID.slice(text)
+	if !ok1 || !ok2 || !ok3 || !ok4 {
+		m.Invalid = fmt.Sprintf("line is too short (%d characters)", len(text))
+		return m
+	}
+	// the reference is the trailing field and may be cut short
+	m.Reference, _ = layout.Reference.slice(text + strings.Repeat(" ", layout.Reference.Length))
+	m.VirtualAccount = va
+	m.TransactionID = txID
+
+	var err error
+	if m.Date, err = time.Parse("20060102", date); err != nil {
+		m.Invalid = fmt.Sprintf("invalid date %q", date)
+		return m
+	}
+	cents, err := strconv.ParseInt(amount, 10, 64)
+	if err != nil {
+		m.Invalid = fmt.Sprintf("invalid amount %q", amount)
+		return m
+	}
+	m.Amount = float64(cents) / 100
+	return m
+}
@@ -0,0 +1,254 @@
+package reconcile
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gobillingengine/engine"
+	"gobillingengine/portfolio"
+)
+
+const (
+	StatusMatched   = "matched"   // StatusMatched the mutation was posted to a loan
+	StatusUnmatched = "unmatched" // StatusUnmatched no loan was found for the mutation
+	StatusRejected  = "rejected"  // StatusRejected the mutation is invalid or the billing refused the payment
+)
+
+// Item is the reconciliation outcome of a mutation.
+type Item struct {
+	Mutation
+	LoanID string
+	Status string
+	Reason string
+}
+
+// Report is the reconciliation of a mutation file.
+type Report struct {
+	Items           []Item
+	Matched         int
+	Unmatched       int
+	Rejected        int
+	MatchedAmount   float64
+	UnmatchedAmount float64
+	RejectedAmount  float64
+}
+
+func (r *Report) add(item Item) {
+	r.Items = append(r.Items, item)
+	switch item.Status {
+	case StatusMatched:
+		r.Matched++
+		r.MatchedAmount += item.Amount
+	case StatusUnmatched:
+		r.Unmatched++
+		r.UnmatchedAmount += item.Amount
+	case StatusRejected:
+		r.Rejected++
+		r.RejectedAmount += item.Amount
+	}
+}
+
+// Importer posts bank mutations to the billings of a portfolio.
+type Importer struct {
+	Store           portfolio.Store
+	VirtualAccounts map[string]string // VirtualAccounts maps a virtual account number to its loan ID
+	Posted          map[string]bool   // Posted transaction IDs of the earlier imports, the ones posted by Import are added
+}
+
+// Import matches every mutation to a loan, by virtual account first then by a loan ID quoted in the reference,
+// and posts the matched ones with MakePayment. A mutation paying several installments at once settles as many periods
+// of the billing, any other amount is posted as it is for the billing to refuse. A transaction already posted, by this
+// import or an earlier one, is rejected so that importing a file twice does not charge the borrowers twice.
+func (im *Importer) Import(mutations []Mutation) (*Report, error) {
+	report := &Report{}
+	if im.Posted == nil {
+		im.Posted = make(map[string]bool)
+	}
+	seen := make(map[string]bool)
+	for _, m := range mutations {
+		item := Item{Mutation: m}
+		switch {
+		case m.Invalid != "":
+			item.Status, item.Reason = StatusRejected, m.Invalid
+		case m.Amount <= 0:
+			item.Status, item.Reason = StatusRejected, "amount must be positive"
+		case m.TransactionID != "" && seen[m.TransactionID]:
+			item.Status, item.Reason = StatusRejected, fmt.Sprintf("duplicate transaction %s", m.TransactionID)
+		case m.TransactionID != "" && im.Posted[m.TransactionID]:
+			item.Status, item.Reason = StatusRejected, fmt.Sprintf("transaction %s was already posted", m.TransactionID)
+		default:
+			seen[m.TransactionID] = true
+			if err := im.post(&item); err != nil {
+				return report, err
+			}
+			if item.Status == StatusMatched && m.TransactionID != "" {
+				im.Posted[m.TransactionID] = true
+			}
+		}
+		report.add(item)
+	}
+	return report, nil
+}
+
+func (im *Importer) post(item *Item) error {
+	loanID, ok := im.VirtualAccounts[item.VirtualAccount]
+	if !ok {
+		loanID = im.matchReference(item.Reference)
+	}
+	if loanID == "" {
+		item.Status, item.Reason = StatusUnmatched, "no loan for the virtual account or reference"
+		return nil
+	}
+
+	b, err := im.Store.Get(loanID)
+	if errors.Is(err, portfolio.ErrNotFound) {
+		item.Status, item.Reason = StatusUnmatched, fmt.Sprintf("loan %s has no billing", loanID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	item.LoanID = loanID
+	if n := installments(b, item.Amount); n == 0 {
+		// not a whole number of installments, posted as it is for the billing to tell why it is refused
+		if err := b.MakePayment(item.Amount); err != nil {
+			item.Status, item.Reason = StatusRejected, err.Error()
+			return nil
+		}
+	} else {
+		for i := 0; i < n; i++ {
+			if err := b.MakePayment(b.DueAmount()); err != nil {
+				item.Status, item.Reason = StatusRejected, err.Error()
+				return nil
+			}
+		}
+	}
+	if err := im.Store.Save(b); err != nil {
+		return err
+	}
+	item.Status = StatusMatched
+	return nil
+}
+
+// installments returns how many of the coming installments of the billing the amount pays, 0 if it does not pay a
+// whole number of them.
+func installments(b *engine.Billing, amount float64) int {
+	schedule := b.GenerateRemainingLoanSchedule()
+	var covered float64
+	for n := 1; !schedule.Empty(); n++ {
+		val, _ := schedule.Pop()
+		covered += val.(*engine.Payment).Amount
+		if n == b.RemainingWeeks {
+			covered = b.Outstanding // the last one takes what is left
+		}
+		switch {
+		case b.Round(covered) == b.Round(amount):
+			return n
+		case covered > amount:
+			return 0
+		}
+	}
+	return 0
+}
+
+// matchReference finds a loan ID among the words of the reference.
+func (im *Importer) matchReference(reference string) string {
+	for _, word := range strings.FieldsFunc(reference, func(r rune) bool {
+		return r == ' ' || r == '/' || r == ',' || r == ';' || r == ':'
+	}) {
+		if _, err := im.Store.Get(word); err == nil {
+			return word
+		}
+	}
+	return ""
+}
+
+// LoadVirtualAccounts reads a CSV with the columns virtual_account and loan_id, with a header row.
+func LoadVirtualAccounts(r io.Reader) (map[string]string, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	accounts := make(map[string]string)
+	for idx, row := range rows {
+		if idx == 0 {
+			continue // header
+		}
+		if len(row) != 2 {
+			return nil, fmt.Errorf("expect 2 columns at line %d, got %d", idx+1, len(row))
+		}
+		accounts[strings.TrimSpace(row[0])] = strings.TrimSpace(row[1])
+	}
+	return accounts, nil
+}
+
+// LoadPosted reads the posted transaction IDs, one per line.
+func LoadPosted(r io.Reader) (map[string]bool, error) {
+	posted := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if id := strings.TrimSpace(scanner.Text()); id != "" {
+			posted[id] = true
+		}
+	}
+	return posted, scanner.Err()
+}
+
+// WritePosted writes the posted transaction IDs, one per line in order.
+func WritePosted(w io.Writer, posted map[string]bool) error {
+	ids := make([]string, 0, len(posted))
+	for id := range posted {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if _, err := fmt.Fprintln(w, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteCSV writes one row per mutation with its reconciliation status.
+func (r *Report) WriteCSV(out io.Writer) error {
+	cw := csv.NewWriter(out)
+	header := []string{"line", "date", "virtual_account", "transaction_id", "reference", "amount", "status", "loan_id", "reason"}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, item := range r.Items {
+		date := ""
+		if !item.Date.IsZero() {
+			date = item.Date.Format("2006-01-02")
+		}
+		err := cw.Write([]string{
+			strconv.Itoa(item.Line),
+			date,
+			item.VirtualAccount,
+			item.TransactionID,
+			item.Reference,
+			strconv.FormatFloat(item.Amount, 'f', 2, 64),
+			item.Status,
+			item.LoanID,
+			item.Reason,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Summary is a one line overview of the report.
+func (r *Report) Summary() string {
+	return fmt.Sprintf("%d matched (%.2f), %d unmatched (%.2f), %d rejected (%.2f)",
+		r.Matched, r.MatchedAmount, r.Unmatched, r.UnmatchedAmount, r.Rejected, r.RejectedAmount)
+}
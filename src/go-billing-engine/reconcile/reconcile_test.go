@@ -0,0 +1,169 @@
+package reconcile
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gobillingengine/engine"
+	"gobillingengine/model"
+	"gobillingengine/portfolio"
+)
+
+func newStore(t *testing.T, loanIDs ...string) portfolio.Store {
+	store := portfolio.NewMemoryStore()
+	for _, loanID := range loanIDs {
+		loan := model.NewLoan(loanID, 50, 5000, 0.1)
+		loan.DisbursementDate = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		require.NoError(t, store.Create(engine.NewBilling(loan)))
+	}
+	return store
+}
+
+func TestParseCSV(t *testing.T) {
+	in := "date,virtual_account,transaction_id,reference,amount\n" +
+		"2024-01-08,8808100000000001,TX1,CICILAN,110\n" +
+		"2024-01-08,8808100000000002,TX2,CICILAN,abc\n" +
+		"2024-01-08,8808100000000003\n"
+
+	mutations, err := ParseCSV(strings.NewReader(in))
+	require.NoError(t, err)
+	require.Len(t, mutations, 3)
+	assert.Equal(t, Mutation{
+		Line:           2,
+		Date:           time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC),
+		VirtualAccount: "8808100000000001",
+		TransactionID:  "TX1",
+		Reference:      "CICILAN",
+		Amount:         110,
+	}, mutations[0])
+	assert.Equal(t, `invalid amount "abc"`, mutations[1].Invalid)
+	assert.Equal(t, "expect 5 columns, got 2", mutations[2].Invalid)
+}
+
+func TestParseFixedWidth(t *testing.T) {
+	line := "20240108" + "8808100000000001" + "000000000011000" + "TX1                 " + "PAY 100"
+	mutations, err := ParseFixedWidth(strings.NewReader(line+"\n\n20240108short\n"), DefaultFixedWidthLayout)
+	require.NoError(t, err)
+	require.Len(t, mutations, 2)
+	assert.Equal(t, Mutation{
+		Line:           1,
+		Date:           time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC),
+		VirtualAccount: "8808100000000001",
+		TransactionID:  "TX1",
+		Reference:      "PAY 100",
+		Amount:         110,
+	}, mutations[0])
+	assert.Equal(t, 3, mutations[1].Line)
+	assert.Equal(t, "line is too short (13 characters)", mutations[1].Invalid)
+}
+
+func TestImport(t *testing.T) {
+	store := newStore(t, "100", "200")
+	importer := &Importer{
+		Store:           store,
+		VirtualAccounts: map[string]string{"VA100": "100"},
+	}
+
+	jan := func(day int) time.Time { return time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC) }
+	report, err := importer.Import([]Mutation{
+		{Line: 1, Date: jan(5), VirtualAccount: "VA100", TransactionID: "TX1", Amount: 110},
+		{Line: 2, Date: jan(8), VirtualAccount: "VA999", TransactionID: "TX2", Reference: "LOAN/200", Amount: 110},
+		{Line: 3, Date: jan(8), VirtualAccount: "VA999", TransactionID: "TX3", Reference: "unknown", Amount: 110},
+		{Line: 4, Date: jan(9), VirtualAccount: "VA100", TransactionID: "TX4", Amount: 50},
+		{Line: 5, Date: jan(9), VirtualAccount: "VA100", TransactionID: "TX1", Amount: 110},
+		{Line: 6, Invalid: "invalid date"},
+		{Line: 7, Date: jan(16), VirtualAccount: "VA100", TransactionID: "TX5", Amount: 220}, // the periods due Jan 15 and Jan 22
+		{Line: 8, Date: jan(16), VirtualAccount: "VA100", TransactionID: "TX6", Amount: 165},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, report.Matched)
+	assert.Equal(t, 440.0, report.MatchedAmount)
+	assert.Equal(t, 1, report.Unmatched)
+	assert.Equal(t, 4, report.Rejected)
+	assert.Equal(t, "200", report.Items[1].LoanID)
+	assert.Equal(t, "payment should be 110.000000 or 0", report.Items[3].Reason)
+	assert.Equal(t, "duplicate transaction TX1", report.Items[4].Reason)
+	assert.Equal(t, "payment should be 110.000000 or 0", report.Items[7].Reason)
+	assert.Equal(t, "3 matched (440.00), 1 unmatched (110.00), 4 rejected (325.00)", report.Summary())
+
+	b, err := store.Get("100")
+	require.NoError(t, err)
+	assert.Equal(t, 5170.0, b.Outstanding)
+	assert.Equal(t, 47, b.RemainingWeeks)
+}
+
+func TestImport_PaidOff(t *testing.T) {
+	store := portfolio.NewMemoryStore()
+	loan := model.NewLoan("100", 3, 1000000, 0.1)
+	loan.Currency = "IDR"
+	loan.DisbursementDate = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, store.Create(engine.NewBilling(loan)))
+	importer := &Importer{Store: store, VirtualAccounts: map[string]string{"VA100": "100"}}
+
+	// the last installment takes the rounding remainder
+	report, err := importer.Import([]Mutation{{Line: 1, VirtualAccount: "VA100", TransactionID: "TX1", Amount: 1100000}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Matched)
+
+	b, err := store.Get("100")
+	require.NoError(t, err)
+	assert.Zero(t, b.Outstanding)
+	assert.Zero(t, b.RemainingWeeks)
+}
+
+func TestImport_Twice(t *testing.T) {
+	store := newStore(t, "100")
+	mutations := []Mutation{
+		{Line: 1, Date: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), VirtualAccount: "VA100", TransactionID: "TX1", Amount: 110},
+		{Line: 2, Date: time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC), VirtualAccount: "VA999", TransactionID: "TX2", Amount: 110},
+	}
+	importer := &Importer{Store: store, VirtualAccounts: map[string]string{"VA100": "100"}}
+	_, err := importer.Import(mutations)
+	require.NoError(t, err)
+
+	// the posted transactions are kept across runs, the unmatched one can be posted once the account is known
+	var buf bytes.Buffer
+	require.NoError(t, WritePosted(&buf, importer.Posted))
+	assert.Equal(t, "TX1\n", buf.String())
+	posted, err := LoadPosted(&buf)
+	require.NoError(t, err)
+	importer = &Importer{Store: store, VirtualAccounts: map[string]string{"VA100": "100", "VA999": "100"}, Posted: posted}
+	report, err := importer.Import(mutations)
+	require.NoError(t, err)
+	assert.Equal(t, "transaction TX1 was already posted", report.Items[0].Reason)
+	assert.Equal(t, StatusMatched, report.Items[1].Status)
+	assert.Equal(t, map[string]bool{"TX1": true, "TX2": true}, importer.Posted)
+
+	b, err := store.Get("100")
+	require.NoError(t, err)
+	assert.Equal(t, 2, b.PaymentRecord.Size())
+}
+
+func TestLoadVirtualAccounts(t *testing.T) {
+	accounts, err := LoadVirtualAccounts(strings.NewReader("virtual_account,loan_id\nVA100,100\n"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"VA100": "100"}, accounts)
+
+	_, err = LoadVirtualAccounts(strings.NewReader("virtual_account,loan_id\nVA100\n"))
+	assert.Error(t, err)
+}
+
+func TestReportWriteCSV(t *testing.T) {
+	report := &Report{}
+	report.add(Item{
+		Mutation: Mutation{Line: 2, Date: time.Date(2024, 1, 8, 0, 0, 0, 0, time.UTC), VirtualAccount: "VA100", TransactionID: "TX1", Amount: 110},
+		LoanID:   "100",
+		Status:   StatusMatched,
+	})
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteCSV(&buf))
+	assert.Equal(t, "line,date,virtual_account,transaction_id,reference,amount,status,loan_id,reason\n"+
+		"2,2024-01-08,VA100,TX1,,110.00,matched,100,\n", buf.String())
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"gobillingengine/portfolio"
+	"gobillingengine/recovery"
+)
+
+func newRecoveriesCmd() *cobra.Command {
+	recoveriesCmd := &cobra.Command{
+		Use:   "recoveries",
+		Short: "Report the write-offs of a persisted portfolio with their settlements and recoveries",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, _ := cmd.Flags().GetString("portfolio")
+
+			to, err := getDate(cmd, "to")
+			if err != nil {
+				return err
+			}
+			from := to.AddDate(0, -1, 0)
+			if value, _ := cmd.Flags().GetString("from"); value != "" {
+				if from, err = getDate(cmd, "from"); err != nil {
+					return err
+				}
+			}
+
+			store, err := portfolio.OpenFileStore(path)
+			if err != nil {
+				return err
+			}
+			billings, err := store.List()
+			if err != nil {
+				return err
+			}
+			report, err := recovery.Generate(billings, from, to)
+			if err != nil {
+				return err
+			}
+
+			out, closeOut, err := getOutput(cmd)
+			if err != nil {
+				return err
+			}
+			err = report.WriteJSON(out)
+			if errClose := closeOut(); err == nil {
+				err = errClose
+			}
+			return err
+		},
+	}
+	recoveriesCmd.Flags().String("portfolio", "portfolio.json", "The persisted portfolio file")
+	recoveriesCmd.Flags().String("from", "", "The start of the period (YYYY-MM-DD), a month before --to by default")
+	recoveriesCmd.Flags().String("to", "", "The end of the period (YYYY-MM-DD), today by default")
+	recoveriesCmd.Flags().StringP("output", "o", "", "Write the report to this file instead of stdout")
+	return recoveriesCmd
+}
@@ -0,0 +1,130 @@
+// Package recovery reports what was recovered on the written off loans of a portfolio through settlements.
+package recovery
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"time"
+
+	"gobillingengine/engine"
+)
+
+// Settlement is the latest settlement agreed on a written off loan.
+type Settlement struct {
+	Date     time.Time `json:"date"`
+	Amount   float64   `json:"amount"`
+	Discount float64   `json:"discount"` // Discount forgiven if the settlement is honoured
+	Paid     float64   `json:"paid"`
+	Deadline time.Time `json:"deadline"`
+	Status   string    `json:"status"`
+}
+
+// Line is the recovery of a written off loan.
+type Line struct {
+	LoanID          string      `json:"loan_id"`
+	WriteOffDate    time.Time   `json:"write_off_date"`
+	WrittenOff      float64     `json:"written_off"`
+	Recovered       float64     `json:"recovered"`         // Recovered over the period
+	RecoveredToDate float64     `json:"recovered_to_date"` // RecoveredToDate since the write-off, up to the end of the period
+	Settlement      *Settlement `json:"settlement,omitempty"`
+}
+
+// Totals sums the lines of the report. The settlements are counted by their status at the end of the period.
+type Totals struct {
+	Loans              int     `json:"loans"`
+	WrittenOff         float64 `json:"written_off"`
+	WrittenOffInPeriod float64 `json:"written_off_in_period"`
+	Recovered          float64 `json:"recovered"`
+	RecoveredToDate    float64 `json:"recovered_to_date"`
+	RecoveryRate       float64 `json:"recovery_rate"` // RecoveryRate of the written off amount recovered to date
+	Forgiven           float64 `json:"forgiven"`      // Forgiven by the honoured settlements
+	Open               int     `json:"open"`
+	Honoured           int     `json:"honoured"`
+	Broken             int     `json:"broken"`
+}
+
+type Report struct {
+	From   time.Time `json:"from"`
+	To     time.Time `json:"to"`
+	Lines  []Line    `json:"lines"`
+	Totals Totals    `json:"totals"`
+}
+
+// Generate reports the loans written off by to with their recoveries between from and to, both inclusive, and since
+// the write-off. Settlements agreed after to are left out.
+func Generate(billings []*engine.Billing, from, to time.Time) (*Report, error) {
+	if to.Before(from) {
+		return nil, errors.New("report end date must not be before its start date")
+	}
+
+	report := &Report{From: from, To: to, Lines: []Line{}}
+	totals := &report.Totals
+	for _, b := range billings {
+		if !b.IsWrittenOff() || b.Closure.Date.After(to) {
+			continue
+		}
+		line := Line{
+			LoanID:       b.Loan.LoanID,
+			WriteOffDate: b.Closure.Date,
+			WrittenOff:   b.Closure.Outstanding,
+		}
+		for _, s := range b.Settlements {
+			if s.Date.After(to) {
+				break
+			}
+			var paid float64
+			for _, p := range s.Payments {
+				if p.Date.After(to) {
+					continue
+				}
+				paid += p.Amount
+				if !p.Date.Before(from) {
+					line.Recovered += p.Amount
+				}
+			}
+			line.RecoveredToDate += paid
+			line.Settlement = &Settlement{
+				Date:     s.Date,
+				Amount:   s.Amount,
+				Discount: s.Discount(),
+				Paid:     paid,
+				Deadline: s.Deadline,
+				Status:   s.StatusAsOf(to),
+			}
+			switch line.Settlement.Status {
+			case engine.SettlementOpen:
+				totals.Open++
+			case engine.SettlementHonoured:
+				totals.Honoured++
+				totals.Forgiven += s.Discount()
+			case engine.SettlementBroken:
+				totals.Broken++
+			}
+		}
+
+		totals.Loans++
+		totals.WrittenOff += line.WrittenOff
+		if !line.WriteOffDate.Before(from) {
+			totals.WrittenOffInPeriod += line.WrittenOff
+		}
+		totals.Recovered += line.Recovered
+		totals.RecoveredToDate += line.RecoveredToDate
+		report.Lines = append(report.Lines, line)
+	}
+	if totals.WrittenOff > 0 {
+		totals.RecoveryRate = totals.RecoveredToDate / totals.WrittenOff
+	}
+	sort.Slice(report.Lines, func(i, j int) bool {
+		return report.Lines[i].LoanID < report.Lines[j].LoanID
+	})
+	return report, nil
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
@@ -0,0 +1,89 @@
+package recovery
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gobillingengine/engine"
+	"gobillingengine/model"
+)
+
+func date(month time.Month, day int) time.Time {
+	return time.Date(2024, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func newWrittenOff(t *testing.T, loanID string, writtenOff time.Time) *engine.Billing {
+	loan := model.NewLoan(loanID, 50, 5000, 0.1)
+	loan.DisbursementDate = date(1, 1)
+	b := engine.NewBilling(loan)
+	require.NoError(t, b.WriteOff(writtenOff))
+	return b
+}
+
+func TestGenerate(t *testing.T) {
+	honoured := newWrittenOff(t, "1001", date(2, 1))
+	_, err := honoured.Settle(2750, 2, date(2, 5), date(3, 8))
+	require.NoError(t, err)
+	require.NoError(t, honoured.PaySettlement(1375, date(2, 12)))
+	require.NoError(t, honoured.PaySettlement(1375, date(3, 4)))
+
+	broken := newWrittenOff(t, "1002", date(3, 1))
+	_, err = broken.Settle(3000, 3, date(3, 4), date(3, 31))
+	require.NoError(t, err)
+	require.NoError(t, broken.PaySettlement(1000, date(3, 11)))
+	require.NoError(t, broken.BreakSettlement(date(4, 1)))
+
+	notSettled := newWrittenOff(t, "1003", date(3, 15))
+	later := newWrittenOff(t, "1004", date(4, 15))
+	loan := model.NewLoan("1005", 50, 5000, 0.1)
+	loan.DisbursementDate = date(1, 1)
+	active := engine.NewBilling(loan)
+
+	report, err := Generate([]*engine.Billing{later, notSettled, broken, honoured, active}, date(3, 1), date(3, 31))
+	require.NoError(t, err)
+	require.Len(t, report.Lines, 3)
+
+	assert.Equal(t, Line{
+		LoanID:          "1001",
+		WriteOffDate:    date(2, 1),
+		WrittenOff:      5500,
+		Recovered:       1375,
+		RecoveredToDate: 2750,
+		Settlement: &Settlement{
+			Date:     date(2, 5),
+			Amount:   2750,
+			Discount: 2750,
+			Paid:     2750,
+			Deadline: date(3, 8),
+			Status:   engine.SettlementHonoured,
+		},
+	}, report.Lines[0])
+	// the settlement is broken after the end of the period
+	assert.Equal(t, engine.SettlementOpen, report.Lines[1].Settlement.Status)
+	assert.Nil(t, report.Lines[2].Settlement)
+
+	assert.Equal(t, Totals{
+		Loans:              3,
+		WrittenOff:         16500,
+		WrittenOffInPeriod: 11000,
+		Recovered:          2375,
+		RecoveredToDate:    3750,
+		RecoveryRate:       3750.0 / 16500,
+		Forgiven:           2750,
+		Open:               1,
+		Honoured:           1,
+	}, report.Totals)
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteJSON(&buf))
+	assert.Contains(t, buf.String(), `"recovered_to_date": 3750`)
+}
+
+func TestGenerate_InvalidPeriod(t *testing.T) {
+	_, err := Generate(nil, date(3, 31), date(3, 1))
+	assert.EqualError(t, err, "report end date must not be before its start date")
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"gobillingengine/engine"
+	"gobillingengine/model"
+	"gobillingengine/portfolio"
+	"gobillingengine/product"
+)
+
+func newRefinanceCmd() *cobra.Command {
+	refinanceCmd := &cobra.Command{
+		Use:   "refinance <loan_id>",
+		Short: "Pay off a loan of the portfolio with a new, bigger loan and net the payoff out of its disbursement",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, _ := cmd.Flags().GetString("portfolio")
+			newLoanID, _ := cmd.Flags().GetString("new-loan")
+			amount, _ := cmd.Flags().GetFloat64("amount")
+			weeks, _ := cmd.Flags().GetInt("weeks")
+			rate, _ := cmd.Flags().GetFloat64("rate")
+			productCode, _ := cmd.Flags().GetString("product")
+			catalogPath, _ := cmd.Flags().GetString("catalog")
+
+			date, err := getDate(cmd, "date")
+			if err != nil {
+				return err
+			}
+			if newLoanID == "" {
+				return errors.New("--new-loan is required")
+			}
+
+			var loan *model.Loan
+			if productCode == "" {
+				if amount <= 0 || weeks <= 0 {
+					return errors.New("--amount and --weeks must be positive")
+				}
+				loan = model.NewLoan(newLoanID, weeks, amount, rate)
+				loan.DisbursementDate = date
+			} else {
+				catalog, err := product.Load(catalogPath)
+				if err != nil {
+					return err
+				}
+				p, err := catalog.Get(productCode)
+				if err != nil {
+					return err
+				}
+				if loan, err = p.NewLoan(newLoanID, amount, weeks, date); err != nil {
+					return err
+				}
+			}
+
+			store, err := portfolio.OpenFileStore(path)
+			if err != nil {
+				return err
+			}
+			old, err := store.Get(args[0])
+			if err != nil {
+				return fmt.Errorf("loanID:%s - %v", args[0], err)
+			}
+			if _, err := store.Get(newLoanID); err == nil {
+				return fmt.Errorf("loanID:%s - %v", newLoanID, portfolio.ErrAlreadyExists)
+			}
+
+			refinanced, err := engine.Refinance(old, loan, date)
+			if err != nil {
+				return fmt.Errorf("cannot refinance loanID:%s with err: %v", args[0], err)
+			}
+			if err := store.SaveAll([]*engine.Billing{old, refinanced}); err != nil {
+				return err
+			}
+
+			payoff := old.Closure.Payoff
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "loan %s paid off on %s: %.2f (principal %.2f, interest %.2f, fees %.2f, penalty %.2f)\n",
+				old.Loan.LoanID, date.Format(dateLayout), payoff.Amount, payoff.Principal, payoff.Interest, payoff.Fees, payoff.Penalty)
+			fmt.Fprintf(out, "loan %s: %.2f over %d weeks, %d x %.2f, net disbursed %.2f\n",
+				refinanced.Loan.LoanID, refinanced.Loan.Amount, refinanced.Loan.Weeks, refinanced.Loan.Weeks,
+				refinanced.PayableAmount, refinanced.Loan.NetDisbursedAmount())
+			return nil
+		},
+	}
+	refinanceCmd.Flags().String("portfolio", "portfolio.json", "The persisted portfolio file")
+	refinanceCmd.Flags().String("new-loan", "", "The loan ID of the new loan")
+	refinanceCmd.Flags().Float64("amount", 0, "The principal amount of the new loan")
+	refinanceCmd.Flags().Int("weeks", 0, "The tenor of the new loan in weeks")
+	refinanceCmd.Flags().Float64("rate", 0, "The flat interest rate of the new loan over its term, 0.1 for 10%")
+	refinanceCmd.Flags().String("product", "", "Create the new loan from this product of the catalog instead of --rate")
+	refinanceCmd.Flags().String("catalog", "examples/products.yaml", "The YAML product catalog")
+	refinanceCmd.Flags().String("date", "", "The date of the refinancing (YYYY-MM-DD), today by default")
+	return refinanceCmd
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"gobillingengine/portfolio"
+	"gobillingengine/reminder"
+)
+
+func newRemindCmd() *cobra.Command {
+	remindCmd := &cobra.Command{
+		Use:   "remind",
+		Short: "Send the repayment reminders due on a date",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, _ := cmd.Flags().GetString("portfolio")
+			senderName, _ := cmd.Flags().GetString("sender")
+			file, _ := cmd.Flags().GetString("file")
+
+			asOf := today()
+
+			var sender reminder.Sender
+			switch senderName {
+			case "console":
+				sender = &reminder.ConsoleSender{W: cmd.OutOrStdout()}
+			case "file":
+				sender = &reminder.FileSender{Path: file}
+			default:
+				return fmt.Errorf("unknown sender %q, expected console or file", senderName)
+			}
+
+			store, err := portfolio.OpenFileStore(path)
+			if err != nil {
+				return err
+			}
+			billings, err := store.List()
+			if err != nil {
+				return err
+			}
+
+			planner, err := reminder.NewPlanner(reminder.DefaultRules)
+			if err != nil {
+				return err
+			}
+			var jobs []reminder.Job
+			for _, b := range billings {
+				planned, err := planner.Plan(b, asOf, asOf)
+				if err != nil {
+					return err
+				}
+				jobs = append(jobs, planned...)
+			}
+
+			result, err := reminder.Send(cmd.Context(), jobs, store, sender)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.ErrOrStderr(), "%d reminder(s) sent, %d skipped, %d failed\n", result.Sent, result.Skipped, result.Failed)
+			return nil
+		},
+	}
+	remindCmd.Flags().String("portfolio", "portfolio.json", "The persisted portfolio file")
+	remindCmd.Flags().String("sender", "console", "Where to send the reminders, console or file")
+	remindCmd.Flags().String("file", "reminders.jsonl", "The file of the file sender")
+	return remindCmd
+}
@@ -0,0 +1,125 @@
+// Package reminder plans and sends repayment reminders around the installment due dates.
+package reminder
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"text/template"
+	"time"
+
+	"gobillingengine/engine"
+)
+
+// Rule sends a reminder Offset days from the due date of every unpaid installment.
+type Rule struct {
+	Name     string
+	Offset   int    // Offset in days, negative before the due date
+	Template string // Template of the message, executed with the Job
+}
+
+// DefaultRules remind two days before, on, and one day after the due date.
+var DefaultRules = []Rule{
+	{Name: "H-2", Offset: -2, Template: "Loan {{.LoanID}}: your installment of {{printf \"%.2f\" .Amount}} for week {{.Week}} is due on {{.DueDate.Format \"2006-01-02\"}}."},
+	{Name: "H-0", Offset: 0, Template: "Loan {{.LoanID}}: your installment of {{printf \"%.2f\" .Amount}} for week {{.Week}} is due today."},
+	{Name: "H+1", Offset: 1, Template: "Loan {{.LoanID}}: your installment of {{printf \"%.2f\" .Amount}} for week {{.Week}} was due yesterday, please pay it as soon as possible."},
+}
+
+// Job is a reminder to send.
+type Job struct {
+	LoanID  string    `json:"loan_id"`
+	Rule    string    `json:"rule"`
+	Period  int       `json:"period"` // Period of the billing the installment is due in
+	Week    int       `json:"week"`
+	Amount  float64   `json:"amount"`
+	DueDate time.Time `json:"due_date"`
+	SendAt  time.Time `json:"send_at"`
+	Message string    `json:"message"`
+}
+
+// Planner turns the upcoming due dates of billings into reminder jobs.
+type Planner struct {
+	rules     []Rule
+	templates []*template.Template
+}
+
+func NewPlanner(rules []Rule) (*Planner, error) {
+	p := &Planner{rules: rules}
+	for _, rule := range rules {
+		tmpl, err := template.New(rule.Name).Parse(rule.Template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template of rule %s with err: %v", rule.Name, err)
+		}
+		p.templates = append(p.templates, tmpl)
+	}
+	return p, nil
+}
+
+// Plan returns the reminders of the billing to send between from and to, both inclusive, in send order.
+// Installments already paid get no reminder.
+func (p *Planner) Plan(b *engine.Billing, from, to time.Time) ([]Job, error) {
+	var jobs []Job
+	if b.GetOutstanding() <= 0 {
+		return jobs, nil
+	}
+
+	// the earliest reminder of a period is sent at its due date plus the smallest offset
+	minOffset, maxOffset := 0, 0
+	for _, rule := range p.rules {
+		minOffset = min(minOffset, rule.Offset)
+		maxOffset = max(maxOffset, rule.Offset)
+	}
+
+	// unpaid periods are the trailing misses and the periods not recorded yet, up to the remaining weeks
+	payments := b.Payments()
+	first := len(payments) + 1
+	for first > 1 && payments[first-2].Amount == 0 {
+		first--
+	}
+	last := len(payments) + b.RemainingWeeks
+	week := (b.Loan.Weeks - b.RemainingWeeks) + 1
+
+	for period := first; period <= last; period++ {
+		dueDate := b.DueDate(period)
+		if dueDate.AddDate(0, 0, minOffset).After(to) {
+			break
+		}
+		if dueDate.AddDate(0, 0, maxOffset).Before(from) {
+			continue
+		}
+		for i, rule := range p.rules {
+			sendAt := dueDate.AddDate(0, 0, rule.Offset)
+			if sendAt.Before(from) || sendAt.After(to) {
+				continue
+			}
+			job := Job{
+				LoanID:  b.Loan.LoanID,
+				Rule:    rule.Name,
+				Period:  period,
+				Week:    week + max(0, period-len(payments)-1),
+				Amount:  b.PayableAmountAt(dueDate),
+				DueDate: dueDate,
+				SendAt:  sendAt,
+			}
+			var msg bytes.Buffer
+			if err := p.templates[i].Execute(&msg, job); err != nil {
+				return nil, err
+			}
+			job.Message = msg.String()
+			jobs = append(jobs, job)
+		}
+	}
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].SendAt.Before(jobs[j].SendAt)
+	})
+	return jobs, nil
+}
+
+// IsPaid reports whether the installment a job reminds of has been paid since it was planned.
+func IsPaid(b *engine.Billing, job Job) bool {
+	if b.GetOutstanding() <= 0 {
+		return true
+	}
+	payments := b.Payments()
+	return job.Period <= len(payments) && payments[job.Period-1].Amount > 0
+}
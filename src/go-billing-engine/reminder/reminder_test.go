@@ -0,0 +1,133 @@
+package reminder
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gobillingengine/engine/enginetest"
+	"gobillingengine/portfolio"
+)
+
+func date(day int) time.Time {
+	return time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestPlan(t *testing.T) {
+	planner, err := NewPlanner(DefaultRules)
+	require.NoError(t, err)
+	billing := enginetest.NewBilling(t, enginetest.NewLoan("1001")) // due on Jan 8, 15, 22...
+
+	jobs, err := planner.Plan(billing, date(6), date(13))
+	require.NoError(t, err)
+
+	var rules []string
+	for _, job := range jobs {
+		rules = append(rules, job.Rule+" "+job.SendAt.Format("01-02"))
+	}
+	assert.Equal(t, []string{"H-2 01-06", "H-0 01-08", "H+1 01-09", "H-2 01-13"}, rules)
+	assert.Equal(t, "Loan 1001: your installment of 110.00 for week 1 is due on 2024-01-08.", jobs[0].Message)
+	assert.Equal(t, 2, jobs[3].Week)
+
+	// once week 1 is paid, its reminders are not planned anymore
+	require.NoError(t, billing.MakePayment(billing.PayableAmount))
+	jobs, err = planner.Plan(billing, date(6), date(13))
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "H-2", jobs[0].Rule)
+	assert.Equal(t, date(15), jobs[0].DueDate)
+
+	// a missed installment is still reminded of
+	require.NoError(t, billing.MakePayment(0))
+	jobs, err = planner.Plan(billing, date(16), date(16))
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "H+1", jobs[0].Rule)
+	assert.Equal(t, 2, jobs[0].Week)
+}
+
+func TestPlan_Repriced(t *testing.T) {
+	planner, err := NewPlanner(DefaultRules)
+	require.NoError(t, err)
+	billing := enginetest.NewBilling(t, enginetest.NewLoan("1001"))
+	billing.Loan.VariableRate = true
+	require.NoError(t, billing.MakePayment(0))
+	require.NoError(t, billing.ChangeRate(0.2, date(15)))
+
+	// the missed installment keeps its rate, the next one is repriced
+	jobs, err := planner.Plan(billing, date(6), date(16))
+	require.NoError(t, err)
+	amounts := make(map[int]float64)
+	for _, job := range jobs {
+		amounts[job.Period] = job.Amount
+	}
+	assert.Equal(t, map[int]float64{1: 110, 2: 120}, amounts)
+}
+
+func TestNewPlanner_InvalidTemplate(t *testing.T) {
+	_, err := NewPlanner([]Rule{{Name: "H-0", Template: "{{.Unclosed"}})
+	assert.Error(t, err)
+}
+
+type failingSender struct{}
+
+func (failingSender) Send(ctx context.Context, job Job) error {
+	return assert.AnError
+}
+
+func TestSend(t *testing.T) {
+	planner, err := NewPlanner(DefaultRules)
+	require.NoError(t, err)
+	billing := enginetest.NewBilling(t, enginetest.NewLoan("1001"))
+	store := portfolio.NewMemoryStore()
+	require.NoError(t, store.Create(billing))
+
+	jobs, err := planner.Plan(billing, date(6), date(9))
+	require.NoError(t, err)
+	require.Len(t, jobs, 3)
+
+	var out bytes.Buffer
+	result, err := Send(context.Background(), jobs[:1], store, &ConsoleSender{W: &out})
+	require.NoError(t, err)
+	assert.Equal(t, Result{Sent: 1}, result)
+	assert.Equal(t, "[2024-01-06] H-2 1001: "+jobs[0].Message+"\n", out.String())
+
+	// paying before the due date stops the remaining reminders of the week
+	require.NoError(t, billing.MakePayment(billing.PayableAmount))
+	path := filepath.Join(t.TempDir(), "reminders.jsonl")
+	result, err = Send(context.Background(), jobs[1:], store, &FileSender{Path: path})
+	require.NoError(t, err)
+	assert.Equal(t, Result{Skipped: 2}, result)
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+
+	jobs, err = planner.Plan(billing, date(13), date(16))
+	require.NoError(t, err)
+	result, err = Send(context.Background(), jobs, store, &FileSender{Path: path})
+	require.NoError(t, err)
+	assert.Equal(t, Result{Sent: 3}, result)
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	var sent []Job
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var job Job
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &job))
+		sent = append(sent, job)
+	}
+	assert.Equal(t, jobs, sent)
+
+	result, err = Send(context.Background(), jobs, store, failingSender{})
+	require.NoError(t, err)
+	assert.Equal(t, Result{Failed: 3}, result)
+}
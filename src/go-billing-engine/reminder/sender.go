@@ -0,0 +1,78 @@
+package reminder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"gobillingengine/portfolio"
+)
+
+// Sender delivers a reminder to the borrower, e.g. by SMS or push notification.
+type Sender interface {
+	Send(ctx context.Context, job Job) error
+}
+
+// ConsoleSender prints the reminders, for local use.
+type ConsoleSender struct {
+	W io.Writer
+}
+
+func (s *ConsoleSender) Send(ctx context.Context, job Job) error {
+	_, err := fmt.Fprintf(s.W, "[%s] %s %s: %s\n", job.SendAt.Format("2006-01-02"), job.Rule, job.LoanID, job.Message)
+	return err
+}
+
+// FileSender appends the reminders to a file as JSON lines, for local use.
+type FileSender struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+func (s *FileSender) Send(ctx context.Context, job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(job); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Result counts the outcome of sending jobs.
+type Result struct {
+	Sent    int
+	Skipped int // Skipped jobs whose installment was paid in the meantime
+	Failed  int
+}
+
+// Send delivers the jobs through the sender, skipping the installments paid since the jobs were planned.
+// It stops at the first error of the store and keeps going on sender errors, which are counted as failed.
+func Send(ctx context.Context, jobs []Job, store portfolio.Store, sender Sender) (Result, error) {
+	var result Result
+	for _, job := range jobs {
+		b, err := store.Get(job.LoanID)
+		if err != nil {
+			return result, fmt.Errorf("loanID:%s - %v", job.LoanID, err)
+		}
+		if IsPaid(b, job) {
+			result.Skipped++
+			continue
+		}
+		if err := sender.Send(ctx, job); err != nil {
+			result.Failed++
+			continue
+		}
+		result.Sent++
+	}
+	return result, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"gobillingengine/portfolio"
+	"gobillingengine/repl"
+)
+
+func newReplCmd() *cobra.Command {
+	replCmd := &cobra.Command{
+		Use:   "repl [<loan_id>]",
+		Short: "Open an interactive shell to pay, miss and undo the installments of a loan",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, _ := cmd.Flags().GetString("portfolio")
+			transcript, _ := cmd.Flags().GetString("transcript")
+
+			store, err := portfolio.OpenFileStore(path)
+			if err != nil {
+				return err
+			}
+			session := repl.NewSession(store)
+			session.Clock = appClock
+			out := cmd.OutOrStdout()
+			if len(args) == 1 {
+				output, err := session.Execute("load " + args[0])
+				if err != nil {
+					return err
+				}
+				fmt.Fprint(out, output)
+			}
+
+			// line editing, history and completion need a terminal, piped input is read as plain lines
+			var reader repl.LineReader = repl.NewLineReader(cmd.InOrStdin(), out)
+			if in, ok := cmd.InOrStdin().(*os.File); ok {
+				if terminal, err := repl.NewTerminal(in, out, session); err == nil {
+					defer terminal.Close()
+					reader = terminal
+				}
+			}
+			if err := repl.Run(session, reader, out); err != nil {
+				return err
+			}
+
+			if transcript != "" {
+				if _, err := session.Execute("export " + transcript); err != nil {
+					return err
+				}
+			}
+			if session.Unsaved() {
+				fmt.Fprintf(out, "unsaved changes of loan %s dropped\n", session.Billing().Loan.LoanID)
+			}
+			return nil
+		},
+	}
+	replCmd.Flags().String("portfolio", "portfolio.json", "The persisted portfolio file")
+	replCmd.Flags().String("transcript", "", "Write the transcript of the session to this file when it ends")
+	return replCmd
+}
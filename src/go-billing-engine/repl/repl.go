@@ -0,0 +1,63 @@
+package repl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// LineReader reads the lines typed in the shell.
+type LineReader interface {
+	ReadLine(prompt string) (string, error)
+}
+
+// Run reads and executes lines until the session is quit or the input ends.
+func Run(s *Session, lr LineReader, out io.Writer) error {
+	for {
+		prompt := "> "
+		if b := s.Billing(); b != nil {
+			prompt = b.Loan.LoanID + "> "
+		}
+		line, err := lr.ReadLine(prompt)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		output, err := s.Execute(line)
+		if errors.Is(err, ErrQuit) {
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+			continue
+		}
+		io.WriteString(out, output)
+	}
+}
+
+// plainReader reads lines from input that is not a terminal, e.g. a script piped in.
+type plainReader struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+// NewLineReader returns a LineReader of plain lines, writing the prompt to out.
+func NewLineReader(in io.Reader, out io.Writer) LineReader {
+	return &plainReader{scanner: bufio.NewScanner(in), out: out}
+}
+
+func (r *plainReader) ReadLine(prompt string) (string, error) {
+	io.WriteString(r.out, prompt)
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return strings.TrimRight(r.scanner.Text(), "\r"), nil
+}
@@ -0,0 +1,347 @@
+// Package repl is an interactive shell over a billing of the portfolio, to walk a borrower through payments
+// during a support call.
+package repl
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gobillingengine/clock"
+	"gobillingengine/engine"
+	"gobillingengine/portfolio"
+)
+
+// ErrQuit is returned by Execute when the session is over.
+var ErrQuit = errors.New("quit")
+
+type command struct {
+	name  string
+	args  string
+	usage string
+	run   func(s *Session, args []string) (string, error)
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"load", "<loan_id>", "load a loan of the portfolio", (*Session).load},
+		{"pay", "<amount>", "pay the installment due, the amount must be the payable amount", (*Session).pay},
+		{"miss", "", "record a missed installment", (*Session).miss},
+		{"schedule", "", "show the installments left with their due dates", (*Session).schedule},
+		{"outstanding", "", "show the outstanding balance and the delinquency status", (*Session).outstanding},
+		{"undo", "", "reverse the last payment or miss of the session", (*Session).undo},
+		{"save", "", "save the loan in the portfolio", (*Session).save},
+		{"history", "", "list the commands of the session", (*Session).history},
+		{"export", "<file>", "write the transcript of the session to a file", (*Session).export},
+		{"help", "", "list the commands", (*Session).help},
+		{"quit", "", "end the session", (*Session).quit},
+	}
+}
+
+// Session is the state of an interactive shell. The changes to the loaded billing stay in memory until saved.
+type Session struct {
+	Store portfolio.Store
+	Clock clock.Clock // Clock dates the transcript, the system clock if nil
+
+	billing    *engine.Billing
+	undoable   int // undoable payments and misses recorded on the billing in this session
+	unsaved    bool
+	warned     bool     // warned that quitting drops the unsaved changes
+	lines      []string // lines is the command history
+	transcript strings.Builder
+}
+
+// NewSession returns a session over the billings of the store.
+func NewSession(store portfolio.Store) *Session {
+	return &Session{Store: store}
+}
+
+// Billing returns the loaded billing, nil if none is.
+func (s *Session) Billing() *engine.Billing {
+	return s.billing
+}
+
+// History returns the lines executed in the session, oldest first.
+func (s *Session) History() []string {
+	return append([]string(nil), s.lines...)
+}
+
+// Transcript returns the lines executed in the session with their output.
+func (s *Session) Transcript() string {
+	return s.transcript.String()
+}
+
+// Unsaved checks if the loaded billing changed since it was loaded or saved.
+func (s *Session) Unsaved() bool {
+	return s.unsaved
+}
+
+// Execute runs a line and returns its output. ErrQuit ends the session, any other error is the failure of
+// the command and leaves the session going.
+func (s *Session) Execute(line string) (string, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	s.lines = append(s.lines, strings.TrimSpace(line))
+
+	out, err := s.run(fields[0], fields[1:])
+	if fields[0] != "quit" && fields[0] != "exit" {
+		s.warned = false
+	}
+	fmt.Fprintf(&s.transcript, "> %s\n", strings.TrimSpace(line))
+	switch {
+	case err != nil && !errors.Is(err, ErrQuit):
+		fmt.Fprintf(&s.transcript, "error: %v\n", err)
+	case out != "":
+		s.transcript.WriteString(out)
+	}
+	return out, err
+}
+
+func (s *Session) run(name string, args []string) (string, error) {
+	if name == "exit" {
+		name = "quit"
+	}
+	for _, c := range commands {
+		if c.name == name {
+			return c.run(s, args)
+		}
+	}
+	return "", fmt.Errorf("unknown command %q, type help for the list", name)
+}
+
+// Complete returns the completions of the line: the commands starting with its first word, or the loan IDs of
+// the portfolio after load.
+func (s *Session) Complete(line string) []string {
+	fields := strings.Fields(line)
+	trailingSpace := strings.HasSuffix(line, " ")
+
+	var candidates []string
+	switch {
+	case len(fields) == 0 || (len(fields) == 1 && !trailingSpace):
+		prefix := ""
+		if len(fields) == 1 {
+			prefix = fields[0]
+		}
+		for _, c := range commands {
+			if strings.HasPrefix(c.name, prefix) {
+				candidates = append(candidates, c.name)
+			}
+		}
+	case fields[0] == "load" && (len(fields) == 1 || (len(fields) == 2 && !trailingSpace)):
+		prefix := ""
+		if len(fields) == 2 {
+			prefix = fields[1]
+		}
+		billings, err := s.Store.List()
+		if err != nil {
+			return nil
+		}
+		for _, b := range billings {
+			if strings.HasPrefix(b.Loan.LoanID, prefix) {
+				candidates = append(candidates, "load "+b.Loan.LoanID)
+			}
+		}
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+func (s *Session) loaded() (*engine.Billing, error) {
+	if s.billing == nil {
+		return nil, errors.New("no loan loaded, use load <loan_id>")
+	}
+	return s.billing, nil
+}
+
+func (s *Session) load(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", errors.New("usage: load <loan_id>")
+	}
+	if s.unsaved {
+		return "", fmt.Errorf("loanID:%s has unsaved changes, save them first", s.billing.Loan.LoanID)
+	}
+	b, err := s.Store.Get(args[0])
+	if err != nil {
+		return "", fmt.Errorf("loanID:%s - %v", args[0], err)
+	}
+	s.billing = b
+	s.undoable = 0
+	out, _ := s.outstanding(nil)
+	return fmt.Sprintf("loan %s loaded: %d weeks of %.2f\n", b.Loan.LoanID, b.Loan.Weeks, b.PayableAmount) + out, nil
+}
+
+func (s *Session) pay(args []string) (string, error) {
+	b, err := s.loaded()
+	if err != nil {
+		return "", err
+	}
+	if len(args) != 1 {
+		return "", errors.New("usage: pay <amount>")
+	}
+	amount, err := strconv.ParseFloat(args[0], 64)
+	if err != nil || amount <= 0 {
+		return "", fmt.Errorf("invalid amount %q", args[0])
+	}
+	// the amount is typed with at most cents, the due amount may have more decimals
+	if due := b.DueAmount(); math.Abs(amount-due) < 0.005 {
+		amount = due
+	}
+	return s.record(b, amount)
+}
+
+func (s *Session) miss(args []string) (string, error) {
+	b, err := s.loaded()
+	if err != nil {
+		return "", err
+	}
+	return s.record(b, 0)
+}
+
+func (s *Session) record(b *engine.Billing, amount float64) (string, error) {
+	if err := b.MakePayment(amount); err != nil {
+		return "", err
+	}
+	s.undoable++
+	s.unsaved = true
+
+	payments := b.Payments()
+	p := payments[len(payments)-1]
+	action := fmt.Sprintf("paid %.2f", p.Amount)
+	if p.Amount == 0 {
+		action = "missed"
+	}
+	out, _ := s.outstanding(nil)
+	return fmt.Sprintf("week %d due %s %s\n", p.Week, p.Date.Format("2006-01-02"), action) + out, nil
+}
+
+func (s *Session) undo(args []string) (string, error) {
+	b, err := s.loaded()
+	if err != nil {
+		return "", err
+	}
+	if s.undoable == 0 {
+		return "", errors.New("nothing to undo in this session")
+	}
+	p, err := b.ReversePayment()
+	if err != nil {
+		return "", err
+	}
+	s.undoable--
+	s.unsaved = true
+
+	action := fmt.Sprintf("payment of %.2f", p.Amount)
+	if p.Amount == 0 {
+		action = "miss"
+	}
+	out, _ := s.outstanding(nil)
+	return fmt.Sprintf("reversed the %s of week %d due %s\n", action, p.Week, p.Date.Format("2006-01-02")) + out, nil
+}
+
+func (s *Session) schedule(args []string) (string, error) {
+	b, err := s.loaded()
+	if err != nil {
+		return "", err
+	}
+	if b.IsClosed() || b.RemainingWeeks == 0 {
+		return "nothing left to pay\n", nil
+	}
+	// the installments of the billing, at the rate of their period and with the rounding remainder on the last one
+	var out strings.Builder
+	period := b.PaymentRecord.Size() + 1
+	for schedule := b.GenerateRemainingLoanSchedule(); !schedule.Empty(); period++ {
+		val, _ := schedule.Pop()
+		installment := val.(*engine.Payment)
+		fmt.Fprintf(&out, "week %d due %s: %.2f\n", installment.Week, b.DueDate(period).Format("2006-01-02"), installment.Amount)
+	}
+	return out.String(), nil
+}
+
+func (s *Session) outstanding(args []string) (string, error) {
+	b, err := s.loaded()
+	if err != nil {
+		return "", err
+	}
+	if b.IsClosed() {
+		return fmt.Sprintf("closed %s on %s\n", b.Closure.Reason, b.Closure.Date.Format("2006-01-02")), nil
+	}
+	return fmt.Sprintf("outstanding %.2f, penalty %.2f, %d weeks left, missed %d, delinquent %t\n",
+		b.Outstanding, b.GetPenalty(), b.RemainingWeeks, b.MissedPayment, b.IsDelinquent()), nil
+}
+
+func (s *Session) save(args []string) (string, error) {
+	b, err := s.loaded()
+	if err != nil {
+		return "", err
+	}
+	if err := s.Store.Save(b); err != nil {
+		return "", fmt.Errorf("cannot save loanID:%s with err: %v", b.Loan.LoanID, err)
+	}
+	s.unsaved = false
+	s.undoable = 0
+	return fmt.Sprintf("loan %s saved\n", b.Loan.LoanID), nil
+}
+
+func (s *Session) history(args []string) (string, error) {
+	var out strings.Builder
+	for i, line := range s.lines {
+		fmt.Fprintf(&out, "%4d  %s\n", i+1, line)
+	}
+	return out.String(), nil
+}
+
+func (s *Session) export(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", errors.New("usage: export <file>")
+	}
+	f, err := os.Create(args[0])
+	if err != nil {
+		return "", err
+	}
+	if err := s.WriteTranscript(f); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("transcript written to %s\n", args[0]), nil
+}
+
+// WriteTranscript writes the transcript of the session, headed by the time it is exported.
+func (s *Session) WriteTranscript(w io.Writer) error {
+	c := s.Clock
+	if c == nil {
+		c = clock.System{}
+	}
+	if _, err := fmt.Fprintf(w, "# billing session exported %s\n", c.Now().Format(time.RFC3339)); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, s.transcript.String())
+	return err
+}
+
+func (s *Session) help(args []string) (string, error) {
+	var out strings.Builder
+	for _, c := range commands {
+		fmt.Fprintf(&out, "  %-22s %s\n", strings.TrimSpace(c.name+" "+c.args), c.usage)
+	}
+	return out.String(), nil
+}
+
+func (s *Session) quit(args []string) (string, error) {
+	if s.unsaved && !s.warned {
+		s.warned = true // quitting right again leaves without saving
+		return "", fmt.Errorf("loanID:%s has unsaved changes, save them or quit again to drop them", s.billing.Loan.LoanID)
+	}
+	return "", ErrQuit
+}
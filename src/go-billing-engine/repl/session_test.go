@@ -0,0 +1,176 @@
+package repl
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gobillingengine/clock"
+	"gobillingengine/engine"
+	"gobillingengine/model"
+	"gobillingengine/portfolio"
+)
+
+func newTestSession(t *testing.T) (*Session, *portfolio.MemoryStore) {
+	store := portfolio.NewMemoryStore()
+	for _, loanID := range []string{"1001", "1002", "2001"} {
+		loan := model.NewLoan(loanID, 50, 5000, 0.1)
+		loan.DisbursementDate = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		require.NoError(t, store.Create(engine.NewBilling(loan)))
+	}
+	return NewSession(store), store
+}
+
+func TestSession(t *testing.T) {
+	s, _ := newTestSession(t)
+
+	_, err := s.Execute("pay 110")
+	assert.EqualError(t, err, "no loan loaded, use load <loan_id>")
+
+	out, err := s.Execute("load 1001")
+	require.NoError(t, err)
+	assert.Equal(t, "loan 1001 loaded: 50 weeks of 110.00\n"+
+		"outstanding 5500.00, penalty 0.00, 50 weeks left, missed 0, delinquent false\n", out)
+
+	out, err = s.Execute("pay 110")
+	require.NoError(t, err)
+	assert.Equal(t, "week 1 due 2024-01-08 paid 110.00\n"+
+		"outstanding 5390.00, penalty 0.00, 49 weeks left, missed 0, delinquent false\n", out)
+
+	_, err = s.Execute("pay 100")
+	assert.EqualError(t, err, "payment should be 110.000000 or 0")
+
+	_, err = s.Execute("miss")
+	require.NoError(t, err)
+	out, err = s.Execute("miss")
+	require.NoError(t, err)
+	assert.Contains(t, out, "missed 2, delinquent true")
+
+	out, err = s.Execute("schedule")
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	assert.Equal(t, 49, len(lines))
+	assert.Equal(t, "week 2 due 2024-01-29: 110.00", lines[0])
+
+	out, err = s.Execute("undo")
+	require.NoError(t, err)
+	assert.Equal(t, "reversed the miss of week 2 due 2024-01-22\n"+
+		"outstanding 5390.00, penalty 0.00, 49 weeks left, missed 1, delinquent false\n", out)
+	assert.Equal(t, 2, len(s.Billing().Payments()))
+
+	_, err = s.Execute("bogus")
+	assert.EqualError(t, err, `unknown command "bogus", type help for the list`)
+
+	assert.Equal(t, []string{"pay 110", "load 1001", "pay 110", "pay 100", "miss", "miss", "schedule", "undo", "bogus"},
+		s.History())
+	assert.Contains(t, s.Transcript(), "> pay 100\nerror: payment should be 110.000000 or 0\n> miss\n")
+}
+
+func TestSession_UndoOnlyThisSession(t *testing.T) {
+	s, store := newTestSession(t)
+	b, err := store.Get("1001")
+	require.NoError(t, err)
+	require.NoError(t, b.MakePayment(b.PayableAmount))
+
+	_, err = s.Execute("load 1001")
+	require.NoError(t, err)
+	_, err = s.Execute("undo")
+	assert.EqualError(t, err, "nothing to undo in this session")
+	assert.Equal(t, 1, len(b.Payments()))
+}
+
+func TestSession_Schedule(t *testing.T) {
+	s, store := newTestSession(t)
+	loan := model.NewLoan("3001", 3, 1000000, 0.1)
+	loan.Currency = "IDR"
+	loan.VariableRate = true
+	loan.DisbursementDate = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := engine.NewBilling(loan)
+	require.NoError(t, b.ChangeRate(0.4, b.DueDate(2)))
+	require.NoError(t, store.Create(b))
+
+	_, err := s.Execute("load 3001")
+	require.NoError(t, err)
+	out, err := s.Execute("schedule")
+	require.NoError(t, err)
+	assert.Equal(t, "week 1 due 2024-01-08: 366667.00\n"+
+		"week 2 due 2024-01-15: 466667.00\n"+
+		"week 3 due 2024-01-22: 466667.00\n", out)
+
+	// the last installment takes what is left of the outstanding
+	for _, amount := range []string{"366667", "466667"} {
+		_, err = s.Execute("pay " + amount)
+		require.NoError(t, err)
+	}
+	out, err = s.Execute("schedule")
+	require.NoError(t, err)
+	assert.Equal(t, "week 3 due 2024-01-22: 466666.00\n", out)
+}
+
+func TestSession_UnsavedChanges(t *testing.T) {
+	s, _ := newTestSession(t)
+	for _, line := range []string{"load 1001", "pay 110"} {
+		_, err := s.Execute(line)
+		require.NoError(t, err)
+	}
+
+	_, err := s.Execute("load 1002")
+	assert.EqualError(t, err, "loanID:1001 has unsaved changes, save them first")
+	_, err = s.Execute("quit")
+	assert.EqualError(t, err, "loanID:1001 has unsaved changes, save them or quit again to drop them")
+	_, err = s.Execute("quit")
+	assert.ErrorIs(t, err, ErrQuit)
+
+	out, err := s.Execute("save")
+	require.NoError(t, err)
+	assert.Equal(t, "loan 1001 saved\n", out)
+	assert.False(t, s.Unsaved())
+	_, err = s.Execute("undo")
+	assert.EqualError(t, err, "nothing to undo in this session")
+	_, err = s.Execute("load 1002")
+	require.NoError(t, err)
+}
+
+func TestSession_Complete(t *testing.T) {
+	s, _ := newTestSession(t)
+	assert.Equal(t, []string{"save", "schedule"}, s.Complete("s"))
+	assert.Equal(t, []string{"undo"}, s.Complete("  u"))
+	assert.Equal(t, []string{"load 1001", "load 1002"}, s.Complete("load 10"))
+	assert.Equal(t, []string{"load 1001", "load 1002", "load 2001"}, s.Complete("load "))
+	assert.Empty(t, s.Complete("pay 1"))
+	assert.Equal(t, 11, len(s.Complete("")))
+}
+
+func TestRun(t *testing.T) {
+	s, store := newTestSession(t)
+	s.Clock = clock.Fixed(time.Date(2024, 2, 1, 9, 0, 0, 0, time.UTC))
+	transcript := filepath.Join(t.TempDir(), "session.txt")
+
+	in := strings.NewReader("load 1002\npay 110\n\nsave\nexport " + transcript + "\nquit\npay 110\n")
+	var out bytes.Buffer
+	require.NoError(t, Run(s, NewLineReader(in, &out), &out))
+
+	// the lines after quit are not run
+	b, err := store.Get("1002")
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(b.Payments()))
+	assert.Contains(t, out.String(), "1002> week 1 due 2024-01-08 paid 110.00\n")
+
+	data, err := os.ReadFile(transcript)
+	require.NoError(t, err)
+	assert.Equal(t, "# billing session exported 2024-02-01T09:00:00Z\n"+
+		"> load 1002\n"+
+		"loan 1002 loaded: 50 weeks of 110.00\n"+
+		"outstanding 5500.00, penalty 0.00, 50 weeks left, missed 0, delinquent false\n"+
+		"> pay 110\n"+
+		"week 1 due 2024-01-08 paid 110.00\n"+
+		"outstanding 5390.00, penalty 0.00, 49 weeks left, missed 0, delinquent false\n"+
+		"> save\n"+
+		"loan 1002 saved\n", string(data))
+}
@@ -0,0 +1,134 @@
+//go:build linux
+
+package repl
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/sys/unix"
+)
+
+// Terminal is a LineReader over a terminal in raw mode: the line is edited at its end, the up and down arrows walk
+// the command history and tab completes the line.
+type Terminal struct {
+	fd       int
+	in       *bufio.Reader
+	out      io.Writer
+	session  *Session
+	original unix.Termios
+}
+
+// NewTerminal switches the terminal in to raw mode, Close restores it. It fails if in is not a terminal.
+func NewTerminal(in *os.File, out io.Writer, s *Session) (*Terminal, error) {
+	fd := int(in.Fd())
+	termios, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return nil, err
+	}
+	t := &Terminal{fd: fd, in: bufio.NewReader(in), out: out, session: s, original: *termios}
+
+	raw := *termios
+	raw.Lflag &^= unix.ICANON | unix.ECHO | unix.ISIG | unix.IEXTEN
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(fd, unix.TCSETS, &raw); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Close restores the terminal as it was.
+func (t *Terminal) Close() error {
+	return unix.IoctlSetTermios(t.fd, unix.TCSETS, &t.original)
+}
+
+func (t *Terminal) ReadLine(prompt string) (string, error) {
+	history := t.session.History()
+	position := len(history) // position in the history, its length for the line being typed
+	var line, typed string
+
+	redraw := func() {
+		io.WriteString(t.out, "\r\033[K"+prompt+line)
+	}
+	redraw()
+	for {
+		r, _, err := t.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case '\r', '\n':
+			io.WriteString(t.out, "\n")
+			return line, nil
+		case 0x03: // ctrl-c drops the line
+			io.WriteString(t.out, "^C\n")
+			return "", nil
+		case 0x04: // ctrl-d ends the input on an empty line
+			if line == "" {
+				io.WriteString(t.out, "\n")
+				return "", io.EOF
+			}
+		case 0x7f, 0x08:
+			if line != "" {
+				_, size := utf8.DecodeLastRuneInString(line)
+				line = line[:len(line)-size]
+				redraw()
+			}
+		case '\t':
+			line = t.complete(line)
+			redraw()
+		case 0x1b:
+			// arrows are ESC [ A to D, other escape sequences are ignored
+			if next, _, _ := t.in.ReadRune(); next != '[' {
+				continue
+			}
+			arrow, _, _ := t.in.ReadRune()
+			switch {
+			case arrow == 'A' && position > 0:
+				if position == len(history) {
+					typed = line
+				}
+				position--
+				line = history[position]
+			case arrow == 'B' && position < len(history):
+				position++
+				line = typed
+				if position < len(history) {
+					line = history[position]
+				}
+			}
+			redraw()
+		default:
+			if r >= ' ' {
+				line += string(r)
+				io.WriteString(t.out, string(r))
+			}
+		}
+	}
+}
+
+// complete extends the line to the longest prefix common to its completions, and lists them if it cannot.
+func (t *Terminal) complete(line string) string {
+	candidates := t.session.Complete(line)
+	switch len(candidates) {
+	case 0:
+		return line
+	case 1:
+		return candidates[0] + " "
+	}
+	prefix := candidates[0]
+	for _, c := range candidates[1:] {
+		for !strings.HasPrefix(c, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if len(prefix) > len(strings.TrimLeft(line, " ")) {
+		return prefix
+	}
+	io.WriteString(t.out, "\n"+strings.Join(candidates, "  ")+"\n")
+	return line
+}
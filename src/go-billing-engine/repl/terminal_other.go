@@ -0,0 +1,24 @@
+//go:build !linux
+
+package repl
+
+import (
+	"errors"
+	"io"
+	"os"
+)
+
+// Terminal line editing is only supported on linux, elsewhere the shell reads plain lines.
+type Terminal struct{}
+
+func NewTerminal(in *os.File, out io.Writer, s *Session) (*Terminal, error) {
+	return nil, errors.New("terminal line editing is not supported on this platform")
+}
+
+func (t *Terminal) Close() error {
+	return nil
+}
+
+func (t *Terminal) ReadLine(prompt string) (string, error) {
+	return "", io.EOF
+}
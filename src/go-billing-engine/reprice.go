@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"gobillingengine/portfolio"
+	"gobillingengine/product"
+)
+
+func newRepriceCmd() *cobra.Command {
+	repriceCmd := &cobra.Command{
+		Use:   "reprice <loan_id>",
+		Short: "Change the rate of a variable-rate loan of the portfolio from an effective date",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, _ := cmd.Flags().GetString("portfolio")
+			rate, _ := cmd.Flags().GetFloat64("rate")
+			annualRate, _ := cmd.Flags().GetFloat64("annual-rate")
+			catalogPath, _ := cmd.Flags().GetString("catalog")
+			effective, err := getDate(cmd, "effective")
+			if err != nil {
+				return err
+			}
+			if cmd.Flags().Changed("rate") == cmd.Flags().Changed("annual-rate") {
+				return errors.New("either --rate or --annual-rate is required")
+			}
+
+			store, err := portfolio.OpenFileStore(path)
+			if err != nil {
+				return err
+			}
+			b, err := store.Get(args[0])
+			if err != nil {
+				return fmt.Errorf("loanID:%s - %v", args[0], err)
+			}
+			if cmd.Flags().Changed("annual-rate") {
+				catalog, err := product.Load(catalogPath)
+				if err != nil {
+					return err
+				}
+				p, err := catalog.Get(b.Loan.ProductCode)
+				if err != nil {
+					return fmt.Errorf("loanID:%s - %v", args[0], err)
+				}
+				rate = p.Prorate(annualRate, b.Loan.Weeks)
+			}
+
+			repriced := len(b.Repricings)
+			if err := b.ChangeRate(rate, effective); err != nil {
+				return fmt.Errorf("cannot reprice loanID:%s with err: %v", args[0], err)
+			}
+			if err := store.Save(b); err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if len(b.Repricings) == repriced {
+				fmt.Fprintf(out, "loan %s: rate %.4g scheduled from %s\n", args[0], rate, effective.Format(dateLayout))
+				return nil
+			}
+			for _, r := range b.Repricings[repriced:] {
+				fmt.Fprintf(out, "loan %s repriced from week %d (due %s): rate %.4g -> %.4g, installment %.2f, outstanding %+.2f\n",
+					args[0], r.Week, r.Date.Format(dateLayout), r.OldRate, r.NewRate, r.PayableAmount, r.Outstanding)
+			}
+			return nil
+		},
+	}
+	repriceCmd.Flags().String("portfolio", "portfolio.json", "The persisted portfolio file")
+	repriceCmd.Flags().Float64("rate", 0, "The new flat interest rate over the term, 0.12 for 12%")
+	repriceCmd.Flags().Float64("annual-rate", 0, "The new annual rate, prorated like the product of the loan instead of --rate")
+	repriceCmd.Flags().String("catalog", "examples/products.yaml", "The YAML product catalog")
+	repriceCmd.Flags().String("effective", "", "The date the new rate is effective from (YYYY-MM-DD), today by default")
+	return repriceCmd
+}
@@ -0,0 +1,324 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.25.3
+// source: billing.proto
+
+package billingpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	BillingService_CreateBilling_FullMethodName       = "/billing.v1.BillingService/CreateBilling"
+	BillingService_GetSchedule_FullMethodName         = "/billing.v1.BillingService/GetSchedule"
+	BillingService_MakePayment_FullMethodName         = "/billing.v1.BillingService/MakePayment"
+	BillingService_GetOutstanding_FullMethodName      = "/billing.v1.BillingService/GetOutstanding"
+	BillingService_IsDelinquent_FullMethodName        = "/billing.v1.BillingService/IsDelinquent"
+	BillingService_StreamPaymentEvents_FullMethodName = "/billing.v1.BillingService/StreamPaymentEvents"
+)
+
+// BillingServiceClient is the client API for BillingService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type BillingServiceClient interface {
+	CreateBilling(ctx context.Context, in *CreateBillingRequest, opts ...grpc.CallOption) (*BillingSummary, error)
+	GetSchedule(ctx context.Context, in *GetScheduleRequest, opts ...grpc.CallOption) (*GetScheduleResponse, error)
+	MakePayment(ctx context.Context, in *MakePaymentRequest, opts ...grpc.CallOption) (*BillingSummary, error)
+	GetOutstanding(ctx context.Context, in *GetOutstandingRequest, opts ...grpc.CallOption) (*GetOutstandingResponse, error)
+	IsDelinquent(ctx context.Context, in *IsDelinquentRequest, opts ...grpc.CallOption) (*IsDelinquentResponse, error)
+	// StreamPaymentEvents streams every payment made on the loan after subscribing.
+	StreamPaymentEvents(ctx context.Context, in *StreamPaymentEventsRequest, opts ...grpc.CallOption) (BillingService_StreamPaymentEventsClient, error)
+}
+
+type billingServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBillingServiceClient(cc grpc.ClientConnInterface) BillingServiceClient {
+	return &billingServiceClient{cc}
+}
+
+func (c *billingServiceClient) CreateBilling(ctx context.Context, in *CreateBillingRequest, opts ...grpc.CallOption) (*BillingSummary, error) {
+	out := new(BillingSummary)
+	err := c.cc.Invoke(ctx, BillingService_CreateBilling_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *billingServiceClient) GetSchedule(ctx context.Context, in *GetScheduleRequest, opts ...grpc.CallOption) (*GetScheduleResponse, error) {
+	out := new(GetScheduleResponse)
+	err := c.cc.Invoke(ctx, BillingService_GetSchedule_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *billingServiceClient) MakePayment(ctx context.Context, in *MakePaymentRequest, opts ...grpc.CallOption) (*BillingSummary, error) {
+	out := new(BillingSummary)
+	err := c.cc.Invoke(ctx, BillingService_MakePayment_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *billingServiceClient) GetOutstanding(ctx context.Context, in *GetOutstandingRequest, opts ...grpc.CallOption) (*GetOutstandingResponse, error) {
+	out := new(GetOutstandingResponse)
+	err := c.cc.Invoke(ctx, BillingService_GetOutstanding_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *billingServiceClient) IsDelinquent(ctx context.Context, in *IsDelinquentRequest, opts ...grpc.CallOption) (*IsDelinquentResponse, error) {
+	out := new(IsDelinquentResponse)
+	err := c.cc.Invoke(ctx, BillingService_IsDelinquent_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *billingServiceClient) StreamPaymentEvents(ctx context.Context, in *StreamPaymentEventsRequest, opts ...grpc.CallOption) (BillingService_StreamPaymentEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &BillingService_ServiceDesc.Streams[0], BillingService_StreamPaymentEvents_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &billingServiceStreamPaymentEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type BillingService_StreamPaymentEventsClient interface {
+	Recv() (*PaymentEvent, error)
+	grpc.ClientStream
+}
+
+type billingServiceStreamPaymentEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *billingServiceStreamPaymentEventsClient) Recv() (*PaymentEvent, error) {
+	m := new(PaymentEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// BillingServiceServer is the server API for BillingService service.
+// All implementations must embed UnimplementedBillingServiceServer
+// for forward compatibility
+type BillingServiceServer interface {
+	CreateBilling(context.Context, *CreateBillingRequest) (*BillingSummary, error)
+	GetSchedule(context.Context, *GetScheduleRequest) (*GetScheduleResponse, error)
+	MakePayment(context.Context, *MakePaymentRequest) (*BillingSummary, error)
+	GetOutstanding(context.Context, *GetOutstandingRequest) (*GetOutstandingResponse, error)
+	IsDelinquent(context.Context, *IsDelinquentRequest) (*IsDelinquentResponse, error)
+	// StreamPaymentEvents streams every payment made on the loan after subscribing.
+	StreamPaymentEvents(*StreamPaymentEventsRequest, BillingService_StreamPaymentEventsServer) error
+	mustEmbedUnimplementedBillingServiceServer()
+}
+
+// UnimplementedBillingServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedBillingServiceServer struct {
+}
+
+func (UnimplementedBillingServiceServer) CreateBilling(context.Context, *CreateBillingRequest) (*BillingSummary, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateBilling not implemented")
+}
+func (UnimplementedBillingServiceServer) GetSchedule(context.Context, *GetScheduleRequest) (*GetScheduleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSchedule not implemented")
+}
+func (UnimplementedBillingServiceServer) MakePayment(context.Context, *MakePaymentRequest) (*BillingSummary, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MakePayment not implemented")
+}
+func (UnimplementedBillingServiceServer) GetOutstanding(context.Context, *GetOutstandingRequest) (*GetOutstandingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOutstanding not implemented")
+}
+func (UnimplementedBillingServiceServer) IsDelinquent(context.Context, *IsDelinquentRequest) (*IsDelinquentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method IsDelinquent not implemented")
+}
+func (UnimplementedBillingServiceServer) StreamPaymentEvents(*StreamPaymentEventsRequest, BillingService_StreamPaymentEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamPaymentEvents not implemented")
+}
+func (UnimplementedBillingServiceServer) mustEmbedUnimplementedBillingServiceServer() {}
+
+// UnsafeBillingServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BillingServiceServer will
+// result in compilation errors.
+type UnsafeBillingServiceServer interface {
+	mustEmbedUnimplementedBillingServiceServer()
+}
+
+func RegisterBillingServiceServer(s grpc.ServiceRegistrar, srv BillingServiceServer) {
+	s.RegisterService(&BillingService_ServiceDesc, srv)
+}
+
+func _BillingService_CreateBilling_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateBillingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BillingServiceServer).CreateBilling(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BillingService_CreateBilling_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BillingServiceServer).CreateBilling(ctx, req.(*CreateBillingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BillingService_GetSchedule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetScheduleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BillingServiceServer).GetSchedule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BillingService_GetSchedule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BillingServiceServer).GetSchedule(ctx, req.(*GetScheduleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BillingService_MakePayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MakePaymentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BillingServiceServer).MakePayment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BillingService_MakePayment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BillingServiceServer).MakePayment(ctx, req.(*MakePaymentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BillingService_GetOutstanding_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOutstandingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BillingServiceServer).GetOutstanding(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BillingService_GetOutstanding_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BillingServiceServer).GetOutstanding(ctx, req.(*GetOutstandingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BillingService_IsDelinquent_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IsDelinquentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BillingServiceServer).IsDelinquent(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BillingService_IsDelinquent_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BillingServiceServer).IsDelinquent(ctx, req.(*IsDelinquentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BillingService_StreamPaymentEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamPaymentEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BillingServiceServer).StreamPaymentEvents(m, &billingServiceStreamPaymentEventsServer{stream})
+}
+
+type BillingService_StreamPaymentEventsServer interface {
+	Send(*PaymentEvent) error
+	grpc.ServerStream
+}
+
+type billingServiceStreamPaymentEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *billingServiceStreamPaymentEventsServer) Send(m *PaymentEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// BillingService_ServiceDesc is the grpc.ServiceDesc for BillingService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var BillingService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "billing.v1.BillingService",
+	HandlerType: (*BillingServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateBilling",
+			Handler:    _BillingService_CreateBilling_Handler,
+		},
+		{
+			MethodName: "GetSchedule",
+			Handler:    _BillingService_GetSchedule_Handler,
+		},
+		{
+			MethodName: "MakePayment",
+			Handler:    _BillingService_MakePayment_Handler,
+		},
+		{
+			MethodName: "GetOutstanding",
+			Handler:    _BillingService_GetOutstanding_Handler,
+		},
+		{
+			MethodName: "IsDelinquent",
+			Handler:    _BillingService_IsDelinquent_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamPaymentEvents",
+			Handler:       _BillingService_StreamPaymentEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "billing.proto",
+}
@@ -0,0 +1,43 @@
+package rpc
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"gobillingengine/portfolio"
+	"gobillingengine/rpc/billingpb"
+)
+
+const inProcessBufSize = 1024 * 1024
+
+// StartInProcess serves BillingService over an in-memory listener and returns a client connected to it.
+// It is meant for tests and for callers embedding the billing engine in the same process.
+// The returned stop function closes the client connection and the server.
+func StartInProcess(store portfolio.Store) (billingpb.BillingServiceClient, func(), error) {
+	lis := bufconn.Listen(inProcessBufSize)
+	srv := grpc.NewServer()
+	billingpb.RegisterBillingServiceServer(srv, NewServer(store))
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		srv.Stop()
+		return nil, nil, err
+	}
+
+	stop := func() {
+		_ = conn.Close()
+		srv.Stop()
+	}
+	return billingpb.NewBillingServiceClient(conn), stop, nil
+}
@@ -0,0 +1,239 @@
+package rpc
+
+//go:generate protoc -I . --go_out=.. --go_opt=module=gobillingengine --go-grpc_out=.. --go-grpc_opt=module=gobillingengine billing.proto
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"gobillingengine/engine"
+	"gobillingengine/model"
+	"gobillingengine/money"
+	"gobillingengine/portfolio"
+	"gobillingengine/rpc/billingpb"
+)
+
+// Server implements billingpb.BillingServiceServer over a portfolio of billings.
+type Server struct {
+	billingpb.UnimplementedBillingServiceServer
+
+	store portfolio.Store
+
+	mu          sync.Mutex // mu serialises billing mutations and guards subscribers
+	subscribers map[string][]chan *billingpb.PaymentEvent
+}
+
+func NewServer(store portfolio.Store) *Server {
+	return &Server{
+		store:       store,
+		subscribers: make(map[string][]chan *billingpb.PaymentEvent),
+	}
+}
+
+func (s *Server) CreateBilling(ctx context.Context, req *billingpb.CreateBillingRequest) (*billingpb.BillingSummary, error) {
+	if len(req.LoanId) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "loan_id is empty")
+	}
+	if req.Amount <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "amount must be positive")
+	}
+	if req.Weeks <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "weeks must be positive")
+	}
+	if req.FlatInterestRate < 0 || req.FlatInterestRate > 1 {
+		return nil, status.Error(codes.InvalidArgument, "flat_interest_rate must be a fraction, 0.1 for 10%")
+	}
+
+	if req.Currency != "" {
+		if _, err := money.Lookup(req.Currency); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	loan := model.NewLoan(req.LoanId, int(req.Weeks), req.Amount, req.FlatInterestRate)
+	loan.BorrowerID = req.BorrowerId
+	loan.Currency = req.Currency
+	billing := engine.NewBilling(loan)
+	if err := s.store.Create(billing); err != nil {
+		return nil, toStatus(err)
+	}
+	return toSummary(billing), nil
+}
+
+func (s *Server) GetSchedule(ctx context.Context, req *billingpb.GetScheduleRequest) (*billingpb.GetScheduleResponse, error) {
+	billing, err := s.store.Get(req.LoanId)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	s.mu.Lock()
+	schedule := billing.GenerateLoanSchedule()
+	if req.RemainingOnly {
+		schedule = billing.GenerateRemainingLoanSchedule()
+	}
+	s.mu.Unlock()
+
+	resp := &billingpb.GetScheduleResponse{LoanId: req.LoanId}
+	for !schedule.Empty() {
+		val, _ := schedule.Pop()
+		payment := val.(*engine.Payment)
+		resp.Installments = append(resp.Installments, &billingpb.Installment{
+			Week:   int32(payment.Week),
+			Amount: payment.Amount,
+		})
+	}
+	return resp, nil
+}
+
+func (s *Server) MakePayment(ctx context.Context, req *billingpb.MakePaymentRequest) (*billingpb.BillingSummary, error) {
+	billing, err := s.store.Get(req.LoanId)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	currency := req.Currency
+	if currency == "" {
+		currency = billing.Loan.Currency
+	}
+	if err := billing.MakePaymentIn(money.Money{Amount: req.Amount, Currency: currency}); err != nil {
+		if errors.Is(err, money.ErrCurrencyMismatch) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if err := s.store.Save(billing); err != nil {
+		return nil, toStatus(err)
+	}
+
+	s.publish(billing)
+	return toSummary(billing), nil
+}
+
+func (s *Server) GetOutstanding(ctx context.Context, req *billingpb.GetOutstandingRequest) (*billingpb.GetOutstandingResponse, error) {
+	billing, err := s.store.Get(req.LoanId)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &billingpb.GetOutstandingResponse{
+		LoanId:      req.LoanId,
+		Outstanding: billing.GetOutstanding(),
+	}, nil
+}
+
+func (s *Server) IsDelinquent(ctx context.Context, req *billingpb.IsDelinquentRequest) (*billingpb.IsDelinquentResponse, error) {
+	billing, err := s.store.Get(req.LoanId)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &billingpb.IsDelinquentResponse{
+		LoanId:     req.LoanId,
+		Delinquent: billing.IsDelinquent(),
+	}, nil
+}
+
+func (s *Server) StreamPaymentEvents(req *billingpb.StreamPaymentEventsRequest, stream billingpb.BillingService_StreamPaymentEventsServer) error {
+	if _, err := s.store.Get(req.LoanId); err != nil {
+		return toStatus(err)
+	}
+
+	events := s.subscribe(req.LoanId)
+	defer s.unsubscribe(req.LoanId, events)
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event := <-events:
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// subscriberBuffer is how many events a slow subscriber may lag behind before events are dropped for it.
+const subscriberBuffer = 16
+
+func (s *Server) subscribe(loanID string) chan *billingpb.PaymentEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := make(chan *billingpb.PaymentEvent, subscriberBuffer)
+	s.subscribers[loanID] = append(s.subscribers[loanID], events)
+	return events
+}
+
+func (s *Server) unsubscribe(loanID string, events chan *billingpb.PaymentEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	subs := s.subscribers[loanID]
+	for i, sub := range subs {
+		if sub == events {
+			s.subscribers[loanID] = append(subs[:i], subs[i+1:]...)
+			break
+		}
+	}
+	if len(s.subscribers[loanID]) == 0 {
+		delete(s.subscribers, loanID)
+	}
+}
+
+// publish fans the latest payment of the billing out to its subscribers. Callers must hold s.mu.
+func (s *Server) publish(billing *engine.Billing) {
+	val, ok := billing.PaymentRecord.Peek()
+	if !ok {
+		return
+	}
+	payment := val.(*engine.Payment)
+	event := &billingpb.PaymentEvent{
+		LoanId:        billing.Loan.LoanID,
+		Week:          int32(payment.Week),
+		Amount:        payment.Amount,
+		Outstanding:   billing.Outstanding,
+		MissedPayment: int32(billing.MissedPayment),
+		Delinquent:    billing.IsDelinquent(),
+	}
+	for _, sub := range s.subscribers[billing.Loan.LoanID] {
+		select {
+		case sub <- event:
+		default: // never block a payment on a slow subscriber
+		}
+	}
+}
+
+func toSummary(billing *engine.Billing) *billingpb.BillingSummary {
+	return &billingpb.BillingSummary{
+		LoanId:         billing.Loan.LoanID,
+		PayableAmount:  billing.PayableAmount,
+		Outstanding:    billing.Outstanding,
+		MissedPayment:  int32(billing.MissedPayment),
+		RemainingWeeks: int32(billing.RemainingWeeks),
+		Delinquent:     billing.IsDelinquent(),
+		Currency:       billing.Loan.Currency,
+	}
+}
+
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, portfolio.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, portfolio.ErrAlreadyExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
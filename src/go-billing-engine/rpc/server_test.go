@@ -0,0 +1,138 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"gobillingengine/portfolio"
+	"gobillingengine/rpc/billingpb"
+)
+
+func TestBillingService(t *testing.T) {
+	client, stop, err := StartInProcess(portfolio.NewMemoryStore())
+	require.NoError(t, err)
+	defer stop()
+
+	ctx := context.Background()
+
+	summary, err := client.CreateBilling(ctx, &billingpb.CreateBillingRequest{
+		LoanId:           "1001",
+		Amount:           5000,
+		FlatInterestRate: 0.1,
+		Weeks:            50,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 110.0, summary.PayableAmount)
+	assert.Equal(t, 5500.0, summary.Outstanding)
+
+	// Creating the same loan twice is rejected
+	_, err = client.CreateBilling(ctx, &billingpb.CreateBillingRequest{LoanId: "1001", Amount: 5000, Weeks: 50})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	schedule, err := client.GetSchedule(ctx, &billingpb.GetScheduleRequest{LoanId: "1001"})
+	require.NoError(t, err)
+	assert.Len(t, schedule.Installments, 50)
+	assert.Equal(t, int32(1), schedule.Installments[0].Week)
+
+	summary, err = client.MakePayment(ctx, &billingpb.MakePaymentRequest{LoanId: "1001", Amount: 110})
+	require.NoError(t, err)
+	assert.Equal(t, int32(49), summary.RemainingWeeks)
+
+	_, err = client.MakePayment(ctx, &billingpb.MakePaymentRequest{LoanId: "1001", Amount: 20})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	remaining, err := client.GetSchedule(ctx, &billingpb.GetScheduleRequest{LoanId: "1001", RemainingOnly: true})
+	require.NoError(t, err)
+	assert.Len(t, remaining.Installments, 49)
+
+	outstanding, err := client.GetOutstanding(ctx, &billingpb.GetOutstandingRequest{LoanId: "1001"})
+	require.NoError(t, err)
+	assert.Equal(t, 5390.0, outstanding.Outstanding)
+
+	for i := 0; i < 2; i++ {
+		_, err = client.MakePayment(ctx, &billingpb.MakePaymentRequest{LoanId: "1001", Amount: 0})
+		require.NoError(t, err)
+	}
+	delinquent, err := client.IsDelinquent(ctx, &billingpb.IsDelinquentRequest{LoanId: "1001"})
+	require.NoError(t, err)
+	assert.True(t, delinquent.Delinquent)
+
+	_, err = client.GetOutstanding(ctx, &billingpb.GetOutstandingRequest{LoanId: "unknown"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestBillingService_Currency(t *testing.T) {
+	client, stop, err := StartInProcess(portfolio.NewMemoryStore())
+	require.NoError(t, err)
+	defer stop()
+	ctx := context.Background()
+
+	_, err = client.CreateBilling(ctx, &billingpb.CreateBillingRequest{LoanId: "1001", Amount: 5000, Weeks: 50, Currency: "XYZ"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	summary, err := client.CreateBilling(ctx, &billingpb.CreateBillingRequest{
+		LoanId:   "1001",
+		Amount:   1000,
+		Weeks:    3,
+		Currency: "USD",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "USD", summary.Currency)
+
+	_, err = client.MakePayment(ctx, &billingpb.MakePaymentRequest{LoanId: "1001", Amount: 333.33, Currency: "IDR"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// the amount is taken to the cent, in the loan currency when none is given
+	summary, err = client.MakePayment(ctx, &billingpb.MakePaymentRequest{LoanId: "1001", Amount: 333.33, Currency: "USD"})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), summary.RemainingWeeks)
+	summary, err = client.MakePayment(ctx, &billingpb.MakePaymentRequest{LoanId: "1001", Amount: 333.33})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), summary.RemainingWeeks)
+}
+
+func TestStreamPaymentEvents(t *testing.T) {
+	client, stop, err := StartInProcess(portfolio.NewMemoryStore())
+	require.NoError(t, err)
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err = client.CreateBilling(ctx, &billingpb.CreateBillingRequest{LoanId: "1001", Amount: 5000, FlatInterestRate: 0.1, Weeks: 50})
+	require.NoError(t, err)
+
+	stream, err := client.StreamPaymentEvents(ctx, &billingpb.StreamPaymentEventsRequest{LoanId: "1001"})
+	require.NoError(t, err)
+
+	// The subscription is registered asynchronously, keep paying until the first event arrives
+	received := make(chan *billingpb.PaymentEvent, 1)
+	go func() {
+		event, err := stream.Recv()
+		if err == nil {
+			received <- event
+		}
+	}()
+
+	for {
+		_, err = client.MakePayment(ctx, &billingpb.MakePaymentRequest{LoanId: "1001", Amount: 0})
+		require.NoError(t, err)
+
+		select {
+		case event := <-received:
+			assert.Equal(t, "1001", event.LoanId)
+			assert.Equal(t, 0.0, event.Amount)
+			assert.Equal(t, 5500.0, event.Outstanding)
+			return
+		case <-time.After(50 * time.Millisecond):
+		case <-ctx.Done():
+			t.Fatal("no payment event received")
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package scenario
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"gobillingengine/engine"
+	"gobillingengine/model"
+)
+
+// Result is the outcome of a scenario run.
+type Result struct {
+	Billing  *engine.Billing
+	Failures []string // Failures of the steps and assertions, empty when the scenario passed
+}
+
+func (r *Result) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// Run applies the steps of the scenario to a new billing and writes the state after each step to w.
+func Run(s *Scenario, w io.Writer) *Result {
+	loan := model.NewLoan(s.Loan.ID, s.Loan.Weeks, s.Loan.Amount, s.Loan.FlatInterestRate)
+	loan.DisbursementDate = s.Loan.DisbursementDate.Time
+	loan.VariableRate = s.Loan.VariableRate
+	billing := engine.NewBilling(loan)
+	result := &Result{Billing: billing}
+
+	if s.Name != "" {
+		fmt.Fprintf(w, "scenario: %s\n", s.Name)
+	}
+	fmt.Fprintf(w, "loan %s: amount %.2f, rate %v, %d weeks, payable %.2f\n",
+		loan.LoanID, loan.Amount, loan.FlatInterestRate, loan.Weeks, billing.PayableAmount)
+
+	for idx, step := range s.Steps {
+		// a dated step first misses every period due before it
+		for !step.Date.IsZero() && billing.DueDate(billing.PaymentRecord.Size()+1).Before(step.Date.Time) {
+			if err := billing.MakePayment(0); err != nil {
+				break // nothing is due on a paid off billing
+			}
+			label := fmt.Sprintf("       %s miss (no step)", billing.DueDate(billing.PaymentRecord.Size()).Format(dateLayout))
+			fmt.Fprintf(w, "%-36s -> %s\n", label, state(billing))
+		}
+
+		err := apply(billing, step)
+		label := stepLabel(idx, step)
+		switch {
+		case err != nil && step.ExpectError:
+			fmt.Fprintf(w, "%-36s -> rejected as expected: %v\n", label, err)
+		case err != nil:
+			fmt.Fprintf(w, "%-36s -> error: %v\n", label, err)
+			result.Failures = append(result.Failures, fmt.Sprintf("step %d: unexpected error: %v", idx+1, err))
+		case step.ExpectError:
+			fmt.Fprintf(w, "%-36s -> %s\n", label, state(billing))
+			result.Failures = append(result.Failures, fmt.Sprintf("step %d: expected an error", idx+1))
+		default:
+			fmt.Fprintf(w, "%-36s -> %s\n", label, state(billing))
+		}
+	}
+
+	if s.Assert != nil {
+		result.Failures = append(result.Failures, check(billing, s.Assert)...)
+	}
+	for _, failure := range result.Failures {
+		fmt.Fprintf(w, "FAIL %s\n", failure)
+	}
+	if result.Passed() {
+		fmt.Fprintln(w, "PASS")
+	}
+	return result
+}
+
+func apply(billing *engine.Billing, step Step) error {
+	switch step.Action {
+	case ActionPay:
+		return billing.MakePayment(step.Amount)
+	case ActionMiss:
+		return billing.MakePayment(0)
+	case ActionReverse:
+		_, err := billing.ReversePayment()
+		return err
+	case ActionRate:
+		effective := step.Date.Time
+		if effective.IsZero() {
+			effective = billing.DueDate(billing.PaymentRecord.Size() + 1)
+		}
+		return billing.ChangeRate(step.Rate, effective)
+	}
+	return fmt.Errorf("unknown action %q", step.Action)
+}
+
+func check(billing *engine.Billing, a *Assert) []string {
+	var failures []string
+	if a.Outstanding != nil && math.Abs(billing.GetOutstanding()-*a.Outstanding) > 0.005 {
+		failures = append(failures, fmt.Sprintf("outstanding is %.2f, expected %.2f", billing.GetOutstanding(), *a.Outstanding))
+	}
+	if a.RemainingWeeks != nil && billing.RemainingWeeks != *a.RemainingWeeks {
+		failures = append(failures, fmt.Sprintf("remaining weeks is %d, expected %d", billing.RemainingWeeks, *a.RemainingWeeks))
+	}
+	if a.MissedPayment != nil && billing.MissedPayment != *a.MissedPayment {
+		failures = append(failures, fmt.Sprintf("missed payment is %d, expected %d", billing.MissedPayment, *a.MissedPayment))
+	}
+	if a.Delinquent != nil && billing.IsDelinquent() != *a.Delinquent {
+		failures = append(failures, fmt.Sprintf("delinquent is %t, expected %t", billing.IsDelinquent(), *a.Delinquent))
+	}
+	return failures
+}
+
+func state(billing *engine.Billing) string {
+	return fmt.Sprintf("outstanding %.2f, remaining %d weeks, missed %d, delinquent %t",
+		billing.GetOutstanding(), billing.RemainingWeeks, billing.MissedPayment, billing.IsDelinquent())
+}
+
+const dateLayout = "2006-01-02"
+
+func stepLabel(idx int, step Step) string {
+	label := fmt.Sprintf("step %d", idx+1)
+	if !step.Date.IsZero() {
+		label += " " + step.Date.Format(dateLayout)
+	}
+	label += " " + step.Action
+	switch step.Action {
+	case ActionPay:
+		label += fmt.Sprintf(" %.2f", step.Amount)
+	case ActionRate:
+		label += fmt.Sprintf(" %v", step.Rate)
+	}
+	return label
+}
@@ -0,0 +1,144 @@
+// Package scenario replays scripted borrower situations against the billing engine.
+package scenario
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ActionPay     = "pay"
+	ActionMiss    = "miss"
+	ActionReverse = "reverse"
+	ActionRate    = "rate"
+)
+
+// Scenario is a loan and the sequence of steps applied to its billing.
+type Scenario struct {
+	Name   string  `json:"name" yaml:"name"`
+	Loan   Loan    `json:"loan" yaml:"loan"`
+	Steps  []Step  `json:"steps" yaml:"steps"`
+	Assert *Assert `json:"assert,omitempty" yaml:"assert,omitempty"`
+}
+
+type Loan struct {
+	ID               string  `json:"id" yaml:"id"`
+	Amount           float64 `json:"amount" yaml:"amount"`
+	FlatInterestRate float64 `json:"flat_interest_rate" yaml:"flat_interest_rate"`
+	VariableRate     bool    `json:"variable_rate,omitempty" yaml:"variable_rate,omitempty"`
+	Weeks            int     `json:"weeks" yaml:"weeks"`
+	DisbursementDate Date    `json:"disbursement_date" yaml:"disbursement_date"`
+}
+
+// Step is one action on the billing. When dated, every period due before the date
+// without a step is missed first. A rate step is effective from its date, from the next period if undated.
+type Step struct {
+	Date        Date    `json:"date,omitempty" yaml:"date,omitempty"`
+	Action      string  `json:"action" yaml:"action"`
+	Amount      float64 `json:"amount,omitempty" yaml:"amount,omitempty"`
+	Rate        float64 `json:"rate,omitempty" yaml:"rate,omitempty"` // Rate of a rate step, the new flat rate over the term
+	ExpectError bool    `json:"expect_error,omitempty" yaml:"expect_error,omitempty"`
+}
+
+// Assert holds the expected final state, unset fields are not checked.
+type Assert struct {
+	Outstanding    *float64 `json:"outstanding,omitempty" yaml:"outstanding,omitempty"`
+	RemainingWeeks *int     `json:"remaining_weeks,omitempty" yaml:"remaining_weeks,omitempty"`
+	MissedPayment  *int     `json:"missed_payment,omitempty" yaml:"missed_payment,omitempty"`
+	Delinquent     *bool    `json:"delinquent,omitempty" yaml:"delinquent,omitempty"`
+}
+
+// Date is a calendar date written as 2006-01-02 (RFC 3339 timestamps are accepted too).
+type Date struct {
+	time.Time
+}
+
+func parseDate(s string) (Date, error) {
+	if s == "" {
+		return Date{}, nil
+	}
+	for _, layout := range []string{dateLayout, time.RFC3339} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return Date{t}, nil
+		}
+	}
+	return Date{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", s)
+}
+
+func (d *Date) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := parseDate(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+func (d *Date) UnmarshalYAML(value *yaml.Node) error {
+	parsed, err := parseDate(value.Value)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+func (d Date) String() string {
+	return d.Format(dateLayout)
+}
+
+// Load reads a scenario from a .json, .yaml or .yml file.
+func Load(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var s Scenario
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &s)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &s)
+	default:
+		return nil, fmt.Errorf("unsupported scenario file %s, expected .json, .yaml or .yml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse scenario %s with err: %v", path, err)
+	}
+	return &s, s.validate()
+}
+
+func (s *Scenario) validate() error {
+	if len(s.Loan.ID) == 0 {
+		return fmt.Errorf("loan id is empty")
+	}
+	if s.Loan.Amount <= 0 || s.Loan.Weeks <= 0 {
+		return fmt.Errorf("loan amount and weeks must be positive")
+	}
+	if s.Loan.FlatInterestRate < 0 || s.Loan.FlatInterestRate > 1 {
+		return fmt.Errorf("flat interest rate %v must be a fraction, 0.1 for 10%%", s.Loan.FlatInterestRate)
+	}
+	for idx, step := range s.Steps {
+		switch step.Action {
+		case ActionPay, ActionMiss, ActionReverse, ActionRate:
+		default:
+			return fmt.Errorf("unknown action %q at step idx: %d", step.Action, idx)
+		}
+		// the periods due before a dated step are counted from the disbursement
+		if !step.Date.IsZero() && s.Loan.DisbursementDate.IsZero() {
+			return fmt.Errorf("step idx: %d has a date but the loan has no disbursement_date", idx)
+		}
+	}
+	return nil
+}
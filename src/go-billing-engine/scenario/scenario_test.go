@@ -0,0 +1,159 @@
+package scenario
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoadAndRun_YAML(t *testing.T) {
+	path := writeFile(t, "scenario.yaml", `
+loan:
+  id: "1001"
+  amount: 5000
+  flat_interest_rate: 0.1
+  weeks: 50
+  disbursement_date: 2024-01-01
+steps:
+  - action: pay
+    amount: 110
+  - date: 2024-01-29
+    action: pay
+    amount: 110
+  - action: reverse
+  - action: pay
+    amount: 20
+    expect_error: true
+assert:
+  outstanding: 5390
+  missed_payment: 2
+  delinquent: true
+`)
+	s, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01-01", s.Loan.DisbursementDate.String())
+
+	var out bytes.Buffer
+	result := Run(s, &out)
+	assert.True(t, result.Passed(), out.String())
+	assert.Equal(t, 3, result.Billing.PaymentRecord.Size())
+	assert.Contains(t, out.String(), "2024-01-15 miss (no step)")
+	assert.Contains(t, out.String(), "PASS")
+}
+
+func TestLoadAndRun_JSON(t *testing.T) {
+	path := writeFile(t, "scenario.json", `{
+  "loan": {"id": "1001", "amount": 5000, "flat_interest_rate": 0.1, "weeks": 50, "disbursement_date": "2024-01-01"},
+  "steps": [{"action": "miss"}, {"action": "pay", "amount": 110}],
+  "assert": {"outstanding": 5000, "remaining_weeks": 49}
+}`)
+	s, err := Load(path)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	result := Run(s, &out)
+	assert.False(t, result.Passed())
+	assert.Equal(t, []string{"outstanding is 5390.00, expected 5000.00"}, result.Failures)
+	assert.Contains(t, out.String(), "FAIL outstanding is 5390.00, expected 5000.00")
+}
+
+func TestLoadAndRun_Rate(t *testing.T) {
+	path := writeFile(t, "scenario.yaml", `
+loan:
+  id: "1001"
+  amount: 5000
+  flat_interest_rate: 0.1
+  variable_rate: true
+  weeks: 50
+  disbursement_date: 2024-01-01
+steps:
+  - action: pay
+    amount: 110
+  - action: pay
+    amount: 110
+  - action: rate
+    rate: 0.2
+  - action: pay
+    amount: 110
+    expect_error: true
+  - action: pay
+    amount: 120
+assert:
+  outstanding: 5640
+  remaining_weeks: 47
+`)
+	s, err := Load(path)
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	result := Run(s, &out)
+	assert.True(t, result.Passed(), out.String())
+	assert.Contains(t, out.String(), "step 3 rate 0.2")
+	assert.Equal(t, 0.1, result.Billing.RateAt(result.Billing.DueDate(2)))
+}
+
+func TestLoadAndRun_PaidOff(t *testing.T) {
+	path := writeFile(t, "scenario.yaml", `
+loan:
+  id: "1001"
+  amount: 1000
+  flat_interest_rate: 0.1
+  weeks: 2
+  disbursement_date: 2024-01-01
+steps:
+  - action: pay
+    amount: 550
+  - action: pay
+    amount: 550
+  - date: 2024-02-05
+    action: miss
+    expect_error: true
+assert:
+  outstanding: 0
+  remaining_weeks: 0
+`)
+	s, err := Load(path)
+	require.NoError(t, err)
+
+	// nothing is missed on the paid off billing before the dated step
+	var out bytes.Buffer
+	result := Run(s, &out)
+	assert.True(t, result.Passed(), out.String())
+	assert.Equal(t, 2, result.Billing.PaymentRecord.Size())
+	assert.NotContains(t, out.String(), "miss (no step)")
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	_, err := Load(writeFile(t, "scenario.txt", ""))
+	assert.Error(t, err)
+
+	_, err = Load(writeFile(t, "scenario.yaml", `
+loan: {id: "1001", amount: 5000, weeks: 50}
+steps: [{action: refund}]
+`))
+	assert.EqualError(t, err, `unknown action "refund" at step idx: 0`)
+
+	_, err = Load(writeFile(t, "scenario.yaml", `
+loan: {id: "1001", amount: 5000, flat_interest_rate: 10.0, weeks: 50}
+`))
+	assert.EqualError(t, err, "flat interest rate 10 must be a fraction, 0.1 for 10%")
+
+	_, err = Load(writeFile(t, "scenario.yaml", `
+loan: {id: "1001", amount: 5000, weeks: 50}
+steps: [{action: pay, date: 2024-01-08}]
+`))
+	assert.EqualError(t, err, "step idx: 0 has a date but the loan has no disbursement_date")
+
+	_, err = Load(writeFile(t, "scenario.json", `{"loan": {"id": "1001", "amount": 5000, "weeks": 50, "disbursement_date": "01/01/2024"}}`))
+	assert.Error(t, err)
+}
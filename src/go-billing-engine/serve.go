@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+
+	"gobillingengine/portfolio"
+	"gobillingengine/rpc"
+	"gobillingengine/rpc/billingpb"
+)
+
+func newServeCmd() *cobra.Command {
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the billing engine over gRPC",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			port, _ := cmd.Flags().GetInt("port")
+			path, _ := cmd.Flags().GetString("portfolio")
+
+			var store portfolio.Store = portfolio.NewMemoryStore()
+			if path != "" {
+				fileStore, err := portfolio.OpenFileStore(path)
+				if err != nil {
+					return err
+				}
+				store = fileStore
+			}
+			store, closeWebhook := withWebhook(cmd, store)
+			defer closeWebhook()
+
+			lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+			if err != nil {
+				return err
+			}
+
+			srv := grpc.NewServer()
+			billingpb.RegisterBillingServiceServer(srv, rpc.NewServer(store))
+
+			// stop gracefully on a signal, so that the queued webhook events are delivered before exiting
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+			go func() {
+				<-ctx.Done()
+				srv.GracefulStop()
+			}()
+
+			fmt.Printf("Starting gRPC server at %s...\n", lis.Addr())
+			return srv.Serve(lis)
+		},
+	}
+	serveCmd.Flags().IntP("port", "p", 50051, "The port to listen on")
+	serveCmd.Flags().String("portfolio", "", "Persist the billings to this file instead of keeping them in memory")
+	addWebhookFlags(serveCmd)
+	return serveCmd
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"gobillingengine/engine"
+	"gobillingengine/portfolio"
+)
+
+func newSettleCmd() *cobra.Command {
+	settleCmd := &cobra.Command{
+		Use:   "settle <loan_id>",
+		Short: "Agree, pay or break a settlement of a written off loan of the portfolio",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, _ := cmd.Flags().GetString("portfolio")
+			amount, _ := cmd.Flags().GetFloat64("amount")
+			installments, _ := cmd.Flags().GetInt("installments")
+			pay, _ := cmd.Flags().GetFloat64("pay")
+			broken, _ := cmd.Flags().GetBool("break")
+			date, err := getDate(cmd, "date")
+			if err != nil {
+				return err
+			}
+			modes := 0
+			for _, flag := range []string{"amount", "pay", "break"} {
+				if cmd.Flags().Changed(flag) {
+					modes++
+				}
+			}
+			if modes != 1 {
+				return errors.New("exactly one of --amount, --pay or --break is required")
+			}
+
+			store, err := portfolio.OpenFileStore(path)
+			if err != nil {
+				return err
+			}
+			b, err := store.Get(args[0])
+			if err != nil {
+				return fmt.Errorf("loanID:%s - %v", args[0], err)
+			}
+
+			var s *engine.Settlement
+			switch {
+			case broken:
+				s = b.OpenSettlement()
+				if err := b.BreakSettlement(date); err != nil {
+					return fmt.Errorf("cannot break the settlement of loanID:%s with err: %v", args[0], err)
+				}
+			case cmd.Flags().Changed("pay"):
+				s = b.OpenSettlement()
+				if err := b.PaySettlement(pay, date); err != nil {
+					return fmt.Errorf("cannot pay the settlement of loanID:%s with err: %v", args[0], err)
+				}
+			default:
+				deadline := date.AddDate(0, 0, 7*installments)
+				if cmd.Flags().Changed("deadline") {
+					if deadline, err = getDate(cmd, "deadline"); err != nil {
+						return err
+					}
+				}
+				if s, err = b.Settle(amount, installments, date, deadline); err != nil {
+					return fmt.Errorf("cannot settle loanID:%s with err: %v", args[0], err)
+				}
+			}
+			if err := store.Save(b); err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "loan %s settlement of %.2f (discount %.2f, %d x %.2f by %s): %s, %.2f paid, %.2f recovered\n",
+				args[0], s.Amount, s.Discount(), s.Installments, s.Installment(), s.Deadline.Format(dateLayout), s.Status,
+				s.Paid(), b.Recovered())
+			return nil
+		},
+	}
+	settleCmd.Flags().String("portfolio", "portfolio.json", "The persisted portfolio file")
+	settleCmd.Flags().Float64("amount", 0, "Agree a settlement of this amount")
+	settleCmd.Flags().Int("installments", 1, "The weekly installments of the agreed amount")
+	settleCmd.Flags().String("deadline", "", "The deadline of the agreed amount (YYYY-MM-DD), the due date of the last installment by default")
+	settleCmd.Flags().Float64("pay", 0, "Pay this amount under the open settlement")
+	settleCmd.Flags().Bool("break", false, "Mark the open settlement as broken")
+	settleCmd.Flags().String("date", "", "The date of the agreement, payment or break (YYYY-MM-DD), today by default")
+	return settleCmd
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"gobillingengine/scenario"
+)
+
+func newSimulateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "simulate <scenario.yaml|scenario.json>",
+		Short: "Replay a scenario file against a billing and check its final state",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := scenario.Load(args[0])
+			if err != nil {
+				return err
+			}
+
+			result := scenario.Run(s, cmd.OutOrStdout())
+			if !result.Passed() {
+				return fmt.Errorf("scenario failed with %d failure(s)", len(result.Failures))
+			}
+			return nil
+		},
+	}
+}
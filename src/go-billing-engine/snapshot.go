@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"gobillingengine/engine"
+	"gobillingengine/portfolio"
+	"gobillingengine/snapshot"
+)
+
+func newSnapshotCmd() *cobra.Command {
+	snapshotCmd := &cobra.Command{
+		Use:   "snapshot [<loan_id>]",
+		Short: "Export the snapshot of a billing of the portfolio, or restore one into it",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, _ := cmd.Flags().GetString("portfolio")
+			format, _ := cmd.Flags().GetString("format")
+			restore, _ := cmd.Flags().GetString("restore")
+			if format != "json" && format != "binary" {
+				return fmt.Errorf("invalid --format %q, expected json or binary", format)
+			}
+
+			store, err := portfolio.OpenFileStore(path)
+			if err != nil {
+				return err
+			}
+			if restore != "" {
+				return restoreSnapshot(cmd, store, restore)
+			}
+			if len(args) == 0 {
+				return errors.New("a loan_id or --restore is required")
+			}
+
+			b, err := store.Get(args[0])
+			if err != nil {
+				return fmt.Errorf("loanID:%s - %v", args[0], err)
+			}
+			var data []byte
+			if format == "binary" {
+				data, err = snapshot.MarshalBinary(b)
+			} else {
+				data, err = snapshot.MarshalJSON(b)
+			}
+			if err != nil {
+				return fmt.Errorf("cannot snapshot loanID:%s with err: %v", args[0], err)
+			}
+
+			w, closeOutput, err := getOutput(cmd)
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(data); err != nil {
+				closeOutput()
+				return err
+			}
+			return closeOutput()
+		},
+	}
+	snapshotCmd.Flags().String("portfolio", "portfolio.json", "The persisted portfolio file")
+	snapshotCmd.Flags().String("format", "json", "The snapshot format: json or binary")
+	snapshotCmd.Flags().String("restore", "", "A snapshot file, of any format and version, to restore into the portfolio")
+	snapshotCmd.Flags().StringP("output", "o", "", "Write the snapshot to a file instead of stdout")
+	return snapshotCmd
+}
+
+// restoreSnapshot saves the billing of a snapshot file in the portfolio, replacing the billing of the same loan.
+func restoreSnapshot(cmd *cobra.Command, store *portfolio.FileStore, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var b *engine.Billing
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		b, err = snapshot.UnmarshalJSON(data)
+	} else {
+		b, err = snapshot.UnmarshalBinary(data)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot restore snapshot %s with err: %v", path, err)
+	}
+
+	if _, err := store.Get(b.Loan.LoanID); err != nil {
+		err = store.Create(b)
+	} else {
+		err = store.Save(b)
+	}
+	if err != nil {
+		return fmt.Errorf("loanID:%s - %v", b.Loan.LoanID, err)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "loan %s restored: %d payments, outstanding %.2f\n", b.Loan.LoanID, len(b.Payments()), b.Outstanding)
+	return nil
+}
@@ -0,0 +1,365 @@
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"gobillingengine/engine"
+)
+
+// magic starts every binary snapshot. The version follows the magic as an unsigned varint and decides how the rest
+// is read.
+var magic = []byte("BSNP")
+
+// minBinaryVersion is the version the binary format was introduced with.
+const minBinaryVersion = 2
+
+// MarshalBinary encodes the snapshot of a billing in the compact binary format.
+func MarshalBinary(b *engine.Billing) ([]byte, error) {
+	return New(b).EncodeBinary()
+}
+
+// UnmarshalBinary decodes a binary snapshot into a billing.
+func UnmarshalBinary(data []byte) (*engine.Billing, error) {
+	r, err := DecodeBinary(data)
+	if err != nil {
+		return nil, err
+	}
+	return r.Billing(), nil
+}
+
+// EncodeBinary encodes the snapshot in the compact binary format: integers as varints, amounts as
+// float64 and times as unix seconds and nanoseconds in UTC.
+func (r *Record) EncodeBinary() ([]byte, error) {
+	return r.encodeBinary(Version)
+}
+
+// encodeBinary encodes the snapshot in the binary format of a version, the fields added after it are left out.
+func (r *Record) encodeBinary(version int) ([]byte, error) {
+	w := &writer{}
+	w.buf.Write(magic)
+	w.uint(version)
+
+	w.string(r.Loan.LoanID)
+	w.string(r.Loan.BorrowerID)
+	w.float(r.Loan.Amount)
+	w.float(r.Loan.FlatInterestRate)
+	w.int(r.Loan.Weeks)
+	w.time(r.Loan.DisbursementDate)
+	w.string(r.Loan.ProductCode)
+	w.int(r.Loan.DelinquentAfter)
+	w.uint(len(r.Loan.Fees))
+	for _, fee := range r.Loan.Fees {
+		w.string(fee.Code)
+		w.string(fee.Kind)
+		w.float(fee.Amount)
+	}
+	w.string(r.Loan.RefinancedLoanID)
+	w.float(r.Loan.PayoffAmount)
+	if version >= 3 {
+		w.string(r.Loan.Currency)
+	}
+	if version >= 4 {
+		w.bool(r.Loan.VariableRate)
+	}
+
+	w.float(r.State.PayableAmount)
+	w.float(r.State.Outstanding)
+	w.int(r.State.MissedPayment)
+	w.int(r.State.RemainingWeeks)
+	if version >= 4 {
+		w.uint(len(r.State.RateChanges))
+		for _, c := range r.State.RateChanges {
+			w.float(c.Rate)
+			w.time(c.EffectiveDate)
+		}
+	}
+
+	for _, entries := range [][]Entry{r.History.Payments, r.History.Penalties} {
+		w.uint(len(entries))
+		for _, e := range entries {
+			w.int(e.Week)
+			w.float(e.Amount)
+			w.time(e.Date)
+		}
+	}
+	if version >= 4 {
+		w.uint(len(r.History.Repricings))
+		for _, p := range r.History.Repricings {
+			w.int(p.Week)
+			w.time(p.Date)
+			w.time(p.EffectiveDate)
+			w.float(p.OldRate)
+			w.float(p.NewRate)
+			w.float(p.PayableAmount)
+			w.float(p.Outstanding)
+		}
+	}
+
+	if c := r.Closure; c == nil {
+		w.buf.WriteByte(0)
+	} else {
+		w.buf.WriteByte(1)
+		w.string(c.Reason)
+		w.time(c.Date)
+		w.string(c.LinkedLoanID)
+		w.float(c.Outstanding)
+		w.float(c.Payoff.Principal)
+		w.float(c.Payoff.Interest)
+		w.float(c.Payoff.Fees)
+		w.float(c.Payoff.Penalty)
+		w.float(c.Payoff.Amount)
+	}
+	if version >= 5 {
+		w.uint(len(r.Settlements))
+		for _, s := range r.Settlements {
+			w.time(s.Date)
+			w.float(s.Amount)
+			w.int(s.Installments)
+			w.time(s.Deadline)
+			w.float(s.Balance)
+			w.uint(len(s.Payments))
+			for _, p := range s.Payments {
+				w.float(p.Amount)
+				w.time(p.Date)
+			}
+			w.string(s.Status)
+			w.time(s.ClosedDate)
+		}
+	}
+	return w.buf.Bytes(), nil
+}
+
+// DecodeBinary decodes a binary snapshot of any version, migrated to the current one.
+func DecodeBinary(data []byte) (*Record, error) {
+	if !bytes.HasPrefix(data, magic) {
+		return nil, errors.New("not a binary billing snapshot")
+	}
+	rd := &reader{r: bufio.NewReader(bytes.NewReader(data[len(magic):]))}
+	version := rd.uint()
+	if rd.err != nil {
+		return nil, fmt.Errorf("invalid snapshot version: %v", rd.err)
+	}
+	if version < minBinaryVersion || version > Version {
+		return nil, fmt.Errorf("unsupported binary snapshot version %d, expected %d to %d", version, minBinaryVersion, Version)
+	}
+
+	r := &Record{Version: Version}
+	r.Loan.LoanID = rd.string()
+	r.Loan.BorrowerID = rd.string()
+	r.Loan.Amount = rd.float()
+	r.Loan.FlatInterestRate = rd.float()
+	r.Loan.Weeks = rd.int()
+	r.Loan.DisbursementDate = rd.time()
+	r.Loan.ProductCode = rd.string()
+	r.Loan.DelinquentAfter = rd.int()
+	for n := rd.uint(); n > 0 && rd.err == nil; n-- {
+		r.Loan.Fees = append(r.Loan.Fees, Fee{Code: rd.string(), Kind: rd.string(), Amount: rd.float()})
+	}
+	r.Loan.RefinancedLoanID = rd.string()
+	r.Loan.PayoffAmount = rd.float()
+	if version >= 3 {
+		r.Loan.Currency = rd.string()
+	}
+	if version >= 4 {
+		r.Loan.VariableRate = rd.byte() == 1
+	}
+
+	r.State.PayableAmount = rd.float()
+	r.State.Outstanding = rd.float()
+	r.State.MissedPayment = rd.int()
+	r.State.RemainingWeeks = rd.int()
+	if version >= 4 {
+		for n := rd.uint(); n > 0 && rd.err == nil; n-- {
+			r.State.RateChanges = append(r.State.RateChanges, RateChange{Rate: rd.float(), EffectiveDate: rd.time()})
+		}
+	}
+
+	r.History.Payments = []Entry{}
+	for _, entries := range []*[]Entry{&r.History.Payments, &r.History.Penalties} {
+		for n := rd.uint(); n > 0 && rd.err == nil; n-- {
+			*entries = append(*entries, Entry{Week: rd.int(), Amount: rd.float(), Date: rd.time()})
+		}
+	}
+	if version >= 4 {
+		for n := rd.uint(); n > 0 && rd.err == nil; n-- {
+			r.History.Repricings = append(r.History.Repricings, Repricing{
+				Week:          rd.int(),
+				Date:          rd.time(),
+				EffectiveDate: rd.time(),
+				OldRate:       rd.float(),
+				NewRate:       rd.float(),
+				PayableAmount: rd.float(),
+				Outstanding:   rd.float(),
+			})
+		}
+	}
+
+	if rd.byte() == 1 {
+		r.Closure = &Closure{
+			Reason:       rd.string(),
+			Date:         rd.time(),
+			LinkedLoanID: rd.string(),
+			Outstanding:  rd.float(),
+			Payoff: Payoff{
+				Principal: rd.float(),
+				Interest:  rd.float(),
+				Fees:      rd.float(),
+				Penalty:   rd.float(),
+				Amount:    rd.float(),
+			},
+		}
+	}
+	if version >= 5 {
+		for n := rd.uint(); n > 0 && rd.err == nil; n-- {
+			s := Settlement{
+				Date:         rd.time(),
+				Amount:       rd.float(),
+				Installments: rd.int(),
+				Deadline:     rd.time(),
+				Balance:      rd.float(),
+			}
+			for m := rd.uint(); m > 0 && rd.err == nil; m-- {
+				s.Payments = append(s.Payments, Entry{Amount: rd.float(), Date: rd.time()})
+			}
+			s.Status = rd.string()
+			s.ClosedDate = rd.time()
+			r.Settlements = append(r.Settlements, s)
+		}
+	}
+	if rd.err != nil {
+		return nil, fmt.Errorf("truncated binary snapshot: %v", rd.err)
+	}
+	if _, err := rd.r.ReadByte(); err != io.EOF {
+		return nil, errors.New("trailing data after binary snapshot")
+	}
+	return r, nil
+}
+
+type writer struct {
+	buf bytes.Buffer
+}
+
+func (w *writer) uint(v int) {
+	w.buf.Write(binary.AppendUvarint(nil, uint64(v)))
+}
+
+func (w *writer) int(v int) {
+	w.buf.Write(binary.AppendVarint(nil, int64(v)))
+}
+
+func (w *writer) float(v float64) {
+	w.buf.Write(binary.LittleEndian.AppendUint64(nil, math.Float64bits(v)))
+}
+
+func (w *writer) bool(v bool) {
+	if v {
+		w.buf.WriteByte(1)
+		return
+	}
+	w.buf.WriteByte(0)
+}
+
+func (w *writer) string(v string) {
+	w.uint(len(v))
+	w.buf.WriteString(v)
+}
+
+// time writes a zero time as a single 0 flag byte.
+func (w *writer) time(t time.Time) {
+	if t.IsZero() {
+		w.buf.WriteByte(0)
+		return
+	}
+	w.buf.WriteByte(1)
+	w.buf.Write(binary.AppendVarint(nil, t.Unix()))
+	w.uint(t.Nanosecond())
+}
+
+// reader reads the values of a binary snapshot, keeping the first error and returning zero values after it.
+type reader struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (rd *reader) byte() byte {
+	if rd.err != nil {
+		return 0
+	}
+	b, err := rd.r.ReadByte()
+	rd.fail(err)
+	return b
+}
+
+func (rd *reader) uint() int {
+	if rd.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(rd.r)
+	if err == nil && v > math.MaxInt32 {
+		err = fmt.Errorf("value %d out of range", v)
+	}
+	rd.fail(err)
+	return int(v)
+}
+
+func (rd *reader) int() int {
+	if rd.err != nil {
+		return 0
+	}
+	v, err := binary.ReadVarint(rd.r)
+	if err == nil && (v > math.MaxInt32 || v < math.MinInt32) {
+		err = fmt.Errorf("value %d out of range", v)
+	}
+	rd.fail(err)
+	return int(v)
+}
+
+func (rd *reader) float() float64 {
+	if rd.err != nil {
+		return 0
+	}
+	var b [8]byte
+	_, err := io.ReadFull(rd.r, b[:])
+	rd.fail(err)
+	return math.Float64frombits(binary.LittleEndian.Uint64(b[:]))
+}
+
+func (rd *reader) string() string {
+	n := rd.uint()
+	if rd.err != nil {
+		return ""
+	}
+	b := make([]byte, n)
+	_, err := io.ReadFull(rd.r, b)
+	rd.fail(err)
+	return string(b)
+}
+
+func (rd *reader) time() time.Time {
+	if rd.byte() == 0 || rd.err != nil {
+		return time.Time{}
+	}
+	sec, err := binary.ReadVarint(rd.r)
+	if err != nil {
+		rd.fail(err)
+		return time.Time{}
+	}
+	nsec := rd.uint()
+	return time.Unix(sec, int64(nsec)).UTC()
+}
+
+func (rd *reader) fail(err error) {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if rd.err == nil {
+		rd.err = err
+	}
+}
@@ -0,0 +1,69 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// migrations upgrade a JSON snapshot from the version of their key to the next one.
+var migrations = map[int]func(map[string]json.RawMessage) error{
+	1: migrateV1,
+	2: func(map[string]json.RawMessage) error { return nil }, // the loan currency of version 3 is optional
+	3: func(map[string]json.RawMessage) error { return nil }, // so are the rate changes of version 4
+	4: func(map[string]json.RawMessage) error { return nil }, // and the settlements of version 5
+}
+
+// migrate upgrades a JSON snapshot of any known version to the current one. A snapshot without version is
+// a version 1 snapshot.
+func migrate(data []byte) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	version := 1
+	if raw, ok := fields["version"]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return nil, fmt.Errorf("invalid snapshot version: %v", err)
+		}
+	}
+	if version == Version {
+		return data, nil
+	}
+	if version < 1 || version > Version {
+		return nil, fmt.Errorf("unsupported snapshot version %d, expected 1 to %d", version, Version)
+	}
+
+	for ; version < Version; version++ {
+		if err := migrations[version](fields); err != nil {
+			return nil, fmt.Errorf("cannot migrate snapshot from version %d with err: %v", version, err)
+		}
+		fields["version"] = json.RawMessage(fmt.Sprint(version + 1))
+	}
+	return json.Marshal(fields)
+}
+
+// migrateV1 groups the derived fields in state and the payments and penalties in history.
+func migrateV1(fields map[string]json.RawMessage) error {
+	if _, ok := fields["loan"]; !ok {
+		return fmt.Errorf("loan is missing")
+	}
+	group := func(name string, keys ...string) error {
+		grouped := map[string]json.RawMessage{}
+		for _, key := range keys {
+			if raw, ok := fields[key]; ok {
+				grouped[key] = raw
+				delete(fields, key)
+			}
+		}
+		raw, err := json.Marshal(grouped)
+		if err != nil {
+			return err
+		}
+		fields[name] = raw
+		return nil
+	}
+	if err := group("state", "payable_amount", "outstanding", "missed_payment", "remaining_weeks"); err != nil {
+		return err
+	}
+	return group("history", "payments", "penalties")
+}
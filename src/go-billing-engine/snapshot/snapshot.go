@@ -0,0 +1,287 @@
+// Package snapshot encodes the state of a billing, history included, in a versioned JSON or binary form.
+// Snapshots of older versions are migrated to the current one when decoded.
+package snapshot
+
+import (
+	"encoding/json"
+	"time"
+
+	lls "github.com/emirpasic/gods/stacks/linkedliststack"
+
+	"gobillingengine/engine"
+	"gobillingengine/model"
+)
+
+// Version of the snapshots written by this engine. Version 1 is the billing record of the first portfolio files,
+// without version field, version 2 groups the state and the history, version 3 adds the loan currency, version 4
+// the rate changes of variable-rate loans and version 5 the settlements of written off loans.
+const Version = 5
+
+// Record is the current version of a billing snapshot.
+type Record struct {
+	Version     int          `json:"version"`
+	Loan        Loan         `json:"loan"`
+	State       State        `json:"state"`
+	History     History      `json:"history"`
+	Closure     *Closure     `json:"closure,omitempty"`
+	Settlements []Settlement `json:"settlements,omitempty"`
+}
+
+type Loan struct {
+	LoanID           string    `json:"loan_id"`
+	BorrowerID       string    `json:"borrower_id,omitempty"`
+	Currency         string    `json:"currency,omitempty"`
+	Amount           float64   `json:"amount"`
+	FlatInterestRate float64   `json:"flat_interest_rate"`
+	VariableRate     bool      `json:"variable_rate,omitempty"`
+	Weeks            int       `json:"weeks"`
+	DisbursementDate time.Time `json:"disbursement_date"`
+	ProductCode      string    `json:"product_code,omitempty"`
+	DelinquentAfter  int       `json:"delinquent_after,omitempty"`
+	Fees             []Fee     `json:"fees,omitempty"`
+	RefinancedLoanID string    `json:"refinanced_loan_id,omitempty"`
+	PayoffAmount     float64   `json:"payoff_amount,omitempty"`
+}
+
+type Fee struct {
+	Code   string  `json:"code"`
+	Kind   string  `json:"kind"`
+	Amount float64 `json:"amount"`
+}
+
+// State holds the derived fields of the billing. They are stored as they are, not recomputed from the history,
+// so an inconsistent billing is loaded as it was saved.
+type State struct {
+	PayableAmount  float64      `json:"payable_amount"`
+	Outstanding    float64      `json:"outstanding"`
+	MissedPayment  int          `json:"missed_payment"`
+	RemainingWeeks int          `json:"remaining_weeks"`
+	RateChanges    []RateChange `json:"rate_changes,omitempty"` // rate changes not effective yet
+}
+
+type RateChange struct {
+	Rate          float64   `json:"rate"`
+	EffectiveDate time.Time `json:"effective_date"`
+}
+
+type History struct {
+	Payments   []Entry     `json:"payments"` // oldest first
+	Penalties  []Entry     `json:"penalties,omitempty"`
+	Repricings []Repricing `json:"repricings,omitempty"`
+}
+
+type Repricing struct {
+	Week          int       `json:"week"`
+	Date          time.Time `json:"date"`
+	EffectiveDate time.Time `json:"effective_date"`
+	OldRate       float64   `json:"old_rate"`
+	NewRate       float64   `json:"new_rate"`
+	PayableAmount float64   `json:"payable_amount"`
+	Outstanding   float64   `json:"outstanding"`
+}
+
+// Entry is a payment or a penalty.
+type Entry struct {
+	Week   int       `json:"week"`
+	Amount float64   `json:"amount"`
+	Date   time.Time `json:"date"`
+}
+
+type Closure struct {
+	Reason       string    `json:"reason"`
+	Date         time.Time `json:"date"`
+	LinkedLoanID string    `json:"linked_loan_id,omitempty"`
+	Outstanding  float64   `json:"outstanding"`
+	Payoff       Payoff    `json:"payoff"`
+}
+
+type Settlement struct {
+	Date         time.Time `json:"date"`
+	Amount       float64   `json:"amount"`
+	Installments int       `json:"installments"`
+	Deadline     time.Time `json:"deadline"`
+	Balance      float64   `json:"balance"`
+	Payments     []Entry   `json:"payments,omitempty"` // the week of the payments is left out
+	Status       string    `json:"status"`
+	ClosedDate   time.Time `json:"closed_date,omitempty"`
+}
+
+type Payoff struct {
+	Principal float64 `json:"principal"`
+	Interest  float64 `json:"interest"`
+	Fees      float64 `json:"fees"`
+	Penalty   float64 `json:"penalty"`
+	Amount    float64 `json:"amount"`
+}
+
+// New takes the snapshot of a billing.
+func New(b *engine.Billing) *Record {
+	r := &Record{
+		Version: Version,
+		Loan: Loan{
+			LoanID:           b.Loan.LoanID,
+			BorrowerID:       b.Loan.BorrowerID,
+			Currency:         b.Loan.Currency,
+			Amount:           b.Loan.Amount,
+			FlatInterestRate: b.Loan.FlatInterestRate,
+			VariableRate:     b.Loan.VariableRate,
+			Weeks:            b.Loan.Weeks,
+			DisbursementDate: b.Loan.DisbursementDate,
+			ProductCode:      b.Loan.ProductCode,
+			DelinquentAfter:  b.Loan.DelinquentAfter,
+			RefinancedLoanID: b.Loan.RefinancedLoanID,
+			PayoffAmount:     b.Loan.PayoffAmount,
+		},
+		State: State{
+			PayableAmount:  b.PayableAmount,
+			Outstanding:    b.Outstanding,
+			MissedPayment:  b.MissedPayment,
+			RemainingWeeks: b.RemainingWeeks,
+		},
+		History: History{Payments: []Entry{}},
+	}
+	for _, fee := range b.Loan.Fees {
+		r.Loan.Fees = append(r.Loan.Fees, Fee{Code: fee.Code, Kind: fee.Kind, Amount: fee.Amount})
+	}
+	for _, p := range b.Payments() {
+		r.History.Payments = append(r.History.Payments, Entry{Week: p.Week, Amount: p.Amount, Date: p.Date})
+	}
+	for _, p := range b.Penalties {
+		r.History.Penalties = append(r.History.Penalties, Entry{Week: p.Week, Amount: p.Amount, Date: p.Date})
+	}
+	for _, c := range b.RateChanges {
+		r.State.RateChanges = append(r.State.RateChanges, RateChange{Rate: c.Rate, EffectiveDate: c.EffectiveDate})
+	}
+	for _, p := range b.Repricings {
+		r.History.Repricings = append(r.History.Repricings, Repricing(*p))
+	}
+	if c := b.Closure; c != nil {
+		r.Closure = &Closure{
+			Reason:       c.Reason,
+			Date:         c.Date,
+			LinkedLoanID: c.LinkedLoanID,
+			Outstanding:  c.Outstanding,
+			Payoff: Payoff{
+				Principal: c.Payoff.Principal,
+				Interest:  c.Payoff.Interest,
+				Fees:      c.Payoff.Fees,
+				Penalty:   c.Payoff.Penalty,
+				Amount:    c.Payoff.Amount,
+			},
+		}
+	}
+	for _, s := range b.Settlements {
+		settlement := Settlement{
+			Date:         s.Date,
+			Amount:       s.Amount,
+			Installments: s.Installments,
+			Deadline:     s.Deadline,
+			Balance:      s.Balance,
+			Status:       s.Status,
+			ClosedDate:   s.ClosedDate,
+		}
+		for _, p := range s.Payments {
+			settlement.Payments = append(settlement.Payments, Entry{Amount: p.Amount, Date: p.Date})
+		}
+		r.Settlements = append(r.Settlements, settlement)
+	}
+	return r
+}
+
+// Billing restores the billing of the snapshot, without subscribers.
+func (r *Record) Billing() *engine.Billing {
+	loan := model.NewLoan(r.Loan.LoanID, r.Loan.Weeks, r.Loan.Amount, r.Loan.FlatInterestRate)
+	loan.BorrowerID = r.Loan.BorrowerID
+	loan.Currency = r.Loan.Currency
+	loan.VariableRate = r.Loan.VariableRate
+	loan.DisbursementDate = r.Loan.DisbursementDate
+	loan.ProductCode = r.Loan.ProductCode
+	loan.DelinquentAfter = r.Loan.DelinquentAfter
+	loan.RefinancedLoanID = r.Loan.RefinancedLoanID
+	loan.PayoffAmount = r.Loan.PayoffAmount
+	for _, fee := range r.Loan.Fees {
+		loan.Fees = append(loan.Fees, model.Fee{Code: fee.Code, Kind: fee.Kind, Amount: fee.Amount})
+	}
+
+	b := &engine.Billing{
+		Loan:           loan,
+		PayableAmount:  r.State.PayableAmount,
+		Outstanding:    r.State.Outstanding,
+		MissedPayment:  r.State.MissedPayment,
+		RemainingWeeks: r.State.RemainingWeeks,
+		PaymentRecord:  lls.New(),
+	}
+	for _, p := range r.History.Payments {
+		b.PaymentRecord.Push(&engine.Payment{Week: p.Week, Amount: p.Amount, Date: p.Date})
+	}
+	for _, p := range r.History.Penalties {
+		b.Penalties = append(b.Penalties, &engine.Penalty{Week: p.Week, Amount: p.Amount, Date: p.Date})
+	}
+	for _, c := range r.State.RateChanges {
+		b.RateChanges = append(b.RateChanges, &engine.RateChange{Rate: c.Rate, EffectiveDate: c.EffectiveDate})
+	}
+	for _, p := range r.History.Repricings {
+		repricing := engine.Repricing(p)
+		b.Repricings = append(b.Repricings, &repricing)
+	}
+	if c := r.Closure; c != nil {
+		b.Closure = &engine.Closure{
+			Reason:       c.Reason,
+			Date:         c.Date,
+			LinkedLoanID: c.LinkedLoanID,
+			Outstanding:  c.Outstanding,
+			Payoff: engine.Payoff{
+				LoanID:    loan.LoanID,
+				Date:      c.Date,
+				Principal: c.Payoff.Principal,
+				Interest:  c.Payoff.Interest,
+				Fees:      c.Payoff.Fees,
+				Penalty:   c.Payoff.Penalty,
+				Amount:    c.Payoff.Amount,
+			},
+		}
+	}
+	for _, s := range r.Settlements {
+		settlement := &engine.Settlement{
+			Date:         s.Date,
+			Amount:       s.Amount,
+			Installments: s.Installments,
+			Deadline:     s.Deadline,
+			Balance:      s.Balance,
+			Status:       s.Status,
+			ClosedDate:   s.ClosedDate,
+		}
+		for _, p := range s.Payments {
+			settlement.Payments = append(settlement.Payments, &engine.SettlementPayment{Amount: p.Amount, Date: p.Date})
+		}
+		b.Settlements = append(b.Settlements, settlement)
+	}
+	return b
+}
+
+// MarshalJSON encodes the snapshot of a billing as JSON.
+func MarshalJSON(b *engine.Billing) ([]byte, error) {
+	return json.Marshal(New(b))
+}
+
+// UnmarshalJSON decodes a JSON snapshot of any version into a billing.
+func UnmarshalJSON(data []byte) (*engine.Billing, error) {
+	r, err := DecodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	return r.Billing(), nil
+}
+
+// DecodeJSON decodes a JSON snapshot of any version, migrated to the current one.
+func DecodeJSON(data []byte) (*Record, error) {
+	data, err := migrate(data)
+	if err != nil {
+		return nil, err
+	}
+	var r Record
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
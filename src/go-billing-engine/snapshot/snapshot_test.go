@@ -0,0 +1,214 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gobillingengine/engine"
+	"gobillingengine/model"
+)
+
+func newTestBilling(t *testing.T) *engine.Billing {
+	loan := model.NewLoan("1001", 50, 5000, 0.1)
+	loan.DisbursementDate = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	loan.BorrowerID = "b-1"
+	loan.Currency = "IDR"
+	loan.ProductCode = "W50"
+	loan.DelinquentAfter = 3
+	loan.Fees = []model.Fee{{Code: "admin", Kind: model.FeeFinanced, Amount: 50}}
+	b := engine.NewBilling(loan)
+	require.NoError(t, b.MakePayment(b.PayableAmount))
+	require.NoError(t, b.MakePayment(0))
+	require.NoError(t, b.ChargePenalty(10))
+	return b
+}
+
+func assertSameBilling(t *testing.T, want, got *engine.Billing) {
+	assert.Equal(t, want.Loan, got.Loan)
+	assert.Equal(t, want.PayableAmount, got.PayableAmount)
+	assert.Equal(t, want.Outstanding, got.Outstanding)
+	assert.Equal(t, want.MissedPayment, got.MissedPayment)
+	assert.Equal(t, want.RemainingWeeks, got.RemainingWeeks)
+	assert.Equal(t, want.Payments(), got.Payments())
+	assert.Equal(t, want.Penalties, got.Penalties)
+	assert.Equal(t, want.Closure, got.Closure)
+}
+
+func TestJSON(t *testing.T) {
+	b := newTestBilling(t)
+
+	data, err := MarshalJSON(b)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"version":5`)
+
+	got, err := UnmarshalJSON(data)
+	require.NoError(t, err)
+	assertSameBilling(t, b, got)
+
+	// the restored billing keeps working as a billing
+	require.NoError(t, got.MakePayment(got.PayableAmount))
+	assert.Equal(t, 3, len(got.Payments()))
+}
+
+func TestBinary(t *testing.T) {
+	b := newTestBilling(t)
+	require.NoError(t, b.WriteOff(b.DueDate(3)))
+
+	data, err := MarshalBinary(b)
+	require.NoError(t, err)
+	jsonData, err := MarshalJSON(b)
+	require.NoError(t, err)
+	assert.Less(t, len(data), len(jsonData)/2)
+
+	got, err := UnmarshalBinary(data)
+	require.NoError(t, err)
+	assertSameBilling(t, b, got)
+	assert.True(t, got.IsWrittenOff())
+}
+
+func TestBinary_Invalid(t *testing.T) {
+	data, err := MarshalBinary(newTestBilling(t))
+	require.NoError(t, err)
+
+	_, err = UnmarshalBinary([]byte(`{"version":2}`))
+	assert.EqualError(t, err, "not a binary billing snapshot")
+
+	_, err = UnmarshalBinary(data[:len(data)-3])
+	assert.ErrorContains(t, err, "truncated binary snapshot")
+
+	_, err = UnmarshalBinary(append(data, 0))
+	assert.EqualError(t, err, "trailing data after binary snapshot")
+
+	future := append([]byte("BSNP"), 9)
+	_, err = UnmarshalBinary(future)
+	assert.EqualError(t, err, "unsupported binary snapshot version 9, expected 2 to 5")
+}
+
+// v1 is a billing as the portfolio files stored it before snapshots were versioned.
+const v1 = `{
+  "loan": {
+    "loan_id": "1001",
+    "borrower_id": "b-1",
+    "amount": 5000,
+    "flat_interest_rate": 0.1,
+    "weeks": 50,
+    "disbursement_date": "2024-01-01T00:00:00Z"
+  },
+  "payable_amount": 110,
+  "outstanding": 5390,
+  "missed_payment": 1,
+  "remaining_weeks": 49,
+  "payments": [
+    {"week": 1, "amount": 110, "date": "2024-01-08T00:00:00Z"},
+    {"week": 2, "amount": 0, "date": "2024-01-15T00:00:00Z"}
+  ],
+  "penalties": [
+    {"week": 2, "amount": 10, "date": "2024-01-08T00:00:00Z"}
+  ]
+}`
+
+func TestMigrate_V1(t *testing.T) {
+	r, err := DecodeJSON([]byte(v1))
+	require.NoError(t, err)
+	assert.Equal(t, Version, r.Version)
+	assert.Equal(t, State{PayableAmount: 110, Outstanding: 5390, MissedPayment: 1, RemainingWeeks: 49}, r.State)
+	assert.Equal(t, 2, len(r.History.Payments))
+	assert.Equal(t, 1, len(r.History.Penalties))
+
+	b := r.Billing()
+	assert.Equal(t, "b-1", b.Loan.BorrowerID)
+	assert.Equal(t, 10.0, b.GetPenalty())
+	require.NoError(t, b.MakePayment(b.PayableAmount))
+	assert.Equal(t, 5280.0, b.Outstanding)
+	assert.Equal(t, 0, b.MissedPayment)
+
+	// once migrated, the snapshot is written in the current version
+	data, err := json.Marshal(New(b))
+	require.NoError(t, err)
+	again, err := DecodeJSON(data)
+	require.NoError(t, err)
+	assert.Equal(t, 3, len(again.History.Payments))
+}
+
+func TestMigrate_Unsupported(t *testing.T) {
+	_, err := DecodeJSON([]byte(`{"version":6,"loan":{}}`))
+	assert.EqualError(t, err, "unsupported snapshot version 6, expected 1 to 5")
+
+	_, err = DecodeJSON([]byte(`{"outstanding":10}`))
+	assert.EqualError(t, err, "cannot migrate snapshot from version 1 with err: loan is missing")
+}
+
+func TestMigrate_V2(t *testing.T) {
+	b := newTestBilling(t)
+	b.Loan.Currency = ""
+	r := New(b)
+	r.Version = 2
+	data, err := json.Marshal(r)
+	require.NoError(t, err)
+	got, err := UnmarshalJSON(data)
+	require.NoError(t, err)
+	assertSameBilling(t, b, got)
+
+	// a version 2 binary snapshot has no currency
+	data, err = r.encodeBinary(2)
+	require.NoError(t, err)
+	decoded, err := DecodeBinary(data)
+	require.NoError(t, err)
+	assert.Equal(t, Version, decoded.Version)
+	assertSameBilling(t, b, decoded.Billing())
+}
+
+func TestRateChanges(t *testing.T) {
+	loan := model.NewLoan("1002", 50, 5000, 0.1)
+	loan.DisbursementDate = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	loan.VariableRate = true
+	b := engine.NewBilling(loan)
+	require.NoError(t, b.MakePayment(b.PayableAmount))
+	require.NoError(t, b.ChangeRate(0.12, time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)))
+	require.NoError(t, b.ChangeRate(0.15, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)))
+
+	for _, codec := range []struct {
+		marshal   func(*engine.Billing) ([]byte, error)
+		unmarshal func([]byte) (*engine.Billing, error)
+	}{{MarshalJSON, UnmarshalJSON}, {MarshalBinary, UnmarshalBinary}} {
+		data, err := codec.marshal(b)
+		require.NoError(t, err)
+		got, err := codec.unmarshal(data)
+		require.NoError(t, err)
+		assertSameBilling(t, b, got)
+		assert.Equal(t, b.Repricings, got.Repricings)
+		assert.Equal(t, b.RateChanges, got.RateChanges)
+		assert.Equal(t, 0.15, got.RateAt(time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)))
+	}
+}
+
+func TestSettlements(t *testing.T) {
+	loan := model.NewLoan("1003", 50, 5000, 0.1)
+	loan.DisbursementDate = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := engine.NewBilling(loan)
+	require.NoError(t, b.WriteOff(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)))
+	_, err := b.Settle(2000, 2, time.Date(2024, 2, 5, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.NoError(t, b.PaySettlement(1000, time.Date(2024, 2, 12, 0, 0, 0, 0, time.UTC)))
+	require.NoError(t, b.BreakSettlement(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)))
+	_, err = b.Settle(3000, 1, time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	for _, codec := range []struct {
+		marshal   func(*engine.Billing) ([]byte, error)
+		unmarshal func([]byte) (*engine.Billing, error)
+	}{{MarshalJSON, UnmarshalJSON}, {MarshalBinary, UnmarshalBinary}} {
+		data, err := codec.marshal(b)
+		require.NoError(t, err)
+		got, err := codec.unmarshal(data)
+		require.NoError(t, err)
+		assertSameBilling(t, b, got)
+		assert.Equal(t, b.Settlements, got.Settlements)
+		assert.Equal(t, 1000.0, got.Recovered())
+		assert.NotNil(t, got.OpenSettlement())
+	}
+}
@@ -0,0 +1,90 @@
+package statement
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+const dateLayout = "2006-01-02"
+
+// RenderText writes the statement as plain text with fixed-width columns.
+func (s *Statement) RenderText(w io.Writer) error {
+	_, err := io.WriteString(w, strings.Join(s.textLines(), "\n")+"\n")
+	return err
+}
+
+func (s *Statement) textLines() []string {
+	lines := []string{
+		fmt.Sprintf("Statement of loan %s", s.LoanID),
+		fmt.Sprintf("Period: %s - %s", s.From.Format(dateLayout), s.To.Format(dateLayout)),
+		"",
+		fmt.Sprintf("%-10s  %-28s %14s %14s %14s %14s", "Date", "Description", "Due", "Paid", "Penalty", "Balance"),
+	}
+	for _, l := range s.Lines {
+		lines = append(lines, fmt.Sprintf("%-10s  %-28s %14.2f %14.2f %14.2f %14.2f",
+			l.Date.Format(dateLayout), l.Description, l.Due, l.Paid, l.Penalty, l.Balance))
+	}
+	lines = append(lines,
+		"",
+		fmt.Sprintf("%-20s %14.2f", "Opening balance:", s.OpeningBalance),
+		fmt.Sprintf("%-20s %14.2f", "Installments due:", s.InstallmentsDue),
+		fmt.Sprintf("%-20s %14.2f", "Payments:", s.Payments),
+		fmt.Sprintf("%-20s %14.2f", "Penalties:", s.Penalties),
+		fmt.Sprintf("%-20s %14.2f", "Cleared:", s.Cleared),
+		fmt.Sprintf("%-20s %14.2f", "Closing balance:", s.ClosingBalance),
+		fmt.Sprintf("%-20s %s", "Status:", s.status()),
+	)
+	return lines
+}
+
+func (s *Statement) status() string {
+	if s.Delinquent {
+		return fmt.Sprintf("DELINQUENT (%d missed payments)", s.MissedPayment)
+	}
+	return "CURRENT"
+}
+
+var htmlTemplate = template.Must(template.New("statement").Funcs(template.FuncMap{
+	"date":   func(l Line) string { return l.Date.Format(dateLayout) },
+	"amount": func(v float64) string { return fmt.Sprintf("%.2f", v) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Statement of loan {{.LoanID}}</title></head>
+<body>
+<h1>Statement of loan {{.LoanID}}</h1>
+<p>Period: {{.From.Format "2006-01-02"}} - {{.To.Format "2006-01-02"}}</p>
+<table>
+<tr><th>Date</th><th>Description</th><th>Due</th><th>Paid</th><th>Penalty</th><th>Balance</th></tr>
+{{- range .Lines}}
+<tr><td>{{date .}}</td><td>{{.Description}}</td><td>{{amount .Due}}</td><td>{{amount .Paid}}</td><td>{{amount .Penalty}}</td><td>{{amount .Balance}}</td></tr>
+{{- end}}
+</table>
+<table>
+<tr><th>Opening balance</th><td>{{amount .OpeningBalance}}</td></tr>
+<tr><th>Installments due</th><td>{{amount .InstallmentsDue}}</td></tr>
+<tr><th>Payments</th><td>{{amount .Payments}}</td></tr>
+<tr><th>Penalties</th><td>{{amount .Penalties}}</td></tr>
+<tr><th>Cleared</th><td>{{amount .Cleared}}</td></tr>
+<tr><th>Closing balance</th><td>{{amount .ClosingBalance}}</td></tr>
+<tr><th>Status</th><td>{{.Status}}</td></tr>
+</table>
+</body>
+</html>
+`))
+
+// RenderHTML writes the statement as a standalone HTML page.
+func (s *Statement) RenderHTML(w io.Writer) error {
+	var buf bytes.Buffer
+	err := htmlTemplate.Execute(&buf, struct {
+		*Statement
+		Status string
+	}{s, s.status()})
+	if err != nil {
+		return err
+	}
+	_, err = buf.WriteTo(w)
+	return err
+}
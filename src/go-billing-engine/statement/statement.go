@@ -0,0 +1,189 @@
+// Package statement builds periodic borrower statements from the billing history.
+package statement
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gobillingengine/engine"
+)
+
+// Line is one entry of a statement, in date order.
+type Line struct {
+	Date        time.Time
+	Description string
+	Due         float64 // Due installment amount of the period
+	Paid        float64 // Paid amount in the period
+	Penalty     float64 // Penalty charged
+	Cleared     float64 // Cleared by the closure of the loan: the interest waived by a payoff or the written off amount
+	Balance     float64 // Balance after the line
+}
+
+// Statement summarises what was due, what was paid and what remains on a loan for a date range.
+type Statement struct {
+	LoanID          string
+	From            time.Time
+	To              time.Time
+	OpeningBalance  float64 // OpeningBalance is the balance right before From
+	InstallmentsDue float64
+	Payments        float64
+	Penalties       float64
+	Cleared         float64 // Cleared by the closure of the loan in the range
+	ClosingBalance  float64 // ClosingBalance is the balance at the end of To
+	MissedPayment   int     // MissedPayment number of continuous missed payments as of To
+	Delinquent      bool
+	Lines           []Line
+}
+
+// Generate builds the statement of the billing between from and to, both inclusive.
+// The balance covers the scheduled installments not paid yet plus the penalties charged. A closed loan gets a line on
+// its closure date that brings the balance to zero, and a written off one a line per settlement payment, which is paid
+// on the written off amount and leaves the balance cleared.
+func Generate(b *engine.Billing, from, to time.Time) (*Statement, error) {
+	if to.Before(from) {
+		return nil, errors.New("statement end date must not be before its start date")
+	}
+
+	st := &Statement{
+		LoanID: b.Loan.LoanID,
+		From:   from,
+		To:     to,
+	}
+
+	type entry struct {
+		date      time.Time
+		payment   *engine.Payment
+		penalty   *engine.Penalty
+		repricing *engine.Repricing
+		closure   *engine.Closure
+		recovery  *engine.SettlementPayment
+	}
+	// a repricing is listed before the payment of the period it starts from, the penalties charged in a period
+	// right after its payment, the closure after every other entry of its date
+	rank := func(e entry) int {
+		switch {
+		case e.repricing != nil:
+			return 0
+		case e.payment != nil:
+			return 1
+		case e.penalty != nil:
+			return 2
+		case e.closure != nil:
+			return 3
+		}
+		return 4
+	}
+	var entries []entry
+	for _, payment := range b.Payments() {
+		entries = append(entries, entry{date: payment.Date, payment: payment})
+	}
+	for _, penalty := range b.Penalties {
+		entries = append(entries, entry{date: penalty.Date, penalty: penalty})
+	}
+	for _, repricing := range b.Repricings {
+		entries = append(entries, entry{date: repricing.Date, repricing: repricing})
+	}
+	if b.IsClosed() {
+		entries = append(entries, entry{date: b.Closure.Date, closure: b.Closure})
+	}
+	for _, settlement := range b.Settlements {
+		for _, payment := range settlement.Payments {
+			entries = append(entries, entry{date: payment.Date, recovery: payment})
+		}
+	}
+	sort.SliceStable(entries, func(i, k int) bool {
+		if !entries[i].date.Equal(entries[k].date) {
+			return entries[i].date.Before(entries[k].date)
+		}
+		return rank(entries[i]) < rank(entries[k])
+	})
+
+	balance := b.ScheduledTotal()
+	for _, r := range b.Repricings {
+		balance -= r.Outstanding
+	}
+	for _, e := range entries {
+		if e.date.After(to) {
+			break
+		}
+		if e.date.Before(from) {
+			balance = applyEntry(balance, e.payment, e.penalty, e.repricing, e.closure)
+			continue
+		}
+		if len(st.Lines) == 0 {
+			st.OpeningBalance = balance
+		}
+		cleared := balance
+		balance = applyEntry(balance, e.payment, e.penalty, e.repricing, e.closure)
+
+		line := Line{Date: e.date, Balance: balance}
+		switch {
+		case e.closure != nil:
+			line.Description = "Written off"
+			line.Cleared = cleared
+			if e.closure.Reason == engine.ClosedRefinanced {
+				line.Description = fmt.Sprintf("Paid off by refinancing into loan %s", e.closure.LinkedLoanID)
+				line.Paid = e.closure.Payoff.Amount
+				line.Cleared = cleared - line.Paid
+				st.Payments += line.Paid
+			}
+			st.Cleared += line.Cleared
+			st.Lines = append(st.Lines, line)
+			continue
+		case e.recovery != nil:
+			line.Description = "Settlement payment"
+			line.Paid = e.recovery.Amount
+			st.Payments += line.Paid
+			st.Lines = append(st.Lines, line)
+			continue
+		}
+		if e.repricing != nil {
+			line.Description = fmt.Sprintf("Week %d on repriced from %.2f%% to %.2f%%, installment %.2f",
+				e.repricing.Week, e.repricing.OldRate*100, e.repricing.NewRate*100, e.repricing.PayableAmount)
+			st.Lines = append(st.Lines, line)
+			continue
+		}
+		if e.payment != nil {
+			line.Due = b.PayableAmountAt(e.payment.Date)
+			line.Paid = e.payment.Amount
+			line.Description = fmt.Sprintf("Week %d installment", e.payment.Week)
+			if e.payment.Amount == 0 {
+				line.Description = fmt.Sprintf("Week %d installment missed", e.payment.Week)
+			}
+			st.InstallmentsDue += line.Due
+			st.Payments += line.Paid
+		} else {
+			line.Penalty = e.penalty.Amount
+			line.Description = fmt.Sprintf("Week %d penalty", e.penalty.Week)
+			st.Penalties += line.Penalty
+		}
+		st.Lines = append(st.Lines, line)
+	}
+	if len(st.Lines) == 0 {
+		st.OpeningBalance = balance
+	}
+	st.ClosingBalance = balance
+	st.MissedPayment = b.MissedPaymentAsOf(to)
+	st.Delinquent = b.IsDelinquentAsOf(to)
+
+	return st, nil
+}
+
+func applyEntry(balance float64, payment *engine.Payment, penalty *engine.Penalty, repricing *engine.Repricing,
+	closure *engine.Closure) float64 {
+	if closure != nil {
+		return 0
+	}
+	if repricing != nil {
+		return balance + repricing.Outstanding
+	}
+	if payment != nil {
+		return balance - payment.Amount
+	}
+	if penalty != nil {
+		return balance + penalty.Amount
+	}
+	return balance
+}
@@ -0,0 +1,134 @@
+package statement
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gobillingengine/engine"
+	"gobillingengine/engine/enginetest"
+	"gobillingengine/model"
+)
+
+func newBilling(t *testing.T) *engine.Billing {
+	// weeks 1-2 paid (Jan 8, Jan 15), missed Jan 22 and Jan 29 with a penalty, paid Feb 5
+	billing := enginetest.NewBilling(t, enginetest.NewLoan("1001"), 110, 110, 0, 0)
+	require.NoError(t, billing.ChargePenalty(1))
+	require.NoError(t, billing.MakePayment(110))
+	return billing
+}
+
+func TestGenerate(t *testing.T) {
+	billing := newBilling(t)
+
+	st, err := Generate(billing, time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	assert.Equal(t, 5390.0, st.OpeningBalance)
+	assert.Equal(t, 330.0, st.InstallmentsDue)
+	assert.Equal(t, 110.0, st.Payments)
+	assert.Equal(t, 1.0, st.Penalties)
+	assert.Equal(t, 5281.0, st.ClosingBalance)
+	assert.Equal(t, 2, st.MissedPayment)
+	assert.True(t, st.Delinquent)
+	assert.Len(t, st.Lines, 4)
+	assert.Equal(t, "Week 3 penalty", st.Lines[3].Description)
+
+	// the delinquency is cured by the next payment
+	st, err = Generate(billing, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, 5281.0, st.OpeningBalance)
+	assert.Equal(t, 5171.0, st.ClosingBalance)
+	assert.False(t, st.Delinquent)
+
+	// a range without activity carries the balance over
+	st, err = Generate(billing, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Empty(t, st.Lines)
+	assert.Equal(t, 5171.0, st.OpeningBalance)
+	assert.Equal(t, 5171.0, st.ClosingBalance)
+
+	_, err = Generate(billing, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.Error(t, err)
+}
+
+func TestGenerate_Repriced(t *testing.T) {
+	loan := model.NewLoan("1001", 50, 5000, 0.1)
+	loan.DisbursementDate = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	loan.VariableRate = true
+	billing := engine.NewBilling(loan)
+	require.NoError(t, billing.MakePayment(110))
+	require.NoError(t, billing.ChangeRate(0.2, billing.DueDate(2)))
+	require.NoError(t, billing.MakePayment(120))
+
+	st, err := Generate(billing, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, 5500.0, st.OpeningBalance)
+	assert.Equal(t, 230.0, st.InstallmentsDue)
+	assert.Equal(t, 5760.0, st.ClosingBalance)
+	require.Len(t, st.Lines, 3)
+	assert.Equal(t, Line{Date: billing.DueDate(2), Description: "Week 2 on repriced from 10.00% to 20.00%, installment 120.00",
+		Balance: 5880}, st.Lines[1])
+}
+
+func TestGenerate_Refinanced(t *testing.T) {
+	billing := newBilling(t)
+	date := time.Date(2024, 2, 6, 0, 0, 0, 0, time.UTC)
+	_, err := engine.Refinance(billing, model.NewLoan("1002", 50, 10000, 0.1), date)
+	require.NoError(t, err)
+
+	st, err := Generate(billing, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Len(t, st.Lines, 2)
+	payoff := billing.Closure.Payoff.Amount
+	assert.Equal(t, Line{Date: date, Description: "Paid off by refinancing into loan 1002", Paid: payoff,
+		Cleared: 5171 - payoff}, st.Lines[1])
+	assert.Equal(t, 110+payoff, st.Payments)
+	assert.Equal(t, 5171-payoff, st.Cleared)
+	assert.Zero(t, st.ClosingBalance)
+}
+
+func TestGenerate_WrittenOff(t *testing.T) {
+	billing := newBilling(t)
+	require.NoError(t, billing.WriteOff(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)))
+	_, err := billing.Settle(2000, 2, time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.NoError(t, billing.PaySettlement(1000, time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)))
+
+	st, err := Generate(billing, time.Date(2024, 2, 6, 0, 0, 0, 0, time.UTC), time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, 5171.0, st.OpeningBalance)
+	require.Len(t, st.Lines, 2)
+	assert.Equal(t, Line{Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Description: "Written off", Cleared: 5171},
+		st.Lines[0])
+	assert.Equal(t, Line{Date: time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC), Description: "Settlement payment", Paid: 1000},
+		st.Lines[1])
+	assert.Equal(t, 1000.0, st.Payments)
+	assert.Equal(t, 5171.0, st.Cleared)
+	assert.Zero(t, st.ClosingBalance)
+}
+
+func TestRender(t *testing.T) {
+	st, err := Generate(newBilling(t), time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	var text bytes.Buffer
+	require.NoError(t, st.RenderText(&text))
+	assert.Contains(t, text.String(), "Statement of loan 1001")
+	assert.Contains(t, text.String(), "Week 3 installment missed")
+	assert.Contains(t, text.String(), "DELINQUENT (2 missed payments)")
+
+	var html bytes.Buffer
+	require.NoError(t, st.RenderHTML(&html))
+	assert.Contains(t, html.String(), "<td>Week 1 installment</td>")
+	assert.Contains(t, html.String(), "<td>5281.00</td>")
+
+	var pdf bytes.Buffer
+	require.NoError(t, st.RenderPDF(&pdf))
+	assert.True(t, bytes.HasPrefix(pdf.Bytes(), []byte("%PDF-1.4")))
+	assert.True(t, bytes.HasSuffix(pdf.Bytes(), []byte("%%EOF\n")))
+	assert.Contains(t, pdf.String(), "(Statement of loan 1001) Tj")
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"gobillingengine/engine"
+	"gobillingengine/notify"
+	"gobillingengine/portfolio"
+)
+
+// webhookDrainTimeout bounds the delivery of the queued events when a command ends.
+const webhookDrainTimeout = 30 * time.Second
+
+// addWebhookFlags adds the flags delivering the billing events of a command to a webhook.
+func addWebhookFlags(cmd *cobra.Command) {
+	cmd.Flags().String("webhook", "", "Post the billing events to this URL, none if empty")
+	cmd.Flags().String("webhook-secret", "", "The secret signing the webhook payloads, $BILLING_WEBHOOK_SECRET by default")
+}
+
+// withWebhook subscribes the billings of the store to the --webhook URL, if any. The returned function delivers the
+// events still queued and stops the dispatcher, it must be called once the command is done with the billings.
+func withWebhook(cmd *cobra.Command, store portfolio.Store) (portfolio.Store, func()) {
+	url, _ := cmd.Flags().GetString("webhook")
+	if url == "" {
+		return store, func() {}
+	}
+	secret, _ := cmd.Flags().GetString("webhook-secret")
+	if secret == "" {
+		secret = os.Getenv("BILLING_WEBHOOK_SECRET")
+	}
+
+	dispatcher := notify.NewWebhookDispatcher(notify.WebhookConfig{URL: url, Secret: secret})
+	closeWebhook := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), webhookDrainTimeout)
+		defer cancel()
+		if err := dispatcher.Close(ctx); err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "cannot deliver every webhook event with err: %v\n", err)
+		}
+	}
+	return &portfolio.SubscribedStore{Store: store, Subscribers: []engine.Subscriber{dispatcher}}, closeWebhook
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"gobillingengine/collection"
+	"gobillingengine/portfolio"
+)
+
+func newWorklistCmd() *cobra.Command {
+	worklistCmd := &cobra.Command{
+		Use:   "worklist",
+		Short: "Generate the collections worklist of the field officers",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, _ := cmd.Flags().GetString("portfolio")
+			assignmentsPath, _ := cmd.Flags().GetString("assignments")
+			dueWithin, _ := cmd.Flags().GetInt("due-within")
+			format, _ := cmd.Flags().GetString("format")
+
+			asOf := today()
+
+			store, err := portfolio.OpenFileStore(path)
+			if err != nil {
+				return err
+			}
+			billings, err := store.List()
+			if err != nil {
+				return err
+			}
+
+			opts := collection.Options{
+				AsOf:      asOf,
+				DueWithin: time.Duration(dueWithin) * 24 * time.Hour,
+			}
+			if assignmentsPath != "" {
+				f, err := os.Open(assignmentsPath)
+				if err != nil {
+					return err
+				}
+				opts.Assignments, err = collection.LoadAssignments(f)
+				f.Close()
+				if err != nil {
+					return fmt.Errorf("cannot load assignments with err: %v", err)
+				}
+			}
+
+			out, closeOut, err := getOutput(cmd)
+			if err != nil {
+				return err
+			}
+			worklist := collection.Generate(billings, opts)
+			switch format {
+			case "csv":
+				err = worklist.WriteCSV(out)
+			case "json":
+				err = worklist.WriteJSON(out)
+			default:
+				err = fmt.Errorf("unknown format %q, expected csv or json", format)
+			}
+			if errClose := closeOut(); err == nil {
+				err = errClose
+			}
+			return err
+		},
+	}
+	worklistCmd.Flags().String("portfolio", "portfolio.json", "The persisted portfolio file")
+	worklistCmd.Flags().String("assignments", "", "CSV of loan_id,officer,area assigning loans to field officers")
+	worklistCmd.Flags().Int("due-within", 2, "Also list loans due within this many days")
+	worklistCmd.Flags().String("format", "csv", "The output format, csv or json")
+	worklistCmd.Flags().StringP("output", "o", "", "Write the worklist to this file instead of stdout")
+	return worklistCmd
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"gobillingengine/portfolio"
+)
+
+func newWriteOffCmd() *cobra.Command {
+	writeOffCmd := &cobra.Command{
+		Use:   "writeoff <loan_id>",
+		Short: "Write off a loan of the portfolio as a loss",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, _ := cmd.Flags().GetString("portfolio")
+			date, err := getDate(cmd, "date")
+			if err != nil {
+				return err
+			}
+
+			store, err := portfolio.OpenFileStore(path)
+			if err != nil {
+				return err
+			}
+			b, err := store.Get(args[0])
+			if err != nil {
+				return fmt.Errorf("loanID:%s - %v", args[0], err)
+			}
+			if err := b.WriteOff(date); err != nil {
+				return fmt.Errorf("cannot write off loanID:%s with err: %v", args[0], err)
+			}
+			if err := store.Save(b); err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "loan %s written off on %s: %.2f\n", args[0], date.Format(dateLayout), b.Closure.Outstanding)
+			return nil
+		},
+	}
+	writeOffCmd.Flags().String("portfolio", "portfolio.json", "The persisted portfolio file")
+	writeOffCmd.Flags().String("date", "", "The date of the write-off (YYYY-MM-DD), today by default")
+	return writeOffCmd
+}
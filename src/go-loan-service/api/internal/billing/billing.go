@@ -0,0 +1,42 @@
+package billing
+
+import (
+	"context"
+	"gobillingengine/contract"
+	types "goloanservice/api/internal/type"
+)
+
+// IBilling starts repayment tracking in the billing engine once a loan is disbursed.
+type IBilling interface {
+	StartBilling(ctx context.Context, loan *types.Loan, disbursement *types.DisbursementRequest) (*contract.ProvisionedBilling, error)
+}
+
+// LocalBilling hands disbursed loans to a billing engine provisioner, linked by loan ID.
+type LocalBilling struct {
+	Provisioner contract.BillingProvisioner
+	TenorWeeks  int
+}
+
+func NewLocalBilling(provisioner contract.BillingProvisioner, tenorWeeks int) IBilling {
+	return &LocalBilling{
+		Provisioner: provisioner,
+		TenorWeeks:  tenorWeeks,
+	}
+}
+
+func (lb *LocalBilling) StartBilling(ctx context.Context, loan *types.Loan, disbursement *types.DisbursementRequest) (*contract.ProvisionedBilling, error) {
+	investors := make([]contract.Investor, len(loan.Investors))
+	for i, investor := range loan.Investors {
+		investors[i] = contract.Investor{InvestorID: investor.InvestorID, Amount: investor.Amount, Currency: investor.Currency}
+	}
+	return lb.Provisioner.ProvisionBilling(ctx, &contract.DisbursedLoan{
+		LoanID:           loan.LoanID,
+		BorrowerID:       loan.BorrowerID,
+		Currency:         loan.Currency,
+		PrincipalAmount:  loan.PrincipalAmount,
+		Rate:             loan.Rate,
+		TenorWeeks:       lb.TenorWeeks,
+		DisbursementDate: disbursement.DisbursementDate,
+		Investors:        investors,
+	})
+}
@@ -0,0 +1,75 @@
+package billing_test
+
+import (
+	"context"
+	"gobillingengine/contract"
+	"gobillingengine/distribution"
+	"gobillingengine/portfolio"
+	"goloanservice/api/internal/billing"
+	types "goloanservice/api/internal/type"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalBilling_StartBilling(t *testing.T) {
+	store := portfolio.NewMemoryStore()
+	localBilling := billing.NewLocalBilling(contract.NewLocalProvisioner(store), 50)
+
+	disbursementDate := time.Date(2024, 5, 11, 12, 5, 0, 0, time.UTC)
+	loan := &types.Loan{
+		LoanID:          "eccae2a6-9d88-4f08-82be-a80ab235a7e7",
+		BorrowerID:      "borrower_id",
+		PrincipalAmount: 1000000,
+		Rate:            0.055,
+	}
+	disbursement := &types.DisbursementRequest{
+		LoanID:           loan.LoanID,
+		DisbursementDate: disbursementDate,
+	}
+
+	provisioned, err := localBilling.StartBilling(context.Background(), loan, disbursement)
+	assert.NoError(t, err)
+	assert.Equal(t, loan.PrincipalAmount, provisioned.NetDisbursedAmount)
+
+	// The billing is linked to the loan by its loan ID
+	b, err := store.Get(loan.LoanID)
+	assert.NoError(t, err)
+	assert.Equal(t, 50, b.Loan.Weeks)
+	assert.Equal(t, loan.BorrowerID, b.Loan.BorrowerID)
+	assert.Equal(t, loan.PrincipalAmount, b.Loan.Amount)
+	assert.Equal(t, loan.Rate, b.Loan.FlatInterestRate)
+	assert.Equal(t, disbursementDate, b.Loan.DisbursementDate)
+}
+
+func TestLocalBilling_StartBilling_Investors(t *testing.T) {
+	store := portfolio.NewMemoryStore()
+	ledger := distribution.NewLedger(0.1)
+	localBilling := billing.NewLocalBilling(&contract.LocalProvisioner{Store: store, Ledger: ledger}, 50)
+
+	loan := &types.Loan{
+		LoanID:          "eccae2a6-9d88-4f08-82be-a80ab235a7e7",
+		PrincipalAmount: 1000000,
+		Rate:            0.055,
+		Investors: []*types.Investor{
+			{InvestorID: "investor1", Amount: 750000},
+			{InvestorID: "investor2", Amount: 250000},
+		},
+	}
+	disbursement := &types.DisbursementRequest{
+		LoanID:           loan.LoanID,
+		DisbursementDate: time.Date(2024, 5, 11, 12, 5, 0, 0, time.UTC),
+	}
+
+	_, err := localBilling.StartBilling(context.Background(), loan, disbursement)
+	assert.NoError(t, err)
+
+	// The investors are repaid pro rata to their investment
+	d, ok := ledger.Get(loan.LoanID)
+	assert.True(t, ok)
+	accounts := d.Accounts()
+	assert.Len(t, accounts, 2)
+	assert.Equal(t, "investor2", accounts[1].InvestorID)
+	assert.Equal(t, 0.25, accounts[1].Share)
+}
@@ -7,4 +7,6 @@ type Config struct {
 	ApprovalInfoTableName string
 	InvestmentsTableName  string
 	DisbursementTableName string
+	BillingTenorWeeks     int     `json:",default=50"`
+	PlatformFeeRate       float64 `json:",optional"` // PlatformFeeRate is the share of the interest kept by the platform
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	log "github.com/sirupsen/logrus"
 	"goloanservice/api/internal/svc"
 	types "goloanservice/api/internal/type"
 	"goloanservice/util"
@@ -46,9 +47,27 @@ func (dl *DisbursementLogic) Disburse(req *types.DisbursementRequest) (resp *typ
 		return nil, err
 	}
 
+	// Start repayment tracking in the billing engine.
+	//	The money is already handed to the borrower at this point, so a billing failure must not fail the disbursement;
+	//	it is reported back and logged so the billing can be provisioned again for the same loan ID.
+	//	The investors are repaid from the billing, pro rata to their investment.
+	isBillingStarted := true
+	var netDisbursedAmount float64
+	if loan.Investors, err = dl.svcCtx.LoanInvestmentModel.FindInvestors(dl.ctx, loan.LoanID); err != nil {
+		log.Errorf("loanID:%s - cannot find investors with err: %v", loan.LoanID, err)
+		isBillingStarted = false
+	} else if provisioned, err := dl.svcCtx.Billing.StartBilling(dl.ctx, loan, req); err != nil {
+		log.Errorf("loanID:%s - cannot start billing with err: %v", loan.LoanID, err)
+		isBillingStarted = false
+	} else {
+		netDisbursedAmount = provisioned.NetDisbursedAmount
+	}
+
 	return &types.DisbursementResponse{
 		DisbursementRequest: *req,
 		IsDisbursed:         true,
+		IsBillingStarted:    isBillingStarted,
+		NetDisbursedAmount:  netDisbursedAmount,
 	}, nil
 }
 
@@ -2,6 +2,8 @@ package logic_test
 
 import (
 	"context"
+	"errors"
+	"gobillingengine/contract"
 	"goloanservice/api/internal/logic"
 	"goloanservice/api/internal/svc"
 	"goloanservice/api/internal/type"
@@ -38,6 +40,18 @@ func (m *MockLoanDisbursementModel) TxDisbursement(ctx context.Context, loan *ty
 	return args.Error(0)
 }
 
+// MockBilling is a mock implementation of the billing.IBilling interface
+type MockBilling struct {
+	mock.Mock
+}
+
+// StartBilling mocks the StartBilling method of billing.IBilling
+func (m *MockBilling) StartBilling(ctx context.Context, loan *types.Loan, req *types.DisbursementRequest) (*contract.ProvisionedBilling, error) {
+	args := m.Called(ctx, loan, req)
+	provisioned, _ := args.Get(0).(*contract.ProvisionedBilling)
+	return provisioned, args.Error(1)
+}
+
 func TestDisbursementLogic_Disburse(t *testing.T) {
 	// Create a context
 	ctx := context.Background()
@@ -59,10 +73,26 @@ func TestDisbursementLogic_Disburse(t *testing.T) {
 	// Set up expectation for the TxDisbursement method
 	mockLoanDisbursementModel.On("TxDisbursement", ctx, mock.Anything, mock.Anything).Return(nil)
 
-	// Create an instance of DisbursementLogic with the mock loan model and mock loan disbursement model
+	// Create a mock loan investment model returning the investors of the loan
+	mockLoanInvestmentModel := &MockLoanInvestmentModel{}
+	investors := []*types.Investor{{InvestorID: "investor1", Amount: 1000}}
+	mockLoanInvestmentModel.On("FindInvestors", ctx, "test_loan_id").Return(investors, nil)
+
+	// Create a mock billing
+	mockBilling := &MockBilling{}
+
+	// Set up expectation for the StartBilling method
+	mockBilling.On("StartBilling", ctx, mock.Anything, mock.Anything).Return(&contract.ProvisionedBilling{
+		LoanID:             "test_loan_id",
+		NetDisbursedAmount: 990,
+	}, nil)
+
+	// Create an instance of DisbursementLogic with the mock loan model, mock loan disbursement model and mock billing
 	disbursementLogic := logic.NewDisbursementLogic(ctx, &svc.ServiceContext{
 		LoanModel:             mockLoanModel,
+		LoanInvestmentModel:   mockLoanInvestmentModel,
 		LoanDisbursementModel: mockLoanDisbursementModel,
+		Billing:               mockBilling,
 	})
 
 	// Create a valid disbursement request
@@ -91,6 +121,59 @@ func TestDisbursementLogic_Disburse(t *testing.T) {
 
 	// Assert that the loan's state has been updated to "disbursed"
 	assert.True(t, response.IsDisbursed)
+
+	// Assert that the billing has been started for the disbursed loan
+	assert.True(t, response.IsBillingStarted)
+	assert.Equal(t, 990.0, response.NetDisbursedAmount)
+	mockBilling.AssertCalled(t, "StartBilling", ctx, mock.MatchedBy(func(loan *types.Loan) bool {
+		return loan.LoanID == "test_loan_id" && len(loan.Investors) == 1
+	}), request)
+}
+
+func TestDisbursementLogic_Disburse_BillingFailure(t *testing.T) {
+	// Create a context
+	ctx := context.Background()
+
+	// Create a mock loan model
+	mockLoanModel := &MockLoanModelForDisburse{}
+	mockLoanModel.On("FindOne", ctx, "test_loan_id").Return(&types.Loan{
+		LoanID:    "test_loan_id",
+		CreatedAt: time.Now().Add(-24 * time.Hour),
+		State:     "invested",
+	}, nil)
+
+	// Create a mock loan disbursement model
+	mockLoanDisbursementModel := &MockLoanDisbursementModel{}
+	mockLoanDisbursementModel.On("TxDisbursement", ctx, mock.Anything, mock.Anything).Return(nil)
+
+	mockLoanInvestmentModel := &MockLoanInvestmentModel{}
+	mockLoanInvestmentModel.On("FindInvestors", ctx, "test_loan_id").Return([]*types.Investor{}, nil)
+
+	// Create a mock billing which fails to start
+	mockBilling := &MockBilling{}
+	mockBilling.On("StartBilling", ctx, mock.Anything, mock.Anything).Return(nil, errors.New("billing engine unavailable"))
+
+	disbursementLogic := logic.NewDisbursementLogic(ctx, &svc.ServiceContext{
+		LoanModel:             mockLoanModel,
+		LoanInvestmentModel:   mockLoanInvestmentModel,
+		LoanDisbursementModel: mockLoanDisbursementModel,
+		Billing:               mockBilling,
+	})
+
+	request := &types.DisbursementRequest{
+		LoanID:                "test_loan_id",
+		DisbursementDate:      time.Now(),
+		SignedAgreementLetter: "https://example.com/agreement.pdf",
+		FieldOfficerID:        "officer123",
+	}
+
+	// Call the Disburse method
+	response, err := disbursementLogic.Disburse(request)
+
+	// Assert that the disbursement still succeeds but reports the billing was not started
+	assert.NoError(t, err)
+	assert.True(t, response.IsDisbursed)
+	assert.False(t, response.IsBillingStarted)
 }
 
 func TestDisbursementLogic_Disburse_InvalidRequest(t *testing.T) {
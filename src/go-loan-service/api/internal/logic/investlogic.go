@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	log "github.com/sirupsen/logrus"
+	"gobillingengine/money"
 	"goloanservice/api/internal/svc"
 	types "goloanservice/api/internal/type"
 )
@@ -28,15 +29,19 @@ func (il *InvestLogic) Invest(req *types.InvestLoanRequest) (resp *types.InvestL
 		return nil, err
 	}
 
-	// Step 2: Calculate the total invested amount
+	// Step 2: Calculate the total invested amount, in the loan currency
 	loan, err := il.svcCtx.LoanModel.FindOne(il.ctx, req.LoanID)
 	if err != nil {
 		return nil, err
 	}
+	if err := validateInvestmentCurrency(loan, req); err != nil {
+		return nil, err
+	}
 	totalInvested := 0.0
 	for _, investor := range req.Investors {
 		totalInvested += investor.Amount
 	}
+	totalInvested = money.Round(totalInvested, loan.Currency)
 
 	// Ensure the total invested amount does not exceed the loan principal amount
 	// 	ASSUMPTION:
@@ -78,6 +83,20 @@ func sendEmail(loan *types.Loan, investments *types.InvestLoanRequest) {
 	}
 }
 
+// validateInvestmentCurrency checks the investments are in the loan currency, with no more decimals than it has.
+func validateInvestmentCurrency(loan *types.Loan, req *types.InvestLoanRequest) error {
+	for idx, investor := range req.Investors {
+		if investor.Currency != "" && investor.Currency != loan.Currency {
+			return fmt.Errorf("%w: investment in %s at idx: %d on a loan in %s",
+				money.ErrCurrencyMismatch, investor.Currency, idx, loan.Currency)
+		}
+		if money.Round(investor.Amount, loan.Currency) != investor.Amount {
+			return fmt.Errorf("investor amount %v is finer than the minor unit of %s at idx: %d", investor.Amount, loan.Currency, idx)
+		}
+	}
+	return nil
+}
+
 func validateInvestmentRequest(req *types.InvestLoanRequest) error {
 	for idx, investor := range req.Investors {
 		if len(investor.InvestorID) == 0 {
@@ -9,6 +9,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"gobillingengine/money"
 )
 
 // MockLoanModel is a mock implementation of the LoanModel interface
@@ -37,6 +38,12 @@ func (m *MockLoanInvestmentModel) TxInvestment(ctx context.Context, loan *types.
 	return args.Error(0)
 }
 
+// FindInvestors mocks the FindInvestors method of LoanInvestmentModel
+func (m *MockLoanInvestmentModel) FindInvestors(ctx context.Context, loanID string) ([]*types.Investor, error) {
+	args := m.Called(ctx, loanID)
+	return args.Get(0).([]*types.Investor), args.Error(1)
+}
+
 func TestInvestLogic_Invest(t *testing.T) {
 	// Create a context
 	ctx := context.Background()
@@ -85,3 +92,53 @@ func TestInvestLogic_Invest(t *testing.T) {
 	// Assert that the loan's invested amount has been updated
 	assert.Equal(t, 1000.0, response.Investors[0].Amount+response.Investors[1].Amount)
 }
+
+func TestInvestLogic_Invest_Currency(t *testing.T) {
+	ctx := context.Background()
+
+	// Mock a dollar loan waiting for investments
+	mockLoanModel := &mockLoanModel{}
+	mockLoanModel.On("FindOne", ctx, "test_loan_id").Return(&types.Loan{
+		LoanID:          "test_loan_id",
+		Currency:        "USD",
+		PrincipalAmount: 1000.0,
+		State:           "approved",
+	}, nil)
+	mockLoanInvestmentModel := &MockLoanInvestmentModel{}
+	mockLoanInvestmentModel.On("TxInvestment", ctx, mock.Anything, mock.Anything).Return(nil)
+
+	investLogic := logic.NewInvestLogic(ctx, &svc.ServiceContext{
+		LoanModel:           mockLoanModel,
+		LoanInvestmentModel: mockLoanInvestmentModel,
+	})
+
+	// An investment in another currency is rejected
+	_, err := investLogic.Invest(&types.InvestLoanRequest{
+		LoanID: "test_loan_id",
+		Investors: []types.Investor{
+			{InvestorID: "investor1", Amount: 500.0, Currency: "USD"},
+			{InvestorID: "investor2", Amount: 500.0, Currency: "IDR"},
+		},
+	})
+	assert.ErrorIs(t, err, money.ErrCurrencyMismatch)
+	assert.ErrorContains(t, err, "investment in IDR at idx: 1 on a loan in USD")
+
+	// So is an amount finer than a cent
+	_, err = investLogic.Invest(&types.InvestLoanRequest{
+		LoanID:    "test_loan_id",
+		Investors: []types.Investor{{InvestorID: "investor1", Amount: 500.005}},
+	})
+	assert.ErrorContains(t, err, "finer than the minor unit of USD")
+
+	// Investments without currency are in the loan currency
+	response, err := investLogic.Invest(&types.InvestLoanRequest{
+		LoanID: "test_loan_id",
+		Investors: []types.Investor{
+			{InvestorID: "investor1", Amount: 500.25, Currency: "USD"},
+			{InvestorID: "investor2", Amount: 499.75},
+		},
+	})
+	assert.NoError(t, err)
+	assert.NotNil(t, response)
+	mockLoanInvestmentModel.AssertCalled(t, "TxInvestment", ctx, mock.Anything, mock.Anything)
+}
@@ -4,11 +4,15 @@ import (
 	"context"
 	"fmt"
 	"github.com/google/uuid"
+	"gobillingengine/money"
 	"goloanservice/api/internal/svc"
 	types "goloanservice/api/internal/type"
 	"goloanservice/states"
 )
 
+// DefaultCurrency of the loans created without currency, the service lent in rupiah only before loans had one.
+const DefaultCurrency = "IDR"
+
 type LoanLogic struct {
 	ctx    context.Context
 	svcCtx *svc.ServiceContext
@@ -29,6 +33,7 @@ func (l *LoanLogic) GetLoan(req *types.GetLoanRequest) (resp *types.LoanResponse
 	return &types.LoanResponse{
 		LoanID:          loan.LoanID,
 		BorrowerID:      loan.BorrowerID,
+		Currency:        loan.Currency,
 		PrincipalAmount: loan.PrincipalAmount,
 		Rate:            loan.Rate,
 		AgreementLink:   loan.AgreementLink,
@@ -40,14 +45,25 @@ func (l *LoanLogic) CreateLoan(req *types.LoanRequest) (resp *types.LoanResponse
 	if req.Rate >= 1.0 {
 		return nil, fmt.Errorf("the rate must be below 1.0, inclusive")
 	}
+	if req.Currency == "" {
+		req.Currency = DefaultCurrency
+	}
+	currency, err := money.Lookup(req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if currency.Round(req.PrincipalAmount) != req.PrincipalAmount {
+		return nil, fmt.Errorf("principal amount %v is finer than the minor unit of %s", req.PrincipalAmount, currency.Code)
+	}
 
 	loanID := uuid.New().String()
 	createdLoan := &types.Loan{
 		LoanID:          loanID,
 		BorrowerID:      req.BorrowerID,
+		Currency:        currency.Code,
 		PrincipalAmount: req.PrincipalAmount,
 		Rate:            req.Rate,
-		ROI:             req.PrincipalAmount * req.Rate,
+		ROI:             currency.Round(req.PrincipalAmount * req.Rate),
 		AgreementLink:   fmt.Sprintf("http://s3.amazonaws.com/loans/%v-agreement.pdf", loanID), // mock agreement letter URL
 		State:           states.InitialState(),
 		InvestedAmount:  0,
@@ -58,6 +74,7 @@ func (l *LoanLogic) CreateLoan(req *types.LoanRequest) (resp *types.LoanResponse
 	return &types.LoanResponse{
 		LoanID:          createdLoan.LoanID,
 		BorrowerID:      createdLoan.BorrowerID,
+		Currency:        createdLoan.Currency,
 		PrincipalAmount: createdLoan.PrincipalAmount,
 		Rate:            createdLoan.Rate,
 		AgreementLink:   createdLoan.AgreementLink,
@@ -110,3 +110,37 @@ func TestCreateLoan(t *testing.T) {
 		t.Errorf("Expected error, but got nil")
 	}
 }
+
+func TestCreateLoan_Currency(t *testing.T) {
+	l := logic.NewLoanLogic(context.Background(), &svc.ServiceContext{LoanModel: &MockLoanModel{}})
+
+	// Test case: the currency defaults to rupiah
+	resp, err := l.CreateLoan(&types.LoanRequest{BorrowerID: "borrower_id", PrincipalAmount: 1000, Rate: 0.05})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if resp.Currency != logic.DefaultCurrency {
+		t.Errorf("Unexpected currency: %s, Expected: %s", resp.Currency, logic.DefaultCurrency)
+	}
+
+	// Test case: cents of a dollar loan
+	resp, err = l.CreateLoan(&types.LoanRequest{BorrowerID: "borrower_id", Currency: "USD", PrincipalAmount: 1000.5, Rate: 0.05})
+	if err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if resp.Currency != "USD" || resp.PrincipalAmount != 1000.5 {
+		t.Errorf("Unexpected response: %+v", resp)
+	}
+
+	// Test case: unknown currency
+	_, err = l.CreateLoan(&types.LoanRequest{BorrowerID: "borrower_id", Currency: "XXX", PrincipalAmount: 1000, Rate: 0.05})
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+
+	// Test case: rupiah have no minor unit
+	_, err = l.CreateLoan(&types.LoanRequest{BorrowerID: "borrower_id", Currency: "IDR", PrincipalAmount: 1000.5, Rate: 0.05})
+	if err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+}
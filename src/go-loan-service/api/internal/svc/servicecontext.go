@@ -2,6 +2,7 @@ package svc
 
 import (
 	"database/sql"
+	"goloanservice/api/internal/billing"
 	"goloanservice/api/model"
 )
 
@@ -11,13 +12,15 @@ type ServiceContext struct {
 	LoanApprovalModel     model.ILoanApprovalModel
 	LoanInvestmentModel   model.ILoanInvestmentModel
 	LoanDisbursementModel model.ILoanDisbursementModel
+	Billing               billing.IBilling
 }
 
 func NewServiceContext(db *sql.DB,
 	loanModel model.ILoanModel,
 	loanApprovalModel model.ILoanApprovalModel,
 	loanInvestmentModel model.ILoanInvestmentModel,
-	loanDisbursementModel model.ILoanDisbursementModel) *ServiceContext {
+	loanDisbursementModel model.ILoanDisbursementModel,
+	billing billing.IBilling) *ServiceContext {
 
 	return &ServiceContext{
 		DB:                    db,
@@ -25,5 +28,6 @@ func NewServiceContext(db *sql.DB,
 		LoanApprovalModel:     loanApprovalModel,
 		LoanInvestmentModel:   loanInvestmentModel,
 		LoanDisbursementModel: loanDisbursementModel,
+		Billing:               billing,
 	}
 }
@@ -10,6 +10,7 @@ type Loan struct {
 	ID              int64             `json:"id"`
 	LoanID          string            `json:"loan_id"`
 	BorrowerID      string            `json:"borrower_id"`
+	Currency        string            `json:"currency"` // Currency ISO 4217 code of the amounts of the loan
 	PrincipalAmount float64           `json:"principal_amount"`
 	Rate            float64           `json:"rate"`
 	ROI             float64           `json:"roi"`
@@ -24,6 +25,7 @@ type Loan struct {
 
 type LoanRequest struct {
 	BorrowerID      string  `json:"borrower_id"`
+	Currency        string  `json:"currency"` // Currency of the loan, IDR if empty
 	PrincipalAmount float64 `json:"principal_amount"`
 	Rate            float64 `json:"rate"`
 }
@@ -35,6 +37,7 @@ type GetLoanRequest struct {
 type LoanResponse struct {
 	LoanID          string    `json:"loan_id"`
 	BorrowerID      string    `json:"borrower_id"`
+	Currency        string    `json:"currency"`
 	PrincipalAmount float64   `json:"principal_amount"`
 	Rate            float64   `json:"rate"`
 	AgreementLink   string    `json:"agreement_link"`
@@ -70,6 +73,7 @@ type ApprovalInfoResponse struct {
 type Investor struct {
 	InvestorID string  `json:"investor_id"`
 	Amount     float64 `json:"amount"`
+	Currency   string  `json:"currency,omitempty"` // Currency of the amount, the loan currency if empty
 }
 
 // Investment represents an investment in the loan.
@@ -111,5 +115,7 @@ type DisbursementRequest struct {
 
 type DisbursementResponse struct {
 	DisbursementRequest
-	IsDisbursed bool `json:"is_disbursed"`
+	IsDisbursed        bool    `json:"is_disbursed"`
+	IsBillingStarted   bool    `json:"is_billing_started"`
+	NetDisbursedAmount float64 `json:"net_disbursed_amount,omitempty"` // NetDisbursedAmount handed to the borrower, the principal minus the deducted fees
 }
@@ -6,6 +6,10 @@ import (
 	"fmt"
 	log "github.com/sirupsen/logrus"
 	"github.com/zeromicro/go-zero/core/conf"
+	"gobillingengine/contract"
+	"gobillingengine/distribution"
+	"gobillingengine/portfolio"
+	"goloanservice/api/internal/billing"
 	"goloanservice/api/internal/config"
 	"goloanservice/api/internal/handler"
 	"goloanservice/api/internal/svc"
@@ -35,11 +39,19 @@ func main() {
 	loanInvestmentModel := model.NewLoanInvestmentModel(db, c.LoansTableName, c.InvestmentsTableName)
 	loanDisbursementModel := model.NewLoanDisbursementModel(db, c.LoansTableName, c.DisbursementTableName)
 
+	// billings live in this process until the billing engine runs as its own service
+	provisioner := &contract.LocalProvisioner{
+		Store:  portfolio.NewMemoryStore(),
+		Ledger: distribution.NewLedger(c.PlatformFeeRate),
+	}
+	loanBilling := billing.NewLocalBilling(provisioner, c.BillingTenorWeeks)
+
 	svctx := svc.NewServiceContext(db,
 		loanModel,
 		loanApprovalModel,
 		loanInvestmentModel,
-		loanDisbursementModel)
+		loanDisbursementModel,
+		loanBilling)
 	r := handler.CreateRouter(svctx)
 
 	fmt.Printf("Starting server at %s:%d...\n", "localhost", c.ListenToPort)
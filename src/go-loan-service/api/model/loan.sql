@@ -4,6 +4,7 @@ CREATE TABLE Loan (
     ID VARCHAR(255) PRIMARY KEY,
     LoanID VARCHAR(255) NOT NULL,
     BorrowerID VARCHAR(255) NOT NULL,
+    Currency CHAR(3) NOT NULL DEFAULT 'IDR',
     PrincipalAmount DECIMAL(18,2) NOT NULL,
     Rate DECIMAL(18,2) NOT NULL,
     ROI DECIMAL(18,2) NOT NULL,
@@ -10,6 +10,7 @@ import (
 
 type ILoanInvestmentModel interface {
 	TxInvestment(ctx context.Context, loan *types.Loan, investments *types.InvestLoanRequest) error
+	FindInvestors(ctx context.Context, loanID string) ([]*types.Investor, error)
 }
 
 type LoanInvestmentModel struct {
@@ -67,3 +68,26 @@ func (lim *LoanInvestmentModel) TxInvestment(ctx context.Context, loan *types.Lo
 
 	return nil
 }
+
+func (lim *LoanInvestmentModel) FindInvestors(ctx context.Context, loanID string) ([]*types.Investor, error) {
+	rows, err := lim.DB.QueryContext(ctx,
+		fmt.Sprintf("SELECT investor_id, amount FROM %s WHERE loan_id = ?", lim.InvestmentTableName), loanID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := rows.Close(); err != nil {
+			log.Errorln(err)
+		}
+	}()
+
+	var investors []*types.Investor
+	for rows.Next() {
+		var investor types.Investor
+		if err := rows.Scan(&investor.InvestorID, &investor.Amount); err != nil {
+			return nil, err
+		}
+		investors = append(investors, &investor)
+	}
+	return investors, rows.Err()
+}
@@ -29,11 +29,12 @@ func (lm *LoanModel) FindOne(ctx context.Context, loanId string) (*types.Loan, e
 	var loan types.Loan
 	err := lm.DB.QueryRowContext(ctx,
 		fmt.Sprintf(
-			"SELECT id, loan_id, borrower_id, principal_amount, rate, roi, agreement_link, state, invested_amount, created_at FROM %s WHERE loan_id = ?",
+			"SELECT id, loan_id, borrower_id, currency, principal_amount, rate, roi, agreement_link, state, invested_amount, created_at FROM %s WHERE loan_id = ?",
 			lm.TableName), loanId).
 		Scan(&loan.ID,
 			&loan.LoanID,
 			&loan.BorrowerID,
+			&loan.Currency,
 			&loan.PrincipalAmount,
 			&loan.Rate,
 			&loan.ROI,
@@ -49,13 +50,13 @@ func (lm *LoanModel) FindOne(ctx context.Context, loanId string) (*types.Loan, e
 
 func (lm *LoanModel) InsertOne(ctx context.Context, loan *types.Loan) error {
 	stmt, err := lm.DB.PrepareContext(ctx,
-		fmt.Sprintf("INSERT INTO %s (loan_id, borrower_id, principal_amount, rate, roi, agreement_link, state) VALUES (?, ?, ?, ?, ?, ?, ?)", lm.TableName))
+		fmt.Sprintf("INSERT INTO %s (loan_id, borrower_id, currency, principal_amount, rate, roi, agreement_link, state) VALUES (?, ?, ?, ?, ?, ?, ?, ?)", lm.TableName))
 	defer func() {
 		if err := stmt.Close(); err != nil {
 			log.Errorln(err)
 		}
 	}()
-	_, err = stmt.Exec(loan.LoanID, loan.BorrowerID, loan.PrincipalAmount, loan.Rate, loan.ROI, loan.AgreementLink, loan.State)
+	_, err = stmt.Exec(loan.LoanID, loan.BorrowerID, loan.Currency, loan.PrincipalAmount, loan.Rate, loan.ROI, loan.AgreementLink, loan.State)
 	if err != nil {
 		return err
 	}